@@ -0,0 +1,349 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"hexagonal-gotest/policies"
+	"hexagonal-gotest/utils"
+	"net"
+	"net/url"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	StorageMongo    = "mongo"
+	StorageMemory   = "memory"
+	StoragePostgres = "postgres"
+	StorageSQLite   = "sqlite"
+)
+
+// Config is loaded by Load, later sources override earlier ones:
+// defaults < config file < environment variables < command line flags
+type Config struct {
+	// Storage selects the repository adapters, memory needs no database and loses everything on restart
+	Storage  string         `yaml:"storage"`
+	Server   ServerConfig   `yaml:"server"`
+	Mongo    MongoConfig    `yaml:"mongo"`
+	SQL      SQLConfig      `yaml:"sql"`
+	Timeouts TimeoutsConfig `yaml:"timeouts"`
+	JWT      JWTConfig      `yaml:"jwt"`
+	Username UsernameConfig `yaml:"username"`
+	Password PasswordConfig `yaml:"password"`
+	Throttle ThrottleConfig `yaml:"throttle"`
+	Users    UsersConfig    `yaml:"users"`
+	Mail     MailConfig     `yaml:"mail"`
+}
+
+type ServerConfig struct {
+	Addr           string `yaml:"addr"`
+	AuthCookieName string `yaml:"auth_cookie_name"`
+	// BodyLimit is the largest request body in bytes, larger ones are rejected with 413
+	BodyLimit int `yaml:"body_limit"`
+	// ProxyHeader names the header holding the client address, like X-Forwarded-For,
+	// it is only read from TrustedProxies when those are set
+	ProxyHeader    string   `yaml:"proxy_header"`
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+type MongoConfig struct {
+	URI                       Secret        `yaml:"uri"`
+	Database                  string        `yaml:"database"`
+	UsersCollection           string        `yaml:"users_collection"`
+	RefreshTokensCollection   string        `yaml:"refresh_tokens_collection"`
+	RevokedTokensCollection   string        `yaml:"revoked_tokens_collection"`
+	LoginAttemptsCollection   string        `yaml:"login_attempts_collection"`
+	UsernameHistoryCollection string        `yaml:"username_history_collection"`
+	PasswordResetsCollection  string        `yaml:"password_resets_collection"`
+	ConnectTimeout            time.Duration `yaml:"connect_timeout"`
+}
+
+// SQLConfig is used by the postgres and sqlite storages, the schema is migrated at startup
+type SQLConfig struct {
+	DSN Secret `yaml:"dsn"`
+}
+
+// TimeoutsConfig Request bounds a whole http request, Read and Write each database call within it
+type TimeoutsConfig struct {
+	Request time.Duration `yaml:"request"`
+	Read    time.Duration `yaml:"read"`
+	Write   time.Duration `yaml:"write"`
+}
+
+type JWTConfig struct {
+	Algorithm       string          `yaml:"algorithm"`
+	KeyId           string          `yaml:"key_id"`
+	Key             Secret          `yaml:"key"`
+	KeyFile         string          `yaml:"key_file"`
+	VerifyKeyFiles  []KeyFileConfig `yaml:"verify_key_files"`
+	Issuer          string          `yaml:"issuer"`
+	Audience        string          `yaml:"audience"`
+	Lifetime        time.Duration   `yaml:"lifetime"`
+	RefreshTokenTTL time.Duration   `yaml:"refresh_token_ttl"`
+}
+
+// UsernameConfig applies to new usernames, lengths count characters. AllowUnicode
+// accepts letters of every script instead of only a-z.
+type UsernameConfig struct {
+	MinLength    int      `yaml:"min_length"`
+	MaxLength    int      `yaml:"max_length"`
+	AllowUnicode bool     `yaml:"allow_unicode"`
+	Reserved     []string `yaml:"reserved"`
+}
+
+// PasswordConfig applies to new passwords, lengths count characters not bytes.
+// RequiredClasses lists lower, upper, digit or symbol and BannedFile holds one
+// password per line. History is how many of the latest passwords, the current one
+// included, cannot be chosen again.
+type PasswordConfig struct {
+	MinLength        int      `yaml:"min_length"`
+	MaxLength        int      `yaml:"max_length"`
+	RequiredClasses  []string `yaml:"required_classes"`
+	DisallowUsername bool     `yaml:"disallow_username"`
+	BannedFile       string   `yaml:"banned_file"`
+	MinEntropyBits   float64  `yaml:"min_entropy_bits"`
+	History          int      `yaml:"history"`
+}
+
+// ThrottleConfig slows down failed logins, see services.ThrottleConfig. A zero
+// DelayAfter, LockoutThreshold or IPLimit turns that check off.
+type ThrottleConfig struct {
+	Window           time.Duration `yaml:"window"`
+	DelayAfter       int           `yaml:"delay_after"`
+	BaseDelay        time.Duration `yaml:"base_delay"`
+	MaxDelay         time.Duration `yaml:"max_delay"`
+	LockoutThreshold int           `yaml:"lockout_threshold"`
+	LockoutDuration  time.Duration `yaml:"lockout_duration"`
+	IPLimit          int           `yaml:"ip_limit"`
+}
+
+// UsersConfig ConcealRegistration answers a register of a taken username like a new
+// one, clients then cannot tell which accounts exist but users get no hint either.
+// UsernameCooldown keeps a changed-away username for its previous owner, 0 releases
+// it right away. PasswordResetTTL is how long a mailed reset token works and
+// PasswordResetURL the client page it is linked to, empty mails the bare token.
+type UsersConfig struct {
+	ConcealRegistration bool          `yaml:"conceal_registration"`
+	UsernameCooldown    time.Duration `yaml:"username_cooldown"`
+	PasswordResetTTL    time.Duration `yaml:"password_reset_ttl"`
+	PasswordResetURL    string        `yaml:"password_reset_url"`
+}
+
+// MailConfig SMTPAddr is the host:port of the relay mails are sent through. Without it
+// mails are written to LogFile, or the log when that is empty too, which only suits
+// development since anyone reading them can reset passwords. MaxPending is how many
+// mails may wait to be sent, requests past it are told to retry later.
+type MailConfig struct {
+	From         string `yaml:"from"`
+	SMTPAddr     string `yaml:"smtp_addr"`
+	SMTPUsername string `yaml:"smtp_username"`
+	SMTPPassword Secret `yaml:"smtp_password"`
+	LogFile      string `yaml:"log_file"`
+	MaxPending   int    `yaml:"max_pending"`
+}
+
+// KeyFileConfig is a retiring key still accepted for verification. Algorithm
+// defaults to jwt.algorithm, set it when rotating from one algorithm to another.
+type KeyFileConfig struct {
+	Kid       string `yaml:"kid"`
+	Algorithm string `yaml:"algorithm"`
+	Path      string `yaml:"path"`
+}
+
+func Default() Config {
+	return Config{
+		Storage: StorageMongo,
+		Server: ServerConfig{
+			Addr:           ":3000",
+			AuthCookieName: "access_token",
+			BodyLimit:      64 * 1024,
+		},
+		Mongo: MongoConfig{
+			Database:                  "julladith",
+			UsersCollection:           "users",
+			RefreshTokensCollection:   "refresh_tokens",
+			RevokedTokensCollection:   "revoked_tokens",
+			LoginAttemptsCollection:   "login_attempts",
+			UsernameHistoryCollection: "username_history",
+			PasswordResetsCollection:  "password_resets",
+			ConnectTimeout:            2 * time.Second,
+		},
+		Timeouts: TimeoutsConfig{
+			Request: 10 * time.Second,
+			Read:    5 * time.Second,
+			Write:   5 * time.Second,
+		},
+		JWT: JWTConfig{
+			Algorithm:       utils.AlgorithmHS256,
+			KeyId:           "default",
+			Lifetime:        time.Hour,
+			RefreshTokenTTL: 7 * 24 * time.Hour,
+		},
+		Username: UsernameConfig{
+			MinLength:    policies.DefaultUsernamePolicyConfig.MinLength,
+			MaxLength:    policies.DefaultUsernamePolicyConfig.MaxLength,
+			AllowUnicode: policies.DefaultUsernamePolicyConfig.AllowUnicode,
+			Reserved:     policies.DefaultUsernamePolicyConfig.Reserved,
+		},
+		Password: PasswordConfig{
+			MinLength:        policies.DefaultPasswordPolicyConfig.MinLength,
+			MaxLength:        policies.DefaultPasswordPolicyConfig.MaxLength,
+			DisallowUsername: policies.DefaultPasswordPolicyConfig.DisallowUsername,
+			MinEntropyBits:   policies.DefaultPasswordPolicyConfig.MinEntropyBits,
+			History:          5,
+		},
+		Throttle: ThrottleConfig{
+			Window:           15 * time.Minute,
+			DelayAfter:       3,
+			BaseDelay:        time.Second,
+			MaxDelay:         30 * time.Second,
+			LockoutThreshold: 10,
+			LockoutDuration:  15 * time.Minute,
+			IPLimit:          100,
+		},
+		Users: UsersConfig{
+			UsernameCooldown: 30 * 24 * time.Hour,
+			PasswordResetTTL: time.Hour,
+		},
+		Mail: MailConfig{
+			From:       "no-reply@localhost",
+			MaxPending: 64,
+		},
+	}
+}
+
+// Validate reports every invalid field at once
+func (c Config) Validate() error {
+	errs := []error{}
+	required := func(name, value string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%v is required", name))
+		}
+	}
+	positive := func(name string, value time.Duration) {
+		if value <= 0 {
+			errs = append(errs, fmt.Errorf("%v must be positive", name))
+		}
+	}
+
+	required("server.addr", c.Server.Addr)
+	if c.Server.BodyLimit <= 0 {
+		errs = append(errs, errors.New("server.body_limit must be positive"))
+	}
+
+	switch c.Storage {
+	case StorageMongo:
+		required("mongo.uri", c.Mongo.URI.Value())
+		required("mongo.database", c.Mongo.Database)
+		required("mongo.users_collection", c.Mongo.UsersCollection)
+		required("mongo.refresh_tokens_collection", c.Mongo.RefreshTokensCollection)
+		required("mongo.revoked_tokens_collection", c.Mongo.RevokedTokensCollection)
+		required("mongo.login_attempts_collection", c.Mongo.LoginAttemptsCollection)
+		required("mongo.username_history_collection", c.Mongo.UsernameHistoryCollection)
+		required("mongo.password_resets_collection", c.Mongo.PasswordResetsCollection)
+		positive("mongo.connect_timeout", c.Mongo.ConnectTimeout)
+	case StoragePostgres, StorageSQLite:
+		required("sql.dsn", c.SQL.DSN.Value())
+	case StorageMemory:
+	default:
+		errs = append(errs, fmt.Errorf("storage %q is not supported", c.Storage))
+	}
+
+	positive("timeouts.request", c.Timeouts.Request)
+	positive("timeouts.read", c.Timeouts.Read)
+	positive("timeouts.write", c.Timeouts.Write)
+
+	switch c.JWT.Algorithm {
+	case utils.AlgorithmHS256, utils.AlgorithmRS256, utils.AlgorithmES256:
+	default:
+		errs = append(errs, fmt.Errorf("jwt.algorithm %q is not supported", c.JWT.Algorithm))
+	}
+	required("jwt.key_id", c.JWT.KeyId)
+	if c.JWT.Key.Value() == "" && c.JWT.KeyFile == "" {
+		errs = append(errs, errors.New("jwt.key or jwt.key_file is required"))
+	}
+	for i, keyFile := range c.JWT.VerifyKeyFiles {
+		required(fmt.Sprintf("jwt.verify_key_files[%v].kid", i), keyFile.Kid)
+		required(fmt.Sprintf("jwt.verify_key_files[%v].path", i), keyFile.Path)
+		switch keyFile.Algorithm {
+		case "", utils.AlgorithmHS256, utils.AlgorithmRS256, utils.AlgorithmES256:
+		default:
+			errs = append(errs, fmt.Errorf("jwt.verify_key_files[%v].algorithm %q is not supported", i, keyFile.Algorithm))
+		}
+	}
+	positive("jwt.lifetime", c.JWT.Lifetime)
+	positive("jwt.refresh_token_ttl", c.JWT.RefreshTokenTTL)
+
+	if c.Username.MinLength < 1 {
+		errs = append(errs, errors.New("username.min_length must be positive"))
+	}
+	if c.Username.MaxLength < c.Username.MinLength {
+		errs = append(errs, errors.New("username.max_length must not be less than username.min_length"))
+	}
+
+	if c.Password.MinLength < 1 {
+		errs = append(errs, errors.New("password.min_length must be positive"))
+	}
+	if c.Password.MaxLength < c.Password.MinLength {
+		errs = append(errs, errors.New("password.max_length must not be less than password.min_length"))
+	}
+	for _, class := range c.Password.RequiredClasses {
+		switch policies.CharacterClass(class) {
+		case policies.ClassLower, policies.ClassUpper, policies.ClassDigit, policies.ClassSymbol:
+		default:
+			errs = append(errs, fmt.Errorf("password.required_classes %q is not supported", class))
+		}
+	}
+	if c.Password.MinEntropyBits < 0 {
+		errs = append(errs, errors.New("password.min_entropy_bits must not be negative"))
+	}
+	if c.Password.History < 0 {
+		errs = append(errs, errors.New("password.history must not be negative"))
+	}
+
+	positive("throttle.window", c.Throttle.Window)
+	if c.Throttle.DelayAfter < 0 || c.Throttle.LockoutThreshold < 0 || c.Throttle.IPLimit < 0 {
+		errs = append(errs, errors.New("throttle.delay_after, throttle.lockout_threshold and throttle.ip_limit must not be negative"))
+	}
+	if c.Throttle.DelayAfter > 0 {
+		positive("throttle.base_delay", c.Throttle.BaseDelay)
+		if c.Throttle.MaxDelay < c.Throttle.BaseDelay {
+			errs = append(errs, errors.New("throttle.max_delay must not be less than throttle.base_delay"))
+		}
+	}
+	if c.Throttle.LockoutThreshold > 0 {
+		positive("throttle.lockout_duration", c.Throttle.LockoutDuration)
+	}
+
+	if c.Users.UsernameCooldown < 0 {
+		errs = append(errs, errors.New("users.username_cooldown must not be negative"))
+	}
+	positive("users.password_reset_ttl", c.Users.PasswordResetTTL)
+	if c.Users.PasswordResetURL != "" {
+		if resetURL, err := url.Parse(c.Users.PasswordResetURL); err != nil || !resetURL.IsAbs() || resetURL.Host == "" {
+			errs = append(errs, errors.New("users.password_reset_url must be an absolute url"))
+		}
+	}
+
+	required("mail.from", c.Mail.From)
+	if c.Mail.MaxPending <= 0 {
+		errs = append(errs, errors.New("mail.max_pending must be positive"))
+	}
+	if c.Mail.SMTPAddr != "" {
+		if _, _, err := net.SplitHostPort(c.Mail.SMTPAddr); err != nil {
+			errs = append(errs, errors.New("mail.smtp_addr must be host:port"))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// String is safe to log, secrets are redacted
+func (c Config) String() string {
+	out, err := yaml.Marshal(c)
+	if err != nil {
+		return err.Error()
+	}
+	return string(out)
+}
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// source binds one Config field to its environment variable and flag
+type source struct {
+	env   string
+	flag  string
+	usage string
+	set   func(value string) error
+}
+
+func sources(c *Config) []source {
+	return []source{
+		{"STORAGE", "storage", "repository adapters, mongo, postgres, sqlite or memory", setString(&c.Storage)},
+
+		{"LISTEN_ADDR", "listen", "address the http server listens on", setString(&c.Server.Addr)},
+		{"AUTH_COOKIE_NAME", "auth-cookie", "cookie read when the Authorization header is missing", setString(&c.Server.AuthCookieName)},
+		{"BODY_LIMIT", "body-limit", "largest request body in bytes", setInt(&c.Server.BodyLimit)},
+		{"PROXY_HEADER", "proxy-header", "header holding the client address behind a proxy, like X-Forwarded-For", setString(&c.Server.ProxyHeader)},
+		{"TRUSTED_PROXIES", "trusted-proxies", "comma separated addresses or cidrs allowed to set the proxy header", setStrings(&c.Server.TrustedProxies)},
+
+		{"MONGO_URI", "mongo-uri", "mongodb connection string", setSecret(&c.Mongo.URI)},
+		{"MONGO_DATABASE", "mongo-database", "mongodb database name", setString(&c.Mongo.Database)},
+		{"MONGO_USERS_COLLECTION", "mongo-users-collection", "users collection", setString(&c.Mongo.UsersCollection)},
+		{"MONGO_REFRESH_TOKENS_COLLECTION", "mongo-refresh-tokens-collection", "refresh tokens collection", setString(&c.Mongo.RefreshTokensCollection)},
+		{"MONGO_REVOKED_TOKENS_COLLECTION", "mongo-revoked-tokens-collection", "revoked tokens collection", setString(&c.Mongo.RevokedTokensCollection)},
+		{"MONGO_LOGIN_ATTEMPTS_COLLECTION", "mongo-login-attempts-collection", "failed logins collection", setString(&c.Mongo.LoginAttemptsCollection)},
+		{"MONGO_USERNAME_HISTORY_COLLECTION", "mongo-username-history-collection", "released usernames collection", setString(&c.Mongo.UsernameHistoryCollection)},
+		{"MONGO_PASSWORD_RESETS_COLLECTION", "mongo-password-resets-collection", "password reset tokens collection", setString(&c.Mongo.PasswordResetsCollection)},
+		{"MONGO_CONNECT_TIMEOUT", "mongo-connect-timeout", "mongodb connect and ping timeout", setDuration(&c.Mongo.ConnectTimeout)},
+
+		{"SQL_DSN", "sql-dsn", "postgres connection string or sqlite file", setSecret(&c.SQL.DSN)},
+
+		{"REQUEST_TIMEOUT", "request-timeout", "deadline of each http request", setDuration(&c.Timeouts.Request)},
+		{"DB_READ_TIMEOUT", "db-read-timeout", "deadline of each database read", setDuration(&c.Timeouts.Read)},
+		{"DB_WRITE_TIMEOUT", "db-write-timeout", "deadline of each database write", setDuration(&c.Timeouts.Write)},
+
+		{"JWT_ALGORITHM", "jwt-algorithm", "HS256, RS256 or ES256", setString(&c.JWT.Algorithm)},
+		{"JWT_KEY_ID", "jwt-key-id", "kid of the signing key", setString(&c.JWT.KeyId)},
+		{"JWT_KEY", "jwt-key", "HS256 secret or PEM private key", setSecret(&c.JWT.Key)},
+		{"JWT_KEY_FILE", "jwt-key-file", "file holding the signing key, takes precedence over jwt-key", setString(&c.JWT.KeyFile)},
+		{"JWT_VERIFY_KEY_FILES", "jwt-verify-key-files", "comma separated kid=path or kid:algorithm=path of retiring keys", setKeyFiles(&c.JWT.VerifyKeyFiles)},
+		{"JWT_ISSUER", "jwt-issuer", "iss claim", setString(&c.JWT.Issuer)},
+		{"JWT_AUDIENCE", "jwt-audience", "aud claim", setString(&c.JWT.Audience)},
+		{"JWT_LIFETIME", "jwt-lifetime", "access token lifetime", setDuration(&c.JWT.Lifetime)},
+		{"REFRESH_TOKEN_TTL", "refresh-token-ttl", "refresh token lifetime", setDuration(&c.JWT.RefreshTokenTTL)},
+
+		{"USERNAME_MIN_LENGTH", "username-min-length", "fewest characters in a new username", setInt(&c.Username.MinLength)},
+		{"USERNAME_MAX_LENGTH", "username-max-length", "most characters in a new username", setInt(&c.Username.MaxLength)},
+		{"USERNAME_ALLOW_UNICODE", "username-allow-unicode", "accept letters of every script, not only a-z", setBool(&c.Username.AllowUnicode)},
+		{"USERNAME_RESERVED", "username-reserved", "comma separated names nobody may register", setStrings(&c.Username.Reserved)},
+
+		{"PASSWORD_MIN_LENGTH", "password-min-length", "fewest characters in a new password", setInt(&c.Password.MinLength)},
+		{"PASSWORD_MAX_LENGTH", "password-max-length", "most characters in a new password", setInt(&c.Password.MaxLength)},
+		{"PASSWORD_REQUIRED_CLASSES", "password-required-classes", "comma separated lower, upper, digit or symbol", setStrings(&c.Password.RequiredClasses)},
+		{"PASSWORD_DISALLOW_USERNAME", "password-disallow-username", "reject passwords containing the username", setBool(&c.Password.DisallowUsername)},
+		{"PASSWORD_BANNED_FILE", "password-banned-file", "file of banned passwords, one per line", setString(&c.Password.BannedFile)},
+		{"PASSWORD_MIN_ENTROPY_BITS", "password-min-entropy-bits", "weakest estimated strength accepted, 0 turns it off", setFloat(&c.Password.MinEntropyBits)},
+		{"PASSWORD_HISTORY", "password-history", "latest passwords, the current one included, that cannot be chosen again, 0 turns it off", setInt(&c.Password.History)},
+
+		{"LOGIN_THROTTLE_WINDOW", "login-throttle-window", "how long a failed login counts", setDuration(&c.Throttle.Window)},
+		{"LOGIN_DELAY_AFTER", "login-delay-after", "failed logins of an account before each retry waits, 0 turns it off", setInt(&c.Throttle.DelayAfter)},
+		{"LOGIN_BASE_DELAY", "login-base-delay", "first wait, doubled with every further failure", setDuration(&c.Throttle.BaseDelay)},
+		{"LOGIN_MAX_DELAY", "login-max-delay", "longest wait between failed logins", setDuration(&c.Throttle.MaxDelay)},
+		{"LOGIN_LOCKOUT_THRESHOLD", "login-lockout-threshold", "failed logins that lock an account, 0 turns it off", setInt(&c.Throttle.LockoutThreshold)},
+		{"LOGIN_LOCKOUT_DURATION", "login-lockout-duration", "how long a locked account stays locked", setDuration(&c.Throttle.LockoutDuration)},
+		{"LOGIN_IP_LIMIT", "login-ip-limit", "failed logins from one address that block it, 0 turns it off", setInt(&c.Throttle.IPLimit)},
+
+		{"CONCEAL_REGISTRATION", "conceal-registration", "answer a register of a taken username as a success", setBool(&c.Users.ConcealRegistration)},
+		{"USERNAME_COOLDOWN", "username-cooldown", "how long a changed-away username stays reserved for its previous owner, 0 turns it off", setDuration(&c.Users.UsernameCooldown)},
+		{"PASSWORD_RESET_TTL", "password-reset-ttl", "how long a mailed password reset token works", setDuration(&c.Users.PasswordResetTTL)},
+		{"PASSWORD_RESET_URL", "password-reset-url", "client page linked in reset mails, the token is added as its token query parameter", setString(&c.Users.PasswordResetURL)},
+
+		{"MAIL_FROM", "mail-from", "sender address of mails", setString(&c.Mail.From)},
+		{"SMTP_ADDR", "smtp-addr", "host:port of the smtp relay, without it mails are only logged", setString(&c.Mail.SMTPAddr)},
+		{"SMTP_USERNAME", "smtp-username", "smtp user, empty sends without authenticating", setString(&c.Mail.SMTPUsername)},
+		{"SMTP_PASSWORD", "smtp-password", "smtp password", setSecret(&c.Mail.SMTPPassword)},
+		{"MAIL_LOG_FILE", "mail-log-file", "file unsent mails are appended to when there is no smtp relay", setString(&c.Mail.LogFile)},
+		{"MAIL_MAX_PENDING", "mail-max-pending", "mails that may wait to be sent before forgot password requests are refused", setInt(&c.Mail.MaxPending)},
+	}
+}
+
+// Load reads the file named by -config or CONFIG_FILE (YAML, or JSON which is
+// valid YAML), then the environment, then args, and validates the result.
+func Load(args []string) (config Config, err error) {
+	config = Default()
+
+	flagValues := map[string]string{}
+	flags := flag.NewFlagSet("hexagonal-gotest", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML or JSON config file")
+	for _, s := range sources(&config) {
+		name := s.flag
+		flags.Func(name, fmt.Sprintf("%v (env %v)", s.usage, s.env), func(value string) error {
+			flagValues[name] = value
+			return nil
+		})
+	}
+	if err = flags.Parse(args); err != nil {
+		return config, err
+	}
+
+	if *configFile != "" {
+		if err = loadFile(&config, *configFile); err != nil {
+			return config, err
+		}
+	}
+
+	// the file may replace slices, so sources are bound again after it is decoded
+	for _, s := range sources(&config) {
+		if value, ok := os.LookupEnv(s.env); ok && value != "" {
+			if err = s.set(value); err != nil {
+				return config, fmt.Errorf("env %v: %w", s.env, err)
+			}
+		}
+	}
+	for _, s := range sources(&config) {
+		if value, ok := flagValues[s.flag]; ok {
+			if err = s.set(value); err != nil {
+				return config, fmt.Errorf("flag -%v: %w", s.flag, err)
+			}
+		}
+	}
+
+	return config, config.Validate()
+}
+
+func loadFile(config *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %v: %w", path, err)
+	}
+	return nil
+}
+
+func setString(field *string) func(string) error {
+	return func(value string) error {
+		*field = value
+		return nil
+	}
+}
+
+func setSecret(field *Secret) func(string) error {
+	return func(value string) error {
+		*field = Secret(value)
+		return nil
+	}
+}
+
+func setInt(field *int) func(string) error {
+	return func(value string) error {
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*field = i
+		return nil
+	}
+}
+
+func setBool(field *bool) func(string) error {
+	return func(value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*field = b
+		return nil
+	}
+}
+
+func setFloat(field *float64) func(string) error {
+	return func(value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		*field = f
+		return nil
+	}
+}
+
+func setStrings(field *[]string) func(string) error {
+	return func(value string) error {
+		values := []string{}
+		for _, entry := range strings.Split(value, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				values = append(values, entry)
+			}
+		}
+		*field = values
+		return nil
+	}
+}
+
+func setDuration(field *time.Duration) func(string) error {
+	return func(value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*field = d
+		return nil
+	}
+}
+
+func setKeyFiles(field *[]KeyFileConfig) func(string) error {
+	return func(value string) error {
+		keyFiles := []KeyFileConfig{}
+		for _, entry := range strings.Split(value, ",") {
+			if entry == "" {
+				continue
+			}
+			kid, path, ok := strings.Cut(entry, "=")
+			if !ok {
+				return fmt.Errorf("%q is not kid=path", entry)
+			}
+			kid, algorithm, _ := strings.Cut(kid, ":")
+			keyFiles = append(keyFiles, KeyFileConfig{Kid: kid, Algorithm: algorithm, Path: path})
+		}
+		*field = keyFiles
+		return nil
+	}
+}
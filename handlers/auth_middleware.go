@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/services"
+	"hexagonal-gotest/utils"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LocalsTokenData is the c.Locals key holding the validated utils.TokenDataModel
+const LocalsTokenData = "token_data"
+
+type authMiddleware struct {
+	tokenSrv   services.TokenService
+	cookieName string
+}
+
+// NewAuthMiddleware reads a Bearer token from the Authorization header, falling
+// back to the cookieName cookie when cookieName is not empty.
+func NewAuthMiddleware(tokenSrv services.TokenService, cookieName string) authMiddleware {
+	return authMiddleware{tokenSrv, cookieName}
+}
+
+func (m authMiddleware) Handle(c *fiber.Ctx) error {
+	tokenString := m.extractToken(c)
+	if tokenString == "" {
+		return unauthorized(c)
+	}
+
+	tokenData, err := m.tokenSrv.Verify(c.UserContext(), tokenString)
+	if err != nil {
+		if errors.Is(err, models.ErrUnexpected) {
+			return errorResponse(c, err)
+		}
+
+		return unauthorized(c)
+	}
+
+	c.Locals(LocalsTokenData, tokenData)
+
+	return c.Next()
+}
+
+func (m authMiddleware) extractToken(c *fiber.Ctx) string {
+	auth := c.Get(fiber.HeaderAuthorization)
+	if auth != "" {
+		scheme, token, found := strings.Cut(auth, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+		return strings.TrimSpace(token)
+	}
+
+	if m.cookieName != "" {
+		return c.Cookies(m.cookieName)
+	}
+
+	return ""
+}
+
+// TokenData returns the token data stored by authMiddleware
+func TokenData(c *fiber.Ctx) (tokenData utils.TokenDataModel, ok bool) {
+	tokenData, ok = c.Locals(LocalsTokenData).(utils.TokenDataModel)
+	return
+}
+
+func unauthorized(c *fiber.Ctx) error {
+	return errorResponse(c, models.ErrUnauthorized)
+}
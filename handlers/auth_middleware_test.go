@@ -0,0 +1,127 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"hexagonal-gotest/handlers"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/services"
+	"hexagonal-gotest/utils"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	type responseData struct {
+		Message string `json:"message"`
+	}
+	tests := []struct {
+		name           string
+		header         string
+		cookie         string
+		wantData       responseData
+		wantStatusCode int
+	}{
+		// TODO: Add test cases.
+		{
+			name:           "error1",
+			wantData:       responseData{Message: models.ErrUnauthorized.Error()},
+			wantStatusCode: 401,
+		},
+		{
+			name:           "error2",
+			header:         "Basic " + token,
+			wantData:       responseData{Message: models.ErrUnauthorized.Error()},
+			wantStatusCode: 401,
+		},
+		{
+			name:           "error3",
+			header:         "Bearer invalid-token",
+			wantData:       responseData{Message: models.ErrUnauthorized.Error()},
+			wantStatusCode: 401,
+		},
+		{
+			name:           "error4",
+			header:         "Bearer invalid-token",
+			cookie:         token,
+			wantData:       responseData{Message: models.ErrUnauthorized.Error()},
+			wantStatusCode: 401,
+		},
+		{
+			name:           "error revoked",
+			header:         "Bearer " + token,
+			wantData:       responseData{Message: models.ErrUnauthorized.Error()},
+			wantStatusCode: 401,
+		},
+		{
+			name:           "error500",
+			header:         "Bearer " + token,
+			wantData:       responseData{Message: models.ErrUnexpected.Error()},
+			wantStatusCode: 500,
+		},
+		{
+			name:           "success header",
+			header:         "Bearer " + token,
+			wantData:       responseData{Message: "admin"},
+			wantStatusCode: 200,
+		},
+		{
+			name:           "success cookie",
+			cookie:         token,
+			wantData:       responseData{Message: "admin"},
+			wantStatusCode: 200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			tokenSrv := services.NewTokenSrvMock()
+
+			// mock verify token
+			tokenData := utils.TokenDataModel{UserId: principal.UserId, Username: principal.Username}
+			switch tt.name {
+			case "error revoked":
+				tokenSrv.On("Verify", mock.Anything, token).Return(nil, models.ErrUnauthorized)
+			case "error500":
+				tokenSrv.On("Verify", mock.Anything, token).Return(nil, models.ErrUnexpected)
+			default:
+				tokenSrv.On("Verify", mock.Anything, token).Return(tokenData, nil)
+				tokenSrv.On("Verify", mock.Anything, mock.Anything).Return(nil, models.ErrUnauthorized)
+			}
+
+			authMiddleware := handlers.NewAuthMiddleware(&tokenSrv, "access_token")
+
+			// http request
+			app := fiber.New()
+			app.Use(authMiddleware.Handle)
+			app.Get("/me", func(c *fiber.Ctx) error {
+				tokenData, _ := handlers.TokenData(c)
+				return c.JSON(fiber.Map{"message": tokenData.Username})
+			})
+
+			req := httptest.NewRequest("GET", "/me", nil)
+			if tt.header != "" {
+				req.Header.Add("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.Header.Add("Cookie", "access_token="+tt.cookie)
+			}
+
+			// -------------------- Act (กระทำ)--------------------
+			res, _ := app.Test(req)
+			defer res.Body.Close()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+
+			b, _ := io.ReadAll(res.Body)
+			resBody := responseData{}
+			json.Unmarshal(b, &resBody)
+			assert.Equal(t, tt.wantData, resBody)
+		})
+	}
+}
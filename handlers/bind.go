@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"hexagonal-gotest/models"
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseBody decodes a json body into out, rejecting other content types, unknown
+// fields, wrong types and trailing data, then validates out. An empty body decodes
+// as {} so missing fields are reported by validation.
+func parseBody(c *fiber.Ctx, out any) error {
+	body := c.Body()
+	if len(bytes.TrimSpace(body)) > 0 {
+		if !c.Is("json") {
+			return models.ErrUnsupportedMediaType
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(out); err != nil {
+			return decodeError(err)
+		}
+		if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+			return models.ErrMalformedBody
+		}
+	}
+
+	return validate(out)
+}
+
+// parseParams binds route params into out and validates it
+func parseParams(c *fiber.Ctx, out any) error {
+	if err := c.ParamsParser(out); err != nil {
+		return models.ErrUnexpected.Wrap(err)
+	}
+
+	return validate(out)
+}
+
+// parseQuery binds the query string into out and validates it
+func parseQuery(c *fiber.Ctx, out any) error {
+	if err := c.QueryParser(out); err != nil {
+		return models.ErrUnexpected.Wrap(err)
+	}
+
+	return validate(out)
+}
+
+func decodeError(err error) error {
+	typeErr := &json.UnmarshalTypeError{}
+	if errors.As(err, &typeErr) {
+		return models.NewValidationError(models.ErrFieldType.For(typeErr.Field))
+	}
+
+	// encoding/json has no typed error for unknown fields
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return models.NewValidationError(models.ErrFieldUnknown.For(strings.Trim(field, `"`)))
+	}
+
+	return models.ErrMalformedBody
+}
@@ -0,0 +1,109 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"hexagonal-gotest/handlers"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/services"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParseBody(t *testing.T) {
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		wantCode       models.ErrorCode
+		wantErrors     []models.HandProblemFieldModel
+		wantStatusCode int
+	}{
+		// TODO: Add test cases.
+		{
+			name:           "malformed json",
+			contentType:    fiber.MIMEApplicationJSON,
+			body:           `{"username":"admin",`,
+			wantCode:       "malformed_body",
+			wantStatusCode: 400,
+		},
+		{
+			name:           "trailing data",
+			contentType:    fiber.MIMEApplicationJSON,
+			body:           `{"username":"admin","password":"admin01"}{}`,
+			wantCode:       "malformed_body",
+			wantStatusCode: 400,
+		},
+		{
+			name:           "unknown field",
+			contentType:    fiber.MIMEApplicationJSON,
+			body:           `{"username":"admin","password":"admin01","role":"admin"}`,
+			wantCode:       "validation_failed",
+			wantErrors:     []models.HandProblemFieldModel{{Field: "role", Code: "unknown_field", Detail: "role is not allowed"}},
+			wantStatusCode: 400,
+		},
+		{
+			name:           "wrong type",
+			contentType:    fiber.MIMEApplicationJSON,
+			body:           `{"username":"admin","password":123456}`,
+			wantCode:       "validation_failed",
+			wantErrors:     []models.HandProblemFieldModel{{Field: "password", Code: "type", Detail: "password has the wrong type"}},
+			wantStatusCode: 400,
+		},
+		{
+			name:           "form content type",
+			contentType:    fiber.MIMEApplicationForm,
+			body:           `username=admin&password=admin01`,
+			wantCode:       "unsupported_media_type",
+			wantStatusCode: 415,
+		},
+		{
+			name:        "empty body",
+			contentType: fiber.MIMEApplicationJSON,
+			body:        ``,
+			wantCode:    "validation_failed",
+			wantErrors: []models.HandProblemFieldModel{
+				{Field: "username", Code: "required", Detail: "username is required"},
+				{Field: "password", Code: "required", Detail: "password is required"},
+			},
+			wantStatusCode: 400,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userSrv := services.NewUserSrvMock()
+			userSrv.On("Register", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+			userHandler := handlers.NewUserHandler(&userSrv)
+
+			app := fiber.New()
+			app.Post("/register", userHandler.Register)
+
+			req := httptest.NewRequest("POST", "/register", strings.NewReader(tt.body))
+			req.Header.Add("Content-Type", tt.contentType)
+			req.Header.Add("Accept", handlers.MIMEApplicationProblemJSON)
+
+			// -------------------- Act (กระทำ)--------------------
+			res, _ := app.Test(req)
+			defer res.Body.Close()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			userSrv.AssertNotCalled(t, "Register", mock.Anything, mock.Anything, mock.Anything)
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+			assert.Equal(t, handlers.MIMEApplicationProblemJSON, res.Header.Get("Content-Type"))
+
+			b, _ := io.ReadAll(res.Body)
+			resBody := models.HandProblemModel{}
+			json.Unmarshal(b, &resBody)
+			assert.Equal(t, tt.wantCode, resBody.Code)
+			assert.Equal(t, tt.wantErrors, resBody.Errors)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package handlers_test
+
+import (
+	"context"
+	"hexagonal-gotest/handlers"
+	"hexagonal-gotest/services"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestContextTimeout(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	var deadline time.Time
+	var hasDeadline bool
+	app := fiber.New()
+	app.Use(handlers.NewContextTimeout(time.Minute))
+	app.Get("/", func(c *fiber.Ctx) error {
+		deadline, hasDeadline = c.UserContext().Deadline()
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	// -------------------- Act (กระทำ)--------------------
+	res, _ := app.Test(httptest.NewRequest("GET", "/", nil))
+	defer res.Body.Close()
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.Equal(t, 204, res.StatusCode)
+	assert.True(t, hasDeadline)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 5*time.Second)
+}
+
+func TestUserContextReachesService(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	type ctxKey struct{}
+	userSrv := services.NewUserSrvMock()
+	userSrv.On("DeleteUser", mock.MatchedBy(func(ctx context.Context) bool {
+		_, hasDeadline := ctx.Deadline()
+		return ctx.Value(ctxKey{}) == "trace-id" && hasDeadline
+	}), principal, principal.UserId).Return(nil)
+
+	userHandler := handlers.NewUserHandler(&userSrv)
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.SetUserContext(context.WithValue(c.UserContext(), ctxKey{}, "trace-id"))
+		return c.Next()
+	})
+	app.Use(handlers.NewContextTimeout(time.Minute))
+	app.Use(newAuthMiddleware())
+	app.Delete("/delete/:user_id", userHandler.DeleteUser)
+
+	req := httptest.NewRequest("DELETE", "/delete/"+principal.UserId, nil)
+	req.Header.Add("Authorization", "Bearer "+token)
+
+	// -------------------- Act (กระทำ)--------------------
+	res, _ := app.Test(req)
+	defer res.Body.Close()
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.Equal(t, 200, res.StatusCode)
+	userSrv.AssertExpectations(t)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"hexagonal-gotest/models"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	MIMEApplicationProblemJSON = "application/problem+json"
+
+	problemTypePrefix = "/problems/"
+)
+
+type errorMapping struct {
+	status int
+	title  string
+}
+
+// errorTable maps domain errors to http, domain errors missing from the table are
+// client errors and anything that is not a domain error is a 500
+var errorTable = map[models.ErrorCode]errorMapping{
+	models.ErrValidation.Code:               {fiber.StatusBadRequest, "Request is invalid"},
+	models.ErrMalformedBody.Code:            {fiber.StatusBadRequest, "Malformed request body"},
+	models.ErrUnsupportedMediaType.Code:     {fiber.StatusUnsupportedMediaType, "Unsupported media type"},
+	models.ErrBodyTooLarge.Code:             {fiber.StatusRequestEntityTooLarge, "Request body is too large"},
+	models.ErrUsernameNotfound.Code:         {fiber.StatusBadRequest, "Username is required"},
+	models.ErrPasswordNotfound.Code:         {fiber.StatusBadRequest, "Password is required"},
+	models.ErrPasswordTooShort.Code:         {fiber.StatusBadRequest, "Password is too short"},
+	models.ErrPasswordTooLong.Code:          {fiber.StatusBadRequest, "Password is too long"},
+	models.ErrPasswordCharacterClass.Code:   {fiber.StatusBadRequest, "Password misses a character class"},
+	models.ErrPasswordContainsUsername.Code: {fiber.StatusBadRequest, "Password contains the username"},
+	models.ErrPasswordBanned.Code:           {fiber.StatusBadRequest, "Password is too common"},
+	models.ErrPasswordWeak.Code:             {fiber.StatusBadRequest, "Password is too weak"},
+	models.ErrPasswordReused.Code:           {fiber.StatusBadRequest, "Password was used recently"},
+	models.ErrCurrentPasswordNotfound.Code:  {fiber.StatusBadRequest, "Current password is required"},
+	models.ErrCurrentPasswordInvalid.Code:   {fiber.StatusBadRequest, "Current password is incorrect"},
+	models.ErrUsernameTooShort.Code:         {fiber.StatusBadRequest, "Username is too short"},
+	models.ErrUsernameTooLong.Code:          {fiber.StatusBadRequest, "Username is too long"},
+	models.ErrUsernameCharacters.Code:       {fiber.StatusBadRequest, "Username contains characters that are not allowed"},
+	models.ErrUsernameConfusable.Code:       {fiber.StatusBadRequest, "Username is confusable"},
+	models.ErrUsernameReserved.Code:         {fiber.StatusBadRequest, "Username is reserved"},
+	models.ErrUsernameIsExist.Code:          {fiber.StatusConflict, "Username is taken"},
+	models.ErrCredentialsChanged.Code:       {fiber.StatusConflict, "Credentials changed"},
+	models.ErrUserIdFormat.Code:             {fiber.StatusBadRequest, "Malformed user id"},
+	models.ErrUserIdIsNotExist.Code:         {fiber.StatusNotFound, "User does not exist"},
+	models.ErrListLimit.Code:                {fiber.StatusBadRequest, "Limit is out of range"},
+	models.ErrListSort.Code:                 {fiber.StatusBadRequest, "Sort is not supported"},
+	models.ErrListCursor.Code:               {fiber.StatusBadRequest, "Cursor is invalid"},
+	models.ErrDisplayNameInvalid.Code:       {fiber.StatusBadRequest, "Display name is invalid"},
+	models.ErrEmailFormat.Code:              {fiber.StatusBadRequest, "Malformed email"},
+	models.ErrLocaleFormat.Code:             {fiber.StatusBadRequest, "Malformed locale"},
+	models.ErrAttributeName.Code:            {fiber.StatusBadRequest, "Attribute name is not allowed"},
+	models.ErrAttributeTooLong.Code:         {fiber.StatusBadRequest, "Attribute is too long"},
+	models.ErrAttributesTooMany.Code:        {fiber.StatusBadRequest, "Too many attributes"},
+	models.ErrRefreshTokenNotfound.Code:     {fiber.StatusBadRequest, "Refresh token is required"},
+	models.ErrRefreshTokenIsNotExist.Code:   {fiber.StatusUnauthorized, "Unknown refresh token"},
+	models.ErrRefreshTokenIsUsed.Code:       {fiber.StatusUnauthorized, "Refresh token was already used"},
+	models.ErrResetTokenNotfound.Code:       {fiber.StatusBadRequest, "Reset token is required"},
+	models.ErrResetTokenInvalid.Code:        {fiber.StatusBadRequest, "Reset token is invalid"},
+	models.ErrInvalidCredentials.Code:       {fiber.StatusUnauthorized, "Invalid credentials"},
+	models.ErrTooManyAttempts.Code:          {fiber.StatusTooManyRequests, "Too many attempts"},
+	models.ErrAccountLocked.Code:            {fiber.StatusTooManyRequests, "Account is locked"},
+	models.ErrUnauthorized.Code:             {fiber.StatusUnauthorized, "Unauthorized"},
+	models.ErrForbidden.Code:                {fiber.StatusForbidden, "Forbidden"},
+	models.ErrUnexpected.Code:               {fiber.StatusInternalServerError, "Unexpected error"},
+}
+
+// ErrorHandler renders the errors fiber raises before a handler runs, like a body
+// over the limit, the same way handlers render theirs
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	fiberErr := &fiber.Error{}
+	if errors.As(err, &fiberErr) && fiberErr.Code == fiber.StatusRequestEntityTooLarge {
+		return errorResponse(c, models.ErrBodyTooLarge)
+	}
+
+	return fiber.DefaultErrorHandler(c, err)
+}
+
+// errorResponse writes application/problem+json to clients asking for it and the
+// legacy {"code", "message"} shape to everyone else while they migrate
+func errorResponse(c *fiber.Ctx, err error) error {
+	problem := newProblem(c, err)
+
+	if problem.Status == fiber.StatusInternalServerError {
+		log.Printf("%v %v: %v", c.Method(), c.Path(), err)
+	}
+
+	throttledErr := &models.ThrottledError{}
+	if errors.As(err, &throttledErr) {
+		c.Set(fiber.HeaderRetryAfter, retryAfterSeconds(throttledErr.RetryAfter))
+	}
+
+	if c.Accepts(fiber.MIMEApplicationJSON, MIMEApplicationProblemJSON) != MIMEApplicationProblemJSON {
+		// legacy clients only ever read one message
+		message := problem.Detail
+		if len(problem.Errors) > 0 {
+			message = problem.Errors[0].Detail
+		}
+		return c.Status(problem.Status).JSON(fiber.Map{
+			"code":    problem.Code,
+			"message": message,
+		})
+	}
+
+	body, err := json.Marshal(problem)
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, MIMEApplicationProblemJSON)
+	return c.Status(problem.Status).Send(body)
+}
+
+// newProblem never exposes a wrapped cause, only the domain error message
+func newProblem(c *fiber.Ctx, err error) models.HandProblemModel {
+	domainErr := models.ErrUnexpected
+	if !errors.As(err, &domainErr) {
+		domainErr = models.ErrUnexpected
+	}
+
+	mapping, ok := errorTable[domainErr.Code]
+	if !ok {
+		mapping = errorMapping{fiber.StatusBadRequest, "Bad request"}
+	}
+
+	problem := models.HandProblemModel{
+		Type:     problemTypePrefix + string(domainErr.Code),
+		Title:    mapping.title,
+		Status:   mapping.status,
+		Detail:   domainErr.Message,
+		Instance: c.OriginalURL(),
+		Code:     domainErr.Code,
+	}
+
+	validationErr := &models.ValidationError{}
+	if errors.As(err, &validationErr) {
+		for _, field := range validationErr.Fields {
+			problem.Errors = append(problem.Errors, models.HandProblemFieldModel{Field: field.Field, Code: field.Code, Detail: field.Message})
+		}
+	} else if domainErr.Field != "" {
+		problem.Errors = []models.HandProblemFieldModel{{Field: domainErr.Field, Code: domainErr.Code, Detail: domainErr.Message}}
+	}
+
+	return problem
+}
+
+// retryAfterSeconds rounds up so a client honouring the header is never early
+func retryAfterSeconds(d time.Duration) string {
+	seconds := int64((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
@@ -0,0 +1,200 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"hexagonal-gotest/handlers"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/services"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestErrorResponse(t *testing.T) {
+	type responseData struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	tests := []struct {
+		name            string
+		accept          string
+		srvErr          error
+		wantData        responseData
+		wantProblem     models.HandProblemModel
+		wantStatusCode  int
+		wantContentType string
+		wantRetryAfter  string
+	}{
+		// TODO: Add test cases.
+		{
+			name:            "legacy domain error",
+			srvErr:          models.ErrPasswordTooShort,
+			wantData:        responseData{Code: "password_too_short", Message: models.ErrPasswordTooShort.Error()},
+			wantStatusCode:  400,
+			wantContentType: fiber.MIMEApplicationJSON,
+		},
+		{
+			name:            "legacy wrapped cause is hidden",
+			accept:          "*/*",
+			srvErr:          models.ErrUnexpected.Wrap(errors.New("connection refused to db.internal:27017")),
+			wantData:        responseData{Code: "unexpected", Message: models.ErrUnexpected.Error()},
+			wantStatusCode:  500,
+			wantContentType: fiber.MIMEApplicationJSON,
+		},
+		{
+			name:            "legacy validation error",
+			accept:          fiber.MIMEApplicationJSON,
+			srvErr:          models.NewValidationError(models.ErrUsernameNotfound, models.ErrPasswordTooShort),
+			wantData:        responseData{Code: "validation_failed", Message: models.ErrUsernameNotfound.Error()},
+			wantStatusCode:  400,
+			wantContentType: fiber.MIMEApplicationJSON,
+		},
+		{
+			name:            "legacy throttled error sets retry-after",
+			srvErr:          models.NewThrottledError(models.ErrAccountLocked, 1500*time.Millisecond),
+			wantData:        responseData{Code: "account_locked", Message: models.ErrAccountLocked.Error()},
+			wantStatusCode:  429,
+			wantContentType: fiber.MIMEApplicationJSON,
+			wantRetryAfter:  "2",
+		},
+		{
+			name:   "problem domain error",
+			accept: handlers.MIMEApplicationProblemJSON,
+			srvErr: models.ErrPasswordTooShort,
+			wantProblem: models.HandProblemModel{
+				Type:     "/problems/password_too_short",
+				Title:    "Password is too short",
+				Status:   400,
+				Detail:   models.ErrPasswordTooShort.Error(),
+				Instance: "/register?source=test",
+				Code:     "password_too_short",
+				Errors:   []models.HandProblemFieldModel{{Field: "password", Code: "password_too_short", Detail: models.ErrPasswordTooShort.Error()}},
+			},
+			wantStatusCode:  400,
+			wantContentType: handlers.MIMEApplicationProblemJSON,
+		},
+		{
+			name:   "problem validation error",
+			accept: "application/problem+json, application/json;q=0.5",
+			srvErr: models.NewValidationError(models.ErrUsernameNotfound, models.ErrPasswordTooShort),
+			wantProblem: models.HandProblemModel{
+				Type:     "/problems/validation_failed",
+				Title:    "Request is invalid",
+				Status:   400,
+				Detail:   models.ErrValidation.Error(),
+				Instance: "/register?source=test",
+				Code:     "validation_failed",
+				Errors: []models.HandProblemFieldModel{
+					{Field: "username", Code: "username_not_found", Detail: models.ErrUsernameNotfound.Error()},
+					{Field: "password", Code: "password_too_short", Detail: models.ErrPasswordTooShort.Error()},
+				},
+			},
+			wantStatusCode:  400,
+			wantContentType: handlers.MIMEApplicationProblemJSON,
+		},
+		{
+			name:   "problem unknown error is unexpected",
+			accept: handlers.MIMEApplicationProblemJSON,
+			srvErr: errors.New("connection refused to db.internal:27017"),
+			wantProblem: models.HandProblemModel{
+				Type:     "/problems/unexpected",
+				Title:    "Unexpected error",
+				Status:   500,
+				Detail:   models.ErrUnexpected.Error(),
+				Instance: "/register?source=test",
+				Code:     "unexpected",
+			},
+			wantStatusCode:  500,
+			wantContentType: handlers.MIMEApplicationProblemJSON,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userSrv := services.NewUserSrvMock()
+			userSrv.On("Register", mock.Anything, "admin", "admin01").Return(tt.srvErr)
+
+			userHandler := handlers.NewUserHandler(&userSrv)
+
+			app := fiber.New()
+			app.Post("/register", userHandler.Register)
+
+			req := httptest.NewRequest("POST", "/register?source=test", strings.NewReader(`{"username":"admin","password":"admin01"}`))
+			req.Header.Add("Content-Type", "application/json")
+			if tt.accept != "" {
+				req.Header.Add("Accept", tt.accept)
+			}
+
+			// -------------------- Act (กระทำ)--------------------
+			res, _ := app.Test(req)
+			defer res.Body.Close()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+			assert.Equal(t, tt.wantContentType, res.Header.Get("Content-Type"))
+			assert.Equal(t, tt.wantRetryAfter, res.Header.Get("Retry-After"))
+
+			b, _ := io.ReadAll(res.Body)
+			assert.NotContains(t, string(b), "db.internal")
+			if tt.wantContentType == handlers.MIMEApplicationProblemJSON {
+				resBody := models.HandProblemModel{}
+				json.Unmarshal(b, &resBody)
+				assert.Equal(t, tt.wantProblem, resBody)
+			} else {
+				resBody := responseData{}
+				json.Unmarshal(b, &resBody)
+				assert.Equal(t, tt.wantData, resBody)
+			}
+		})
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantBody       string
+		wantStatusCode int
+	}{
+		// TODO: Add test cases.
+		{
+			name:           "body too large",
+			err:            fiber.ErrRequestEntityTooLarge,
+			wantBody:       `{"code":"body_too_large","message":"request body is too large"}`,
+			wantStatusCode: 413,
+		},
+		{
+			name:           "other fiber error",
+			err:            fiber.ErrMethodNotAllowed,
+			wantBody:       "Method Not Allowed",
+			wantStatusCode: 405,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			// fasthttp rejects an oversized body before routing and hands fiber.ErrRequestEntityTooLarge to the ErrorHandler
+			app := fiber.New(fiber.Config{ErrorHandler: handlers.ErrorHandler})
+			app.Post("/register", func(c *fiber.Ctx) error { return tt.err })
+
+			req := httptest.NewRequest("POST", "/register", nil)
+
+			// -------------------- Act (กระทำ)--------------------
+			res, _ := app.Test(req)
+			defer res.Body.Close()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+
+			b, _ := io.ReadAll(res.Body)
+			assert.Equal(t, tt.wantBody, string(b))
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type passwordResetHandler struct {
+	passwordResetSrv services.PasswordResetService
+}
+
+func NewPasswordResetHandler(passwordResetSrv services.PasswordResetService) passwordResetHandler {
+	return passwordResetHandler{passwordResetSrv}
+}
+
+// Forgot answers 202 whether or not a mail goes out, the client cannot tell either
+func (h passwordResetHandler) Forgot(c *fiber.Ctx) error {
+	body := models.HandForgotPasswordBodyModel{}
+	if err := parseBody(c, &body); err != nil {
+		return errorResponse(c, err)
+	}
+
+	err := h.passwordResetSrv.Forgot(c.UserContext(), body.Username, c.IP())
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "forgot password success",
+	})
+}
+
+func (h passwordResetHandler) Reset(c *fiber.Ctx) error {
+	body := models.HandResetPasswordBodyModel{}
+	if err := parseBody(c, &body); err != nil {
+		return errorResponse(c, err)
+	}
+
+	err := h.passwordResetSrv.Reset(c.UserContext(), body.Token, body.Password)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "reset password success",
+	})
+}
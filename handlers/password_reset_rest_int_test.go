@@ -0,0 +1,145 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hexagonal-gotest/handlers"
+	"hexagonal-gotest/mailers"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"hexagonal-gotest/services"
+	"io"
+	"log"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordResetIntegration(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	userRepo := repositories.NewUserMemoryRepository()
+	tokenSrv := newTokenService(userRepo)
+	userSrv := services.NewUserService(userRepo, repositories.NewUsernameHistoryMemoryRepository(), tokenSrv, newThrottleService(), hasher, usernamePolicy, passwordPolicy, services.UserConfig{PasswordHistory: 2})
+	mails := bytes.Buffer{}
+	mailer := mailers.NewLogMailer(log.New(&mails, "", 0))
+	passwordResetSrv := services.NewPasswordResetService(repositories.NewPasswordResetMemoryRepository(), userRepo, userSrv, newThrottleService(), mailer, usernamePolicy, services.PasswordResetConfig{TokenTTL: time.Hour, SendTimeout: time.Second, MaxPending: 4})
+	userHandler := handlers.NewUserHandler(userSrv)
+	tokenHandler := handlers.NewTokenHandler(tokenSrv)
+	passwordResetHandler := handlers.NewPasswordResetHandler(passwordResetSrv)
+
+	app := fiber.New()
+	app.Post("/register", userHandler.Register)
+	app.Post("/login", userHandler.Login)
+	app.Post("/token/refresh", tokenHandler.Refresh)
+	app.Post("/password/forgot", passwordResetHandler.Forgot)
+	app.Post("/password/reset", passwordResetHandler.Reset)
+
+	type tokens struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	post := func(path, body string) (status int, result tokens) {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		res, _ := app.Test(req, -1)
+		defer res.Body.Close()
+		json.NewDecoder(res.Body).Decode(&result)
+		return res.StatusCode, result
+	}
+
+	post("/register", `{"username":"admin","password":"admin01"}`)
+	post("/register", `{"username":"other","password":"other01"}`)
+	_, session := post("/login", `{"username":"admin","password":"admin01"}`)
+	users, _ := userRepo.Gets(context.Background(), models.RepoGetUserModel{Username: "admin"})
+	email := "admin@example.com"
+	userRepo.Update(context.Background(), users[0].UserId, models.RepoUpdateUserModel{Email: &email})
+
+	// -------------------- Act (กระทำ)--------------------
+	statusUnknown, _ := post("/password/forgot", `{"username":"nobody"}`)
+	statusNoEmail, _ := post("/password/forgot", `{"username":"other"}`)
+	passwordResetSrv.Wait()
+	mailsBefore := mails.Len()
+	statusForgot, _ := post("/password/forgot", `{"username":"admin"}`)
+	passwordResetSrv.Wait()
+	token := ""
+	if match := regexp.MustCompile(`reset token is (\S+)`).FindStringSubmatch(mails.String()); match != nil {
+		token = match[1]
+	}
+	statusWeak, _ := post("/password/reset", fmt.Sprintf(`{"token":%q,"password":"123"}`, token))
+	statusReset, _ := post("/password/reset", fmt.Sprintf(`{"token":%q,"password":"admin02"}`, token))
+	statusReplay, _ := post("/password/reset", fmt.Sprintf(`{"token":%q,"password":"admin03"}`, token))
+	statusRefresh, _ := post("/token/refresh", fmt.Sprintf(`{"refresh_token":%q}`, session.RefreshToken))
+	statusOldLogin, _ := post("/login", `{"username":"admin","password":"admin01"}`)
+	statusNewLogin, _ := post("/login", `{"username":"admin","password":"admin02"}`)
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.Equal(t, fiber.StatusAccepted, statusUnknown)
+	assert.Equal(t, fiber.StatusAccepted, statusNoEmail)
+	assert.Equal(t, 0, mailsBefore, "unknown accounts and accounts without an email get no mail")
+	assert.Equal(t, fiber.StatusAccepted, statusForgot)
+	assert.Contains(t, mails.String(), "mail to admin@example.com")
+	assert.NotEmpty(t, token)
+	assert.Equal(t, fiber.StatusBadRequest, statusWeak)
+	assert.Equal(t, fiber.StatusOK, statusReset, "a refused password does not use up the token")
+	assert.Equal(t, fiber.StatusBadRequest, statusReplay, "the token works once")
+	assert.Equal(t, fiber.StatusUnauthorized, statusRefresh, "every session ends")
+	assert.Equal(t, fiber.StatusUnauthorized, statusOldLogin)
+	assert.Equal(t, fiber.StatusOK, statusNewLogin)
+}
+
+func TestForgotPasswordThrottleIntegration(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	userRepo := repositories.NewUserMemoryRepository()
+	throttleSrv := newThrottleService()
+	userSrv := services.NewUserService(userRepo, repositories.NewUsernameHistoryMemoryRepository(), newTokenService(userRepo), throttleSrv, hasher, usernamePolicy, passwordPolicy, services.UserConfig{})
+	mails := bytes.Buffer{}
+	mailer := mailers.NewLogMailer(log.New(&mails, "", 0))
+	passwordResetSrv := services.NewPasswordResetService(repositories.NewPasswordResetMemoryRepository(), userRepo, userSrv, throttleSrv, mailer, usernamePolicy, services.PasswordResetConfig{TokenTTL: time.Hour, MaxPending: 4})
+	userHandler := handlers.NewUserHandler(userSrv)
+	passwordResetHandler := handlers.NewPasswordResetHandler(passwordResetSrv)
+
+	app := fiber.New()
+	app.Post("/register", userHandler.Register)
+	app.Post("/login", userHandler.Login)
+	app.Post("/password/forgot", passwordResetHandler.Forgot)
+
+	post := func(path, body string) (status int, result string) {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		res, _ := app.Test(req, -1)
+		defer res.Body.Close()
+		b, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(b)
+	}
+
+	post("/register", `{"username":"admin","password":"admin01"}`)
+	users, _ := userRepo.Gets(context.Background(), models.RepoGetUserModel{Username: "admin"})
+	email := "admin@example.com"
+	userRepo.Update(context.Background(), users[0].UserId, models.RepoUpdateUserModel{Email: &email})
+
+	// -------------------- Act (กระทำ)--------------------
+	statusUnknown, bodyUnknown := post("/password/forgot", `{"username":"nobody"}`)
+	statuses := []int{}
+	bodies := []string{}
+	for i := 0; i < 5; i++ {
+		status, body := post("/password/forgot", `{"username":"Admin"}`)
+		statuses = append(statuses, status)
+		bodies = append(bodies, body)
+	}
+	passwordResetSrv.Wait()
+	statusLogin, _ := post("/login", `{"username":"admin","password":"admin01"}`)
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.Equal(t, fiber.StatusAccepted, statusUnknown)
+	assert.Equal(t, []int{fiber.StatusAccepted, fiber.StatusAccepted, fiber.StatusTooManyRequests, fiber.StatusTooManyRequests, fiber.StatusTooManyRequests}, statuses)
+	assert.Equal(t, bodyUnknown, bodies[0], "a known username answers like an unknown one")
+	assert.Equal(t, 2, strings.Count(mails.String(), "mail to admin@example.com"), "mails stop once the username is throttled")
+	assert.Equal(t, fiber.StatusOK, statusLogin, "forgot requests do not count against the login")
+}
@@ -0,0 +1,208 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"hexagonal-gotest/handlers"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/services"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestForgotPassword(t *testing.T) {
+	type reqBody struct {
+		Username string `json:"username"`
+	}
+	type responseData struct {
+		Code    models.ErrorCode `json:"code"`
+		Message string           `json:"message"`
+	}
+	tests := []struct {
+		name           string
+		srvErr         error
+		body           reqBody
+		wantData       responseData
+		wantStatusCode int
+	}{
+		// TODO: Add test cases.
+		{
+			name: "error400",
+			body: reqBody{Username: ""},
+			wantData: responseData{
+				Code:    models.ErrValidation.Code,
+				Message: models.ErrFieldRequired.For("username").Error(),
+			},
+			wantStatusCode: 400,
+		},
+		{
+			name:   "error429",
+			srvErr: models.NewThrottledError(models.ErrTooManyAttempts, time.Second),
+			body:   reqBody{Username: "admin"},
+			wantData: responseData{
+				Code:    models.ErrTooManyAttempts.Code,
+				Message: models.ErrTooManyAttempts.Error(),
+			},
+			wantStatusCode: 429,
+		},
+		{
+			name:   "error500",
+			srvErr: models.ErrUnexpected,
+			body:   reqBody{Username: "admin"},
+			wantData: responseData{
+				Code:    models.ErrUnexpected.Code,
+				Message: models.ErrUnexpected.Error(),
+			},
+			wantStatusCode: 500,
+		},
+		{
+			name: "success",
+			body: reqBody{Username: "admin"},
+			wantData: responseData{
+				Message: "forgot password success",
+			},
+			wantStatusCode: 202,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			passwordResetSrv := services.NewPasswordResetSrvMock()
+
+			// mock forgot service, app.Test requests come from 0.0.0.0
+			passwordResetSrv.On("Forgot", mock.Anything, tt.body.Username, "0.0.0.0").Return(tt.srvErr)
+
+			passwordResetHandler := handlers.NewPasswordResetHandler(&passwordResetSrv)
+
+			// http request
+			app := fiber.New()
+			app.Post("/password/forgot", passwordResetHandler.Forgot)
+
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest("POST", "/password/forgot", bytes.NewBuffer(body))
+			req.Header.Add("Content-Type", "application/json")
+
+			// -------------------- Act (กระทำ)--------------------
+			res, _ := app.Test(req)
+			defer res.Body.Close()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			// the handler rejects an invalid body before the service
+			switch tt.name {
+			case "error400":
+				passwordResetSrv.AssertNotCalled(t, "Forgot", mock.Anything, tt.body.Username, mock.Anything)
+			default:
+				passwordResetSrv.AssertCalled(t, "Forgot", mock.Anything, tt.body.Username, "0.0.0.0")
+			}
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+
+			b, _ := io.ReadAll(res.Body)
+			resBody := responseData{}
+			json.Unmarshal(b, &resBody)
+			assert.Equal(t, tt.wantData, resBody)
+		})
+	}
+}
+
+func TestResetPasswordWithToken(t *testing.T) {
+	type reqBody struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	type responseData struct {
+		Code    models.ErrorCode `json:"code"`
+		Message string           `json:"message"`
+	}
+	tests := []struct {
+		name           string
+		srvErr         error
+		body           reqBody
+		wantData       responseData
+		wantStatusCode int
+	}{
+		// TODO: Add test cases.
+		{
+			name: "error400",
+			body: reqBody{Token: "", Password: "admin01"},
+			wantData: responseData{
+				Code:    models.ErrValidation.Code,
+				Message: models.ErrFieldRequired.For("token").Error(),
+			},
+			wantStatusCode: 400,
+		},
+		{
+			name:   "invalid token",
+			srvErr: models.ErrResetTokenInvalid,
+			body:   reqBody{Token: "reset-token", Password: "admin01"},
+			wantData: responseData{
+				Code:    models.ErrResetTokenInvalid.Code,
+				Message: models.ErrResetTokenInvalid.Error(),
+			},
+			wantStatusCode: 400,
+		},
+		{
+			name:   "error500",
+			srvErr: models.ErrUnexpected,
+			body:   reqBody{Token: "reset-token", Password: "admin01"},
+			wantData: responseData{
+				Code:    models.ErrUnexpected.Code,
+				Message: models.ErrUnexpected.Error(),
+			},
+			wantStatusCode: 500,
+		},
+		{
+			name: "success",
+			body: reqBody{Token: "reset-token", Password: "admin01"},
+			wantData: responseData{
+				Message: "reset password success",
+			},
+			wantStatusCode: 200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			passwordResetSrv := services.NewPasswordResetSrvMock()
+
+			// mock reset service
+			passwordResetSrv.On("Reset", mock.Anything, tt.body.Token, tt.body.Password).Return(tt.srvErr)
+
+			passwordResetHandler := handlers.NewPasswordResetHandler(&passwordResetSrv)
+
+			// http request
+			app := fiber.New()
+			app.Post("/password/reset", passwordResetHandler.Reset)
+
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest("POST", "/password/reset", bytes.NewBuffer(body))
+			req.Header.Add("Content-Type", "application/json")
+
+			// -------------------- Act (กระทำ)--------------------
+			res, _ := app.Test(req)
+			defer res.Body.Close()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			// the handler rejects an invalid body before the service
+			switch tt.name {
+			case "error400":
+				passwordResetSrv.AssertNotCalled(t, "Reset", mock.Anything, tt.body.Token, tt.body.Password)
+			default:
+				passwordResetSrv.AssertCalled(t, "Reset", mock.Anything, tt.body.Token, tt.body.Password)
+			}
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+
+			b, _ := io.ReadAll(res.Body)
+			resBody := responseData{}
+			json.Unmarshal(b, &resBody)
+			assert.Equal(t, tt.wantData, resBody)
+		})
+	}
+}
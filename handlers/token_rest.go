@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type tokenHandler struct {
+	tokenSrv services.TokenService
+}
+
+func NewTokenHandler(tokenSrv services.TokenService) tokenHandler {
+	return tokenHandler{tokenSrv}
+}
+
+func (h tokenHandler) Refresh(c *fiber.Ctx) error {
+	body := models.HandRefreshTokenBodyModel{}
+	if err := parseBody(c, &body); err != nil {
+		return errorResponse(c, err)
+	}
+
+	token, refreshToken, err := h.tokenSrv.Refresh(c.UserContext(), body.RefreshToken)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"token":         token,
+		"refresh_token": refreshToken,
+		"message":       "refresh token success",
+	})
+}
+
+func (h tokenHandler) Logout(c *fiber.Ctx) error {
+	body := models.HandLogoutBodyModel{}
+	if err := parseBody(c, &body); err != nil {
+		return errorResponse(c, err)
+	}
+
+	tokenData, _ := TokenData(c)
+
+	err := h.tokenSrv.Logout(c.UserContext(), tokenData, body.RefreshToken)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "logout success",
+	})
+}
+
+func (h tokenHandler) LogoutAll(c *fiber.Ctx) error {
+	tokenData, _ := TokenData(c)
+
+	err := h.tokenSrv.LogoutAll(c.UserContext(), tokenData.UserId)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "logout all success",
+	})
+}
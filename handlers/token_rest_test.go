@@ -0,0 +1,272 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"hexagonal-gotest/handlers"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/services"
+	"hexagonal-gotest/utils"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRefresh(t *testing.T) {
+	type reqBody struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	type responseData struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+		Message      string `json:"message"`
+	}
+	tests := []struct {
+		name           string
+		srvErr         error
+		body           reqBody
+		wantData       responseData
+		wantStatusCode int
+	}{
+		// TODO: Add test cases.
+		{
+			name: "error400",
+			body: reqBody{RefreshToken: ""},
+			wantData: responseData{
+				Message: models.ErrFieldRequired.For("refresh_token").Error(),
+			},
+			wantStatusCode: 400,
+		},
+		{
+			name:   "error401",
+			srvErr: models.ErrUnauthorized,
+			body:   reqBody{RefreshToken: "refresh-token"},
+			wantData: responseData{
+				Message: models.ErrUnauthorized.Error(),
+			},
+			wantStatusCode: 401,
+		},
+		{
+			name:   "error500",
+			srvErr: models.ErrUnexpected,
+			body:   reqBody{RefreshToken: "refresh-token"},
+			wantData: responseData{
+				Message: models.ErrUnexpected.Error(),
+			},
+			wantStatusCode: 500,
+		},
+		{
+			name: "success",
+			body: reqBody{RefreshToken: "refresh-token"},
+			wantData: responseData{
+				Token:        "token",
+				RefreshToken: "new-refresh-token",
+				Message:      "refresh token success",
+			},
+			wantStatusCode: 200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			tokenSrv := services.NewTokenSrvMock()
+
+			// mock refresh service
+			switch tt.name {
+			case "success":
+				tokenSrv.On("Refresh", mock.Anything, tt.body.RefreshToken).Return(tt.wantData.Token, tt.wantData.RefreshToken, nil)
+			default:
+				tokenSrv.On("Refresh", mock.Anything, tt.body.RefreshToken).Return("", "", tt.srvErr)
+			}
+
+			tokenHandler := handlers.NewTokenHandler(&tokenSrv)
+
+			// http request
+			app := fiber.New()
+			app.Post("/token/refresh", tokenHandler.Refresh)
+
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest("POST", "/token/refresh", bytes.NewBuffer(body))
+			req.Header.Add("Content-Type", "application/json")
+
+			// -------------------- Act (กระทำ)--------------------
+			res, _ := app.Test(req)
+			defer res.Body.Close()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			// the handler rejects an invalid body before the service
+			switch tt.name {
+			case "error400":
+				tokenSrv.AssertNotCalled(t, "Refresh", mock.Anything, tt.body.RefreshToken)
+			default:
+				tokenSrv.AssertCalled(t, "Refresh", mock.Anything, tt.body.RefreshToken)
+			}
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+
+			b, _ := io.ReadAll(res.Body)
+			resBody := responseData{}
+			json.Unmarshal(b, &resBody)
+			assert.Equal(t, tt.wantData, resBody)
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	type reqBody struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	type responseData struct {
+		Message string `json:"message"`
+	}
+	tokenData := utils.TokenDataModel{UserId: principal.UserId, Username: principal.Username}
+	tests := []struct {
+		name           string
+		srvErr         error
+		body           reqBody
+		wantData       responseData
+		wantStatusCode int
+	}{
+		// TODO: Add test cases.
+		{
+			name:   "error401",
+			srvErr: models.ErrUnauthorized,
+			body:   reqBody{RefreshToken: "refresh-token"},
+			wantData: responseData{
+				Message: models.ErrUnauthorized.Error(),
+			},
+			wantStatusCode: 401,
+		},
+		{
+			name:   "error500",
+			srvErr: models.ErrUnexpected,
+			body:   reqBody{RefreshToken: "refresh-token"},
+			wantData: responseData{
+				Message: models.ErrUnexpected.Error(),
+			},
+			wantStatusCode: 500,
+		},
+		{
+			name: "success",
+			body: reqBody{RefreshToken: "refresh-token"},
+			wantData: responseData{
+				Message: "logout success",
+			},
+			wantStatusCode: 200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			tokenSrv := services.NewTokenSrvMock()
+			tokenSrv.On("Verify", mock.Anything, token).Return(tokenData, nil)
+
+			// mock logout service
+			switch tt.name {
+			case "success":
+				tokenSrv.On("Logout", mock.Anything, tokenData, tt.body.RefreshToken).Return(nil)
+			default:
+				tokenSrv.On("Logout", mock.Anything, tokenData, tt.body.RefreshToken).Return(tt.srvErr)
+			}
+
+			tokenHandler := handlers.NewTokenHandler(&tokenSrv)
+
+			// http request
+			app := fiber.New()
+			app.Use(handlers.NewAuthMiddleware(&tokenSrv, "").Handle)
+			app.Post("/logout", tokenHandler.Logout)
+
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest("POST", "/logout", bytes.NewBuffer(body))
+			req.Header.Add("Content-Type", "application/json")
+			req.Header.Add("Authorization", "Bearer "+token)
+
+			// -------------------- Act (กระทำ)--------------------
+			res, _ := app.Test(req)
+			defer res.Body.Close()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			tokenSrv.AssertCalled(t, "Logout", mock.Anything, tokenData, tt.body.RefreshToken)
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+
+			b, _ := io.ReadAll(res.Body)
+			resBody := responseData{}
+			json.Unmarshal(b, &resBody)
+			assert.Equal(t, tt.wantData, resBody)
+		})
+	}
+}
+
+func TestLogoutAll(t *testing.T) {
+	type responseData struct {
+		Message string `json:"message"`
+	}
+	tokenData := utils.TokenDataModel{UserId: principal.UserId, Username: principal.Username}
+	tests := []struct {
+		name           string
+		srvErr         error
+		wantData       responseData
+		wantStatusCode int
+	}{
+		// TODO: Add test cases.
+		{
+			name:   "error500",
+			srvErr: models.ErrUnexpected,
+			wantData: responseData{
+				Message: models.ErrUnexpected.Error(),
+			},
+			wantStatusCode: 500,
+		},
+		{
+			name: "success",
+			wantData: responseData{
+				Message: "logout all success",
+			},
+			wantStatusCode: 200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			tokenSrv := services.NewTokenSrvMock()
+			tokenSrv.On("Verify", mock.Anything, token).Return(tokenData, nil)
+
+			// mock logout all service
+			switch tt.name {
+			case "success":
+				tokenSrv.On("LogoutAll", mock.Anything, tokenData.UserId).Return(nil)
+			default:
+				tokenSrv.On("LogoutAll", mock.Anything, tokenData.UserId).Return(tt.srvErr)
+			}
+
+			tokenHandler := handlers.NewTokenHandler(&tokenSrv)
+
+			// http request
+			app := fiber.New()
+			app.Use(handlers.NewAuthMiddleware(&tokenSrv, "").Handle)
+			app.Post("/logout/all", tokenHandler.LogoutAll)
+
+			req := httptest.NewRequest("POST", "/logout/all", nil)
+			req.Header.Add("Authorization", "Bearer "+token)
+
+			// -------------------- Act (กระทำ)--------------------
+			res, _ := app.Test(req)
+			defer res.Body.Close()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			tokenSrv.AssertCalled(t, "LogoutAll", mock.Anything, tokenData.UserId)
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+
+			b, _ := io.ReadAll(res.Body)
+			resBody := responseData{}
+			json.Unmarshal(b, &resBody)
+			assert.Equal(t, tt.wantData, resBody)
+		})
+	}
+}
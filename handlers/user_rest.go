@@ -3,6 +3,7 @@ package handlers
 import (
 	"hexagonal-gotest/models"
 	"hexagonal-gotest/services"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -17,21 +18,13 @@ func NewUserHandler(userSrv services.UserService) userHandler {
 
 func (h userHandler) Register(c *fiber.Ctx) error {
 	body := models.HandRegisterBodyModel{}
-	c.BodyParser(&body)
+	if err := parseBody(c, &body); err != nil {
+		return errorResponse(c, err)
+	}
 
-	err := h.userSrv.Register(body.Username, body.Password)
+	err := h.userSrv.Register(c.UserContext(), body.Username, body.Password)
 	if err != nil {
-		switch err.Error() {
-		case models.ErrUnexpected:
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"message": err.Error(),
-			})
-
-		default:
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"message": err.Error(),
-			})
-		}
+		return errorResponse(c, err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -41,84 +34,211 @@ func (h userHandler) Register(c *fiber.Ctx) error {
 
 func (h userHandler) Login(c *fiber.Ctx) error {
 	body := models.HandLoginBodyModel{}
-	c.BodyParser(&body)
+	if err := parseBody(c, &body); err != nil {
+		return errorResponse(c, err)
+	}
 
-	token, err := h.userSrv.Login(body.Username, body.Password)
+	token, refreshToken, err := h.userSrv.Login(c.UserContext(), body.Username, body.Password, c.IP())
 	if err != nil {
-		switch err.Error() {
-		case models.ErrUsernameIsNotExist, models.ErrUnauthorized:
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"token":   token,
-				"message": err.Error(),
-			})
-
-		case models.ErrUnexpected:
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"token":   token,
-				"message": err.Error(),
-			})
-
-		default:
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"token":   token,
-				"message": err.Error(),
-			})
-		}
+		return errorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"token":         token,
+		"refresh_token": refreshToken,
+		"message":       "login success",
+	})
+}
+
+// ChangePassword answers with new tokens, the ones the request came with stop working
+func (h userHandler) ChangePassword(c *fiber.Ctx) error {
+	body := models.HandChangePasswordBodyModel{}
+	if err := parseBody(c, &body); err != nil {
+		return errorResponse(c, err)
+	}
+
+	token, refreshToken, err := h.userSrv.ChangePassword(c.UserContext(), principal(c), body.CurrentPassword, body.Password, c.IP())
+	if err != nil {
+		return errorResponse(c, err)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"token":   token,
-		"message": "login success",
+		"token":         token,
+		"refresh_token": refreshToken,
+		"message":       "change password success",
 	})
 }
 
-func (h userHandler) ResetPassword(c *fiber.Ctx) error {
-	params := models.HandResetPasswordParamsModel{}
-	c.ParamsParser(&params)
+func (h userHandler) ChangeUsername(c *fiber.Ctx) error {
+	params := models.HandChangeUsernameParamsModel{}
+	if err := parseParams(c, &params); err != nil {
+		return errorResponse(c, err)
+	}
+
+	body := models.HandChangeUsernameBodyModel{}
+	if err := parseBody(c, &body); err != nil {
+		return errorResponse(c, err)
+	}
+
+	token, refreshToken, err := h.userSrv.ChangeUsername(c.UserContext(), principal(c), params.UserId, body.Username)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	// tokens are only issued when the caller renamed their own account
+	response := fiber.Map{
+		"message": "change username success",
+	}
+	if token != "" {
+		response["token"] = token
+		response["refresh_token"] = refreshToken
+	}
 
-	body := models.HandResetPasswordBodyModel{}
-	c.BodyParser(&body)
+	return c.Status(fiber.StatusOK).JSON(response)
+}
 
-	err := h.userSrv.ResetPassword(params.UserId, body.Password)
+func (h userHandler) GetUser(c *fiber.Ctx) error {
+	params := models.HandGetUserParamsModel{}
+	if err := parseParams(c, &params); err != nil {
+		return errorResponse(c, err)
+	}
+
+	user, err := h.userSrv.GetUser(c.UserContext(), principal(c), params.UserId)
 	if err != nil {
-		switch err.Error() {
-		case models.ErrUnexpected:
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"message": err.Error(),
-			})
-
-		default:
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"message": err.Error(),
-			})
+		return errorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newHandUser(user))
+}
+
+func (h userHandler) ListUsers(c *fiber.Ctx) error {
+	query := models.HandListUsersQueryModel{}
+	if err := parseQuery(c, &query); err != nil {
+		return errorResponse(c, err)
+	}
+
+	limit := 0
+	if query.Limit != "" {
+		var err error
+		// digits only after validation, so the only failure left is overflow
+		if limit, err = strconv.Atoi(query.Limit); err != nil {
+			return errorResponse(c, models.ErrListLimit)
 		}
 	}
 
+	page, err := h.userSrv.ListUsers(c.UserContext(), principal(c), models.SrvListUsersModel{
+		UsernamePrefix: query.UsernamePrefix,
+		Sort:           query.Sort,
+		Cursor:         query.Cursor,
+		Limit:          limit,
+	})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	res := models.HandUserPageModel{Users: make([]models.HandUserModel, 0, len(page.Users)), NextCursor: page.NextCursor}
+	for _, user := range page.Users {
+		res.Users = append(res.Users, newHandUser(user))
+	}
+	return c.Status(fiber.StatusOK).JSON(res)
+}
+
+func (h userHandler) GetProfile(c *fiber.Ctx) error {
+	params := models.HandProfileParamsModel{}
+	if err := parseParams(c, &params); err != nil {
+		return errorResponse(c, err)
+	}
+
+	profile, err := h.userSrv.GetProfile(c.UserContext(), principal(c), params.UserId)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newHandProfile(profile))
+}
+
+func (h userHandler) UpdateProfile(c *fiber.Ctx) error {
+	params := models.HandProfileParamsModel{}
+	if err := parseParams(c, &params); err != nil {
+		return errorResponse(c, err)
+	}
+
+	body := models.HandUpdateProfileBodyModel{}
+	if err := parseBody(c, &body); err != nil {
+		return errorResponse(c, err)
+	}
+
+	profile, err := h.userSrv.UpdateProfile(c.UserContext(), principal(c), params.UserId, models.SrvUpdateProfileModel{
+		DisplayName: body.DisplayName,
+		Email:       body.Email,
+		Locale:      body.Locale,
+		Attributes:  body.Attributes,
+	})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newHandProfile(profile))
+}
+
+func (h userHandler) UnlockUser(c *fiber.Ctx) error {
+	params := models.HandUnlockUserParamsModel{}
+	if err := parseParams(c, &params); err != nil {
+		return errorResponse(c, err)
+	}
+
+	err := h.userSrv.UnlockUser(c.UserContext(), principal(c), params.UserId)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "reset password success",
+		"message": "unlock user success",
 	})
 }
 
 func (h userHandler) DeleteUser(c *fiber.Ctx) error {
 	params := models.HandDeleteUserParamsModel{}
-	c.ParamsParser(&params)
+	if err := parseParams(c, &params); err != nil {
+		return errorResponse(c, err)
+	}
 
-	err := h.userSrv.DeleteUser(params.UserId)
+	err := h.userSrv.DeleteUser(c.UserContext(), principal(c), params.UserId)
 	if err != nil {
-		switch err.Error() {
-		case models.ErrUnexpected:
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"message": err.Error(),
-			})
-
-		default:
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"message": err.Error(),
-			})
-		}
+		return errorResponse(c, err)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "delete user success",
 	})
 }
+
+func newHandUser(user models.SrvUserModel) models.HandUserModel {
+	return models.HandUserModel{UserId: user.UserId, Username: user.Username, Role: user.Role, CreatedAt: user.CreatedAt}
+}
+
+func newHandProfile(profile models.SrvProfileModel) models.HandProfileModel {
+	res := models.HandProfileModel{
+		UserId:      profile.UserId,
+		Username:    profile.Username,
+		DisplayName: profile.DisplayName,
+		Email:       profile.Email,
+		Locale:      profile.Locale,
+		Attributes:  map[string]string{},
+		CreatedAt:   profile.CreatedAt,
+		UpdatedAt:   profile.UpdatedAt,
+	}
+	for key, value := range profile.Attributes {
+		res.Attributes[key] = value
+	}
+	if !profile.LastLoginAt.IsZero() {
+		res.LastLoginAt = &profile.LastLoginAt
+	}
+	return res
+}
+
+// principal converts the token data stored by authMiddleware, it is empty on public routes
+func principal(c *fiber.Ctx) models.SrvPrincipalModel {
+	tokenData, _ := TokenData(c)
+	return models.SrvPrincipalModel{UserId: tokenData.UserId, Username: tokenData.Username, Role: tokenData.Role, CredentialVersion: tokenData.CredentialVersion}
+}
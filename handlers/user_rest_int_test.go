@@ -2,23 +2,71 @@ package handlers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hexagonal-gotest/config"
 	"hexagonal-gotest/handlers"
+	"hexagonal-gotest/hashers"
 	"hexagonal-gotest/models"
+	"hexagonal-gotest/policies"
 	"hexagonal-gotest/repositories"
 	"hexagonal-gotest/services"
+	"hexagonal-gotest/utils"
 	"io"
+	"net/http"
 	"net/http/httptest"
+	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	hasher         = hashers.NewBcryptHasher(bcrypt.MinCost)
+	usernamePolicy = policies.NewUsernamePolicy(policies.UsernamePolicyConfig{MinLength: 1, MaxLength: 32})
+	passwordPolicy = policies.NewPasswordPolicy(policies.PasswordPolicyConfig{MinLength: 6, MaxLength: 16})
+)
+
+func newTokenService(userRepo repositories.UserRepository) services.TokenService {
+	return services.NewTokenService(userRepo, repositories.NewRefreshTokenMemoryRepository(), repositories.NewRevokedTokenMemoryRepository(), testJWT, time.Hour)
+}
+
+// newThrottleService throttles like a server started with the default config
+func newThrottleService() services.ThrottleService {
+	defaults := config.Default().Throttle
+	return services.NewThrottleService(repositories.NewLoginAttemptMemoryRepository(), services.ThrottleConfig{
+		Window:           defaults.Window,
+		DelayAfter:       defaults.DelayAfter,
+		BaseDelay:        defaults.BaseDelay,
+		MaxDelay:         defaults.MaxDelay,
+		LockoutThreshold: defaults.LockoutThreshold,
+		LockoutDuration:  defaults.LockoutDuration,
+		IPLimit:          defaults.IPLimit,
+	})
+}
+
+// newAuthMiddleware accepts any token testJWT signed, its user exists and never changed the password
+func newAuthMiddleware() fiber.Handler {
+	userRepo := repositories.NewUserRepoMock()
+	userRepo.On("Gets", mock.Anything, mock.AnythingOfType("models.RepoGetUserModel")).Return([]models.RepoUserModel{{}}, nil)
+	return handlers.NewAuthMiddleware(newTokenService(&userRepo), "").Handle
+}
+
+func isHashOf(password, hash string) bool {
+	ok, _, _ := hasher.Verify(password, hash)
+	return ok
+}
+
 func TestRegisterIntegration(t *testing.T) {
 	type reqBody struct {
 		Username string `json:"username"`
@@ -38,7 +86,7 @@ func TestRegisterIntegration(t *testing.T) {
 			name: "error1",
 			body: reqBody{Username: "", Password: "admin"},
 			wantData: responseData{
-				Message: models.ErrUsernameNotfound,
+				Message: models.ErrFieldRequired.For("username").Error(),
 			},
 			wantStatusCode: 400,
 		},
@@ -46,7 +94,7 @@ func TestRegisterIntegration(t *testing.T) {
 			name: "error2",
 			body: reqBody{Username: "admin", Password: ""},
 			wantData: responseData{
-				Message: models.ErrPasswordNotfound,
+				Message: models.ErrFieldRequired.For("password").Error(),
 			},
 			wantStatusCode: 400,
 		},
@@ -54,7 +102,7 @@ func TestRegisterIntegration(t *testing.T) {
 			name: "error3",
 			body: reqBody{Username: "admin", Password: "123"},
 			wantData: responseData{
-				Message: models.ErrPasswordFormat,
+				Message: "password must be at least 6 characters",
 			},
 			wantStatusCode: 400,
 		},
@@ -62,7 +110,7 @@ func TestRegisterIntegration(t *testing.T) {
 			name: "error4",
 			body: reqBody{Username: "admin", Password: "123456789123456789"},
 			wantData: responseData{
-				Message: models.ErrPasswordFormat,
+				Message: "password must be at most 16 characters",
 			},
 			wantStatusCode: 400,
 		},
@@ -70,15 +118,15 @@ func TestRegisterIntegration(t *testing.T) {
 			name: "error5",
 			body: reqBody{Username: "admin", Password: "admin01"},
 			wantData: responseData{
-				Message: models.ErrUsernameIsExist,
+				Message: models.ErrUsernameIsExist.Error(),
 			},
-			wantStatusCode: 400,
+			wantStatusCode: 409,
 		},
 		{
 			name: "error500",
 			body: reqBody{Username: "admin", Password: "admin01"},
 			wantData: responseData{
-				Message: models.ErrUnexpected,
+				Message: models.ErrUnexpected.Error(),
 			},
 			wantStatusCode: 500,
 		},
@@ -99,15 +147,15 @@ func TestRegisterIntegration(t *testing.T) {
 			// mock Gets user
 			switch tt.name {
 			case "error5":
-				userRepo.On("Gets", mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
+				userRepo.On("Gets", mock.Anything, mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
 					return filter.Username == tt.body.Username
 				})).Return([]models.RepoUserModel{
 					{Username: tt.body.Username},
 				}, nil)
 			case "error500":
-				userRepo.On("Gets", mock.AnythingOfType("models.RepoGetUserModel")).Return(nil, errors.New(""))
+				userRepo.On("Gets", mock.Anything, mock.AnythingOfType("models.RepoGetUserModel")).Return(nil, errors.New(""))
 			default:
-				userRepo.On("Gets", mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
+				userRepo.On("Gets", mock.Anything, mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
 					return filter.Username == tt.body.Username
 				})).Return([]models.RepoUserModel{}, nil)
 			}
@@ -115,15 +163,15 @@ func TestRegisterIntegration(t *testing.T) {
 			// mock Create user
 			switch tt.name {
 			case "error500":
-				userRepo.On("Create", mock.AnythingOfType("models.RepoCreateUserModel")).Return(errors.New(""))
+				userRepo.On("Create", mock.Anything, mock.AnythingOfType("models.RepoCreateUserModel")).Return(errors.New(""))
 			default:
-				userRepo.On("Create", mock.MatchedBy(func(payload models.RepoCreateUserModel) bool {
+				userRepo.On("Create", mock.Anything, mock.MatchedBy(func(payload models.RepoCreateUserModel) bool {
 					_, errUUID := uuid.Parse(payload.UserId)
-					return payload.Username == tt.body.Username && payload.Password == tt.body.Password && errUUID == nil
+					return payload.Username == tt.body.Username && isHashOf(tt.body.Password, payload.Password) && errUUID == nil
 				})).Return(nil)
 			}
 
-			userSrv := services.NewUserService(&userRepo)
+			userSrv := services.NewUserService(&userRepo, repositories.NewUsernameHistoryMemoryRepository(), newTokenService(&userRepo), newThrottleService(), hasher, usernamePolicy, passwordPolicy, services.UserConfig{})
 
 			userHandler := handlers.NewUserHandler(userSrv)
 
@@ -151,6 +199,135 @@ func TestRegisterIntegration(t *testing.T) {
 	}
 }
 
+// TestRegisterConcurrentIntegration registrations racing past the existence check must be
+// stopped by the repository, for mongo that is the unique username index
+func TestRegisterConcurrentIntegration(t *testing.T) {
+	type responseData struct {
+		Code models.ErrorCode `json:"code"`
+	}
+	tests := []struct {
+		name     string
+		userRepo func(t *testing.T) repositories.UserRepository
+	}{
+		// TODO: Add test cases.
+		{
+			name:     "memory",
+			userRepo: func(t *testing.T) repositories.UserRepository { return repositories.NewUserMemoryRepository() },
+		},
+		{
+			name:     "mongo",
+			userRepo: newMongoUserRepository,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userRepo := tt.userRepo(t)
+			userSrv := services.NewUserService(userRepo, repositories.NewUsernameHistoryMemoryRepository(), newTokenService(userRepo), newThrottleService(), hasher, usernamePolicy, passwordPolicy, services.UserConfig{})
+			userHandler := handlers.NewUserHandler(userSrv)
+
+			app := fiber.New()
+			app.Post("/register", userHandler.Register)
+
+			// -------------------- Act (กระทำ)--------------------
+			statusCodes := make([]int, 20)
+			codes := make([]models.ErrorCode, 20)
+			var wg sync.WaitGroup
+			for i := range statusCodes {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					req := httptest.NewRequest("POST", "/register", bytes.NewBufferString(`{"username":"admin","password":"admin01"}`))
+					req.Header.Add("Content-Type", "application/json")
+					res, err := app.Test(req, -1)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					defer res.Body.Close()
+					resBody := responseData{}
+					json.NewDecoder(res.Body).Decode(&resBody)
+					statusCodes[i], codes[i] = res.StatusCode, resBody.Code
+				}(i)
+			}
+			wg.Wait()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			created := 0
+			for i, statusCode := range statusCodes {
+				if statusCode == fiber.StatusCreated {
+					created++
+				} else {
+					assert.Equal(t, fiber.StatusConflict, statusCode)
+					assert.Equal(t, models.ErrUsernameIsExist.Code, codes[i])
+				}
+			}
+			assert.Equal(t, 1, created)
+
+			users, _ := userRepo.Gets(context.Background(), models.RepoGetUserModel{Username: "admin"})
+			assert.Len(t, users, 1)
+		})
+	}
+}
+
+// newMongoUserRepository needs a real server in TEST_MONGO_URI, each test gets its own
+// collection with the indexes main creates
+func newMongoUserRepository(t *testing.T) repositories.UserRepository {
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+	db := client.Database("hexagonal_gotest_integration")
+
+	collection := "users_" + uuid.NewString()
+	t.Cleanup(func() { db.Collection(collection).Drop(context.Background()) })
+	if err := repositories.EnsureUserIndexes(ctx, db, collection); err != nil {
+		t.Fatal(err)
+	}
+	return repositories.NewUserRepository(db, collection, repositories.DefaultTimeouts)
+}
+
+// TestRegisterConcealedIntegration a taken username gets the answer of a new one
+func TestRegisterConcealedIntegration(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	userRepo := repositories.NewUserMemoryRepository()
+	userSrv := services.NewUserService(userRepo, repositories.NewUsernameHistoryMemoryRepository(), newTokenService(userRepo), newThrottleService(), hasher, usernamePolicy, passwordPolicy, services.UserConfig{ConcealRegistration: true})
+	userHandler := handlers.NewUserHandler(userSrv)
+
+	app := fiber.New()
+	app.Post("/register", userHandler.Register)
+
+	// -------------------- Act (กระทำ)--------------------
+	statusCodes := []int{}
+	bodies := []string{}
+	for _, password := range []string{"admin01", "other01"} {
+		req := httptest.NewRequest("POST", "/register", bytes.NewBufferString(fmt.Sprintf(`{"username":"admin","password":%q}`, password)))
+		req.Header.Add("Content-Type", "application/json")
+		res, _ := app.Test(req, -1)
+		b, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		statusCodes = append(statusCodes, res.StatusCode)
+		bodies = append(bodies, string(b))
+	}
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.Equal(t, []int{201, 201}, statusCodes)
+	assert.Equal(t, bodies[0], bodies[1])
+
+	users, _ := userRepo.Gets(context.Background(), models.RepoGetUserModel{Username: "admin"})
+	if assert.Len(t, users, 1) {
+		assert.True(t, isHashOf("admin01", users[0].Password))
+	}
+}
+
 func TestLoginIntegration(t *testing.T) {
 	type reqBody struct {
 		Username string `json:"username"`
@@ -170,7 +347,7 @@ func TestLoginIntegration(t *testing.T) {
 			name: "error1",
 			body: reqBody{Username: "", Password: "admin"},
 			wantData: responseData{
-				Message: models.ErrUsernameNotfound,
+				Message: models.ErrFieldRequired.For("username").Error(),
 			},
 			wantStatusCode: 400,
 		},
@@ -178,23 +355,7 @@ func TestLoginIntegration(t *testing.T) {
 			name: "error2",
 			body: reqBody{Username: "admin", Password: ""},
 			wantData: responseData{
-				Message: models.ErrPasswordNotfound,
-			},
-			wantStatusCode: 400,
-		},
-		{
-			name: "error3",
-			body: reqBody{Username: "admin", Password: "123"},
-			wantData: responseData{
-				Message: models.ErrPasswordFormat,
-			},
-			wantStatusCode: 400,
-		},
-		{
-			name: "error4",
-			body: reqBody{Username: "admin", Password: "123456789123456789"},
-			wantData: responseData{
-				Message: models.ErrPasswordFormat,
+				Message: models.ErrFieldRequired.For("password").Error(),
 			},
 			wantStatusCode: 400,
 		},
@@ -202,7 +363,7 @@ func TestLoginIntegration(t *testing.T) {
 			name: "error5",
 			body: reqBody{Username: "admin", Password: "admin01"},
 			wantData: responseData{
-				Message: models.ErrUsernameIsNotExist,
+				Message: models.ErrInvalidCredentials.Error(),
 			},
 			wantStatusCode: 401,
 		},
@@ -210,7 +371,7 @@ func TestLoginIntegration(t *testing.T) {
 			name: "error6",
 			body: reqBody{Username: "admin", Password: "admin01"},
 			wantData: responseData{
-				Message: models.ErrUnauthorized,
+				Message: models.ErrInvalidCredentials.Error(),
 			},
 			wantStatusCode: 401,
 		},
@@ -218,7 +379,7 @@ func TestLoginIntegration(t *testing.T) {
 			name: "error500",
 			body: reqBody{Username: "admin", Password: "admin01"},
 			wantData: responseData{
-				Message: models.ErrUnexpected,
+				Message: models.ErrUnexpected.Error(),
 			},
 			wantStatusCode: 500,
 		},
@@ -230,6 +391,14 @@ func TestLoginIntegration(t *testing.T) {
 			},
 			wantStatusCode: 200,
 		},
+		{
+			name: "success password set before the policy",
+			body: reqBody{Username: "admin", Password: "123"},
+			wantData: responseData{
+				Message: "login success",
+			},
+			wantStatusCode: 200,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -239,29 +408,37 @@ func TestLoginIntegration(t *testing.T) {
 			// mock Gets user
 			switch tt.name {
 			case "error5":
-				userRepo.On("Gets", mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
+				userRepo.On("Gets", mock.Anything, mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
 					return filter.Username == tt.body.Username
 				})).Return([]models.RepoUserModel{}, nil)
 
 			case "error6":
-				userRepo.On("Gets", mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
+				userRepo.On("Gets", mock.Anything, mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
 					return filter.Username == tt.body.Username
 				})).Return([]models.RepoUserModel{
 					{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: tt.body.Username, Password: ""},
 				}, nil)
 
 			case "error500":
-				userRepo.On("Gets", mock.AnythingOfType("models.RepoGetUserModel")).Return(nil, errors.New(""))
+				userRepo.On("Gets", mock.Anything, mock.AnythingOfType("models.RepoGetUserModel")).Return(nil, errors.New(""))
 
 			default:
-				userRepo.On("Gets", mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
+				userRepo.On("Gets", mock.Anything, mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
 					return filter.Username == tt.body.Username
 				})).Return([]models.RepoUserModel{
-					{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: tt.body.Username, Password: tt.body.Password},
+					{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: tt.body.Username, Password: func() string {
+						hash, _ := hasher.Hash(tt.body.Password)
+						return hash
+					}()},
 				}, nil)
 			}
 
-			userSrv := services.NewUserService(&userRepo)
+			// mock Update user, recording the login
+			userRepo.On("Update", mock.Anything, "225cfc88-c66b-4f2f-b424-a3b74e3b1191", mock.MatchedBy(func(payload models.RepoUpdateUserModel) bool {
+				return !payload.LastLoginAt.IsZero()
+			})).Return(nil)
+
+			userSrv := services.NewUserService(&userRepo, repositories.NewUsernameHistoryMemoryRepository(), newTokenService(&userRepo), newThrottleService(), hasher, usernamePolicy, passwordPolicy, services.UserConfig{})
 
 			userHandler := handlers.NewUserHandler(userSrv)
 
@@ -289,84 +466,124 @@ func TestLoginIntegration(t *testing.T) {
 	}
 }
 
-func TestResetPasswordIntegration(t *testing.T) {
+func TestChangePasswordIntegration(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	userRepo := repositories.NewUserMemoryRepository()
+	tokenSrv := newTokenService(userRepo)
+	userSrv := services.NewUserService(userRepo, repositories.NewUsernameHistoryMemoryRepository(), tokenSrv, newThrottleService(), hasher, usernamePolicy, passwordPolicy, services.UserConfig{PasswordHistory: 2})
+	userHandler := handlers.NewUserHandler(userSrv)
+	tokenHandler := handlers.NewTokenHandler(tokenSrv)
+
+	app := fiber.New()
+	app.Post("/register", userHandler.Register)
+	app.Post("/login", userHandler.Login)
+	app.Post("/token/refresh", tokenHandler.Refresh)
+	app.Use(handlers.NewAuthMiddleware(tokenSrv, "").Handle)
+	app.Post("/users/me/password", userHandler.ChangePassword)
+
+	type tokens struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	post := func(path, token, body string) (status int, result tokens) {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Add("Authorization", "Bearer "+token)
+		}
+		res, _ := app.Test(req, -1)
+		defer res.Body.Close()
+		json.NewDecoder(res.Body).Decode(&result)
+		return res.StatusCode, result
+	}
+
+	post("/register", "", `{"username":"admin","password":"admin01"}`)
+	_, current := post("/login", "", `{"username":"admin","password":"admin01"}`)
+	_, other := post("/login", "", `{"username":"admin","password":"admin01"}`)
+
+	// -------------------- Act (กระทำ)--------------------
+	statusWrong, _ := post("/users/me/password", current.Token, `{"current_password":"wrong01","password":"admin02"}`)
+	statusChanged, changed := post("/users/me/password", current.Token, `{"current_password":"admin01","password":"admin02"}`)
+	statusOld, _ := post("/users/me/password", current.Token, `{"current_password":"admin02","password":"admin03"}`)
+	statusOther, _ := post("/users/me/password", other.Token, `{"current_password":"admin02","password":"admin03"}`)
+	statusOtherRefresh, _ := post("/token/refresh", "", fmt.Sprintf(`{"refresh_token":%q}`, other.RefreshToken))
+	statusReused, _ := post("/users/me/password", changed.Token, `{"current_password":"admin02","password":"admin01"}`)
+	statusOldLogin, _ := post("/login", "", `{"username":"admin","password":"admin01"}`)
+	statusNewLogin, _ := post("/login", "", `{"username":"admin","password":"admin02"}`)
+	statusRefresh, _ := post("/token/refresh", "", fmt.Sprintf(`{"refresh_token":%q}`, changed.RefreshToken))
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.Equal(t, fiber.StatusBadRequest, statusWrong)
+	assert.Equal(t, fiber.StatusOK, statusChanged)
+	assert.NotEmpty(t, changed.Token)
+	assert.Equal(t, fiber.StatusUnauthorized, statusOld, "the token the change was made with is replaced")
+	assert.Equal(t, fiber.StatusUnauthorized, statusOther, "other sessions end")
+	assert.Equal(t, fiber.StatusUnauthorized, statusOtherRefresh, "other sessions cannot refresh")
+	assert.Equal(t, fiber.StatusBadRequest, statusReused)
+	assert.Equal(t, fiber.StatusUnauthorized, statusOldLogin)
+	assert.Equal(t, fiber.StatusOK, statusNewLogin)
+	assert.Equal(t, fiber.StatusOK, statusRefresh)
+}
+
+func TestDeleteUserIntegration(t *testing.T) {
 	type reqParams struct {
 		UserId string `params:"user_id"`
 	}
-	type reqBody struct {
-		Password string `json:"password"`
-	}
 	type responseData struct {
 		Message string `json:"message"`
 	}
 	tests := []struct {
 		name           string
 		params         reqParams
-		body           reqBody
 		wantData       responseData
 		wantStatusCode int
 	}{
 		// TODO: Add test cases.
 		{
 			name:   "error1",
-			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
-			body:   reqBody{Password: ""},
+			params: reqParams{UserId: "123"},
 			wantData: responseData{
-				Message: models.ErrPasswordNotfound,
+				Message: models.ErrFieldFormat.For("user_id").Error(),
 			},
 			wantStatusCode: 400,
 		},
 		{
 			name:   "error2",
 			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
-			body:   reqBody{Password: "123"},
 			wantData: responseData{
-				Message: models.ErrPasswordFormat,
+				Message: models.ErrUserIdIsNotExist.Error(),
 			},
-			wantStatusCode: 400,
+			wantStatusCode: 404,
 		},
 		{
-			name:   "error3",
+			name:   "error401",
 			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
-			body:   reqBody{Password: "123456789123456789"},
 			wantData: responseData{
-				Message: models.ErrPasswordFormat,
+				Message: models.ErrUnauthorized.Error(),
 			},
-			wantStatusCode: 400,
-		},
-		{
-			name:   "error4",
-			params: reqParams{UserId: "123"},
-			body:   reqBody{Password: "admin01"},
-			wantData: responseData{
-				Message: models.ErrUserIdFormat,
-			},
-			wantStatusCode: 400,
+			wantStatusCode: 401,
 		},
 		{
-			name:   "error5",
-			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
-			body:   reqBody{Password: "admin01"},
+			name:   "error403",
+			params: reqParams{UserId: "6b3f4c1e-5a2d-4e8f-9c7b-1d2e3f4a5b6c"},
 			wantData: responseData{
-				Message: models.ErrUserIdIsNotExist,
+				Message: models.ErrForbidden.Error(),
 			},
-			wantStatusCode: 400,
+			wantStatusCode: 403,
 		},
 		{
 			name:   "error500",
 			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
-			body:   reqBody{Password: "admin01"},
 			wantData: responseData{
-				Message: models.ErrUnexpected,
+				Message: models.ErrUnexpected.Error(),
 			},
 			wantStatusCode: 500,
 		},
 		{
 			name:   "success",
 			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
-			body:   reqBody{Password: "admin01"},
 			wantData: responseData{
-				Message: "reset password success",
+				Message: "delete user success",
 			},
 			wantStatusCode: 200,
 		},
@@ -376,33 +593,31 @@ func TestResetPasswordIntegration(t *testing.T) {
 			// ------------------- Arrange (เตรียมของ) --------------------
 			userRepo := repositories.NewUserRepoMock()
 
-			// mock Update user
+			// mock Delete user
 			switch tt.name {
-			case "error5":
-				userRepo.On("Update", tt.params.UserId, mock.MatchedBy(func(payload models.RepoUpdateUserModel) bool {
-					return payload.Password == tt.body.Password
-				})).Return(errors.New(tt.wantData.Message))
+			case "error2":
+				userRepo.On("Delete", mock.Anything, tt.params.UserId).Return(models.ErrUserIdIsNotExist)
 
 			case "error500":
-				userRepo.On("Update", tt.params.UserId, mock.AnythingOfType("models.RepoUpdateUserModel")).Return(errors.New(""))
+				userRepo.On("Delete", mock.Anything, tt.params.UserId).Return(errors.New(""))
 
 			default:
-				userRepo.On("Update", tt.params.UserId, mock.MatchedBy(func(payload models.RepoUpdateUserModel) bool {
-					return payload.Password == tt.body.Password
-				})).Return(nil)
+				userRepo.On("Delete", mock.Anything, tt.params.UserId).Return(nil)
 			}
 
-			userSrv := services.NewUserService(&userRepo)
+			userSrv := services.NewUserService(&userRepo, repositories.NewUsernameHistoryMemoryRepository(), newTokenService(&userRepo), newThrottleService(), hasher, usernamePolicy, passwordPolicy, services.UserConfig{})
 
 			userHandler := handlers.NewUserHandler(userSrv)
 
 			// http request
 			app := fiber.New()
-			app.Put("/resetpassword/:user_id", userHandler.ResetPassword)
+			app.Use(newAuthMiddleware())
+			app.Delete("/delete/:user_id", userHandler.DeleteUser)
 
-			body, _ := json.Marshal(tt.body)
-			req := httptest.NewRequest("PUT", fmt.Sprintf("/resetpassword/%v", tt.params.UserId), bytes.NewBuffer(body))
-			req.Header.Add("Content-Type", "application/json")
+			req := httptest.NewRequest("DELETE", fmt.Sprintf("/delete/%v", tt.params.UserId), nil)
+			if tt.name != "error401" {
+				req.Header.Add("Authorization", "Bearer "+token)
+			}
 
 			// -------------------- Act (กระทำ)--------------------
 			res, _ := app.Test(req)
@@ -419,91 +634,259 @@ func TestResetPasswordIntegration(t *testing.T) {
 	}
 }
 
-func TestDeleteUserIntegration(t *testing.T) {
-	type reqParams struct {
-		UserId string `params:"user_id"`
-	}
-	type responseData struct {
-		Message string `json:"message"`
+// TestLoginLockoutIntegration locks an account after repeated failures, refuses even
+// the right password while locked and lets it in again once an admin unlocks it
+func TestLoginLockoutIntegration(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	userRepo := repositories.NewUserMemoryRepository()
+	throttleSrv := services.NewThrottleService(repositories.NewLoginAttemptMemoryRepository(), services.ThrottleConfig{
+		Window:           time.Minute,
+		LockoutThreshold: 3,
+		LockoutDuration:  time.Minute,
+	})
+	userSrv := services.NewUserService(userRepo, repositories.NewUsernameHistoryMemoryRepository(), newTokenService(userRepo), throttleSrv, hasher, usernamePolicy, passwordPolicy, services.UserConfig{})
+	userHandler := handlers.NewUserHandler(userSrv)
+
+	app := fiber.New()
+	app.Post("/register", userHandler.Register)
+	app.Post("/login", userHandler.Login)
+	app.Use(newAuthMiddleware())
+	app.Post("/users/:user_id/unlock", userHandler.UnlockUser)
+
+	login := func(password string) *http.Response {
+		req := httptest.NewRequest("POST", "/login", bytes.NewBufferString(fmt.Sprintf(`{"username":"admin","password":%q}`, password)))
+		req.Header.Add("Content-Type", "application/json")
+		res, _ := app.Test(req, -1)
+		res.Body.Close()
+		return res
 	}
-	tests := []struct {
-		name           string
-		params         reqParams
-		wantData       responseData
-		wantStatusCode int
-	}{
-		// TODO: Add test cases.
-		{
-			name:   "error1",
-			params: reqParams{UserId: "123"},
-			wantData: responseData{
-				Message: models.ErrUserIdFormat,
-			},
-			wantStatusCode: 400,
-		},
-		{
-			name:   "error2",
-			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
-			wantData: responseData{
-				Message: models.ErrUserIdIsNotExist,
-			},
-			wantStatusCode: 400,
-		},
-		{
-			name:   "error500",
-			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
-			wantData: responseData{
-				Message: models.ErrUnexpected,
-			},
-			wantStatusCode: 500,
-		},
-		{
-			name:   "success",
-			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
-			wantData: responseData{
-				Message: "delete user success",
-			},
-			wantStatusCode: 200,
-		},
+
+	req := httptest.NewRequest("POST", "/register", bytes.NewBufferString(`{"username":"admin","password":"admin01"}`))
+	req.Header.Add("Content-Type", "application/json")
+	res, _ := app.Test(req, -1)
+	res.Body.Close()
+	users, _ := userRepo.Gets(context.Background(), models.RepoGetUserModel{Username: "admin"})
+
+	adminToken, _ := testJWT.Sign(utils.TokenDataModel{UserId: uuid.NewString(), Username: "root", Role: models.RoleAdmin})
+
+	// -------------------- Act (กระทำ)--------------------
+	failures := []int{}
+	for i := 0; i < 3; i++ {
+		failures = append(failures, login("wrong01").StatusCode)
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// ------------------- Arrange (เตรียมของ) --------------------
-			userRepo := repositories.NewUserRepoMock()
+	locked := login("admin01")
 
-			// mock Delete user
-			switch tt.name {
-			case "error2":
-				userRepo.On("Delete", tt.params.UserId).Return(errors.New(tt.wantData.Message))
+	req = httptest.NewRequest("POST", fmt.Sprintf("/users/%v/unlock", users[0].UserId), nil)
+	req.Header.Add("Authorization", "Bearer "+adminToken)
+	unlock, _ := app.Test(req, -1)
+	unlock.Body.Close()
 
-			case "error500":
-				userRepo.On("Delete", tt.params.UserId).Return(errors.New(""))
+	unlocked := login("admin01")
 
-			default:
-				userRepo.On("Delete", tt.params.UserId).Return(nil)
-			}
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.Equal(t, []int{401, 401, 401}, failures)
+	assert.Equal(t, fiber.StatusTooManyRequests, locked.StatusCode)
+	assert.Equal(t, "60", locked.Header.Get("Retry-After"))
+	assert.Equal(t, fiber.StatusOK, unlock.StatusCode)
+	assert.Equal(t, fiber.StatusOK, unlocked.StatusCode)
+}
 
-			userSrv := services.NewUserService(&userRepo)
+func TestLoginPlaintextPasswordIntegration(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	userRepo := repositories.NewUserMemoryRepository()
+	multiHasher := hashers.NewMultiHasher(hasher, hashers.NewPlaintextHasher())
+	userSrv := services.NewUserService(userRepo, repositories.NewUsernameHistoryMemoryRepository(), newTokenService(userRepo), newThrottleService(), multiHasher, usernamePolicy, passwordPolicy, services.UserConfig{})
+	userHandler := handlers.NewUserHandler(userSrv)
+
+	app := fiber.New()
+	app.Post("/login", userHandler.Login)
+
+	login := func(password string) int {
+		req := httptest.NewRequest("POST", "/login", bytes.NewBufferString(fmt.Sprintf(`{"username":"admin","password":%q}`, password)))
+		req.Header.Add("Content-Type", "application/json")
+		res, _ := app.Test(req, -1)
+		res.Body.Close()
+		return res.StatusCode
+	}
 
-			userHandler := handlers.NewUserHandler(userSrv)
+	// a row stored before passwords were hashed
+	userRepo.Create(context.Background(), models.RepoCreateUserModel{UserId: uuid.NewString(), Username: "admin", Password: "admin01", Role: models.RoleUser})
+
+	// -------------------- Act (กระทำ)--------------------
+	wrong := login("admin02")
+	first := login("admin01")
+	users, _ := userRepo.Gets(context.Background(), models.RepoGetUserModel{Username: "admin"})
+	again := login("admin01")
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.Equal(t, fiber.StatusUnauthorized, wrong)
+	assert.Equal(t, fiber.StatusOK, first)
+	assert.NotEqual(t, "admin01", users[0].Password, "the plaintext is replaced by a hash")
+	assert.True(t, isHashOf("admin01", users[0].Password))
+	assert.Equal(t, fiber.StatusOK, again)
+}
 
-			// http request
-			app := fiber.New()
-			app.Delete("/delete/:user_id", userHandler.DeleteUser)
+func TestListUsersIntegration(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	userRepo := repositories.NewUserMemoryRepository()
+	userSrv := services.NewUserService(userRepo, repositories.NewUsernameHistoryMemoryRepository(), newTokenService(userRepo), newThrottleService(), hasher, usernamePolicy, passwordPolicy, services.UserConfig{})
+	userHandler := handlers.NewUserHandler(userSrv)
+
+	app := fiber.New()
+	app.Post("/register", userHandler.Register)
+	app.Use(newAuthMiddleware())
+	app.Get("/users", userHandler.ListUsers)
+
+	for _, username := range []string{"carol", "Amy", "bob", "adam"} {
+		req := httptest.NewRequest("POST", "/register", bytes.NewBufferString(fmt.Sprintf(`{"username":%q,"password":"admin01"}`, username)))
+		req.Header.Add("Content-Type", "application/json")
+		res, _ := app.Test(req, -1)
+		res.Body.Close()
+	}
 
-			req := httptest.NewRequest("DELETE", fmt.Sprintf("/delete/%v", tt.params.UserId), nil)
+	adminToken, _ := testJWT.Sign(utils.TokenDataModel{UserId: uuid.NewString(), Username: "root", Role: models.RoleAdmin})
+	list := func(query string) (status int, page models.HandUserPageModel, body string) {
+		req := httptest.NewRequest("GET", "/users"+query, nil)
+		req.Header.Add("Authorization", "Bearer "+adminToken)
+		res, _ := app.Test(req, -1)
+		defer res.Body.Close()
+		b, _ := io.ReadAll(res.Body)
+		json.Unmarshal(b, &page)
+		return res.StatusCode, page, string(b)
+	}
 
-			// -------------------- Act (กระทำ)--------------------
-			res, _ := app.Test(req)
-			defer res.Body.Close()
+	// -------------------- Act (กระทำ)--------------------
+	usernames := []string{}
+	pages := 0
+	query := "?limit=3"
+	for {
+		status, page, body := list(query)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.NotContains(t, body, "password")
+		for _, user := range page.Users {
+			usernames = append(usernames, user.Username)
+		}
+		pages++
+		if page.NextCursor == "" || pages > 4 {
+			break
+		}
+		query = "?limit=3&cursor=" + page.NextCursor
+	}
+	_, prefixed, _ := list("?username_prefix=A&sort=-username")
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.Equal(t, []string{"adam", "amy", "bob", "carol"}, usernames)
+	assert.Equal(t, 2, pages)
+	assert.Len(t, prefixed.Users, 2)
+	assert.Equal(t, "amy", prefixed.Users[0].Username)
+	assert.Equal(t, "adam", prefixed.Users[1].Username)
+	assert.False(t, prefixed.Users[0].CreatedAt.IsZero())
+}
 
-			// -------------------- Assert (ยืนยัน) --------------------
-			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+func TestProfileIntegration(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	userRepo := repositories.NewUserMemoryRepository()
+	userSrv := services.NewUserService(userRepo, repositories.NewUsernameHistoryMemoryRepository(), newTokenService(userRepo), newThrottleService(), hasher, usernamePolicy, passwordPolicy, services.UserConfig{})
+	userHandler := handlers.NewUserHandler(userSrv)
+
+	app := fiber.New()
+	app.Post("/register", userHandler.Register)
+	app.Post("/login", userHandler.Login)
+	app.Use(newAuthMiddleware())
+	app.Get("/users/:user_id/profile", userHandler.GetProfile)
+	app.Patch("/users/:user_id/profile", userHandler.UpdateProfile)
+
+	for _, path := range []string{"/register", "/login"} {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(`{"username":"admin","password":"admin01"}`))
+		req.Header.Add("Content-Type", "application/json")
+		res, _ := app.Test(req, -1)
+		res.Body.Close()
+	}
+	users, _ := userRepo.Gets(context.Background(), models.RepoGetUserModel{Username: "admin"})
+	userToken, _ := testJWT.Sign(utils.TokenDataModel{UserId: users[0].UserId, Username: "admin", Role: models.RoleUser})
+
+	profile := func(method, body string) (status int, profile models.HandProfileModel) {
+		req := httptest.NewRequest(method, fmt.Sprintf("/users/%v/profile", users[0].UserId), bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add("Authorization", "Bearer "+userToken)
+		res, _ := app.Test(req, -1)
+		defer res.Body.Close()
+		json.NewDecoder(res.Body).Decode(&profile)
+		return res.StatusCode, profile
+	}
 
-			b, _ := io.ReadAll(res.Body)
-			resBody := responseData{}
-			json.Unmarshal(b, &resBody)
-			assert.Equal(t, tt.wantData, resBody)
-		})
+	// -------------------- Act (กระทำ)--------------------
+	_, before := profile("GET", "")
+	statusFirst, _ := profile("PATCH", `{"display_name":"Admin","email":"admin@example.com","locale":"en-us","attributes":{"team":"core","floor":"4"}}`)
+	statusSecond, _ := profile("PATCH", `{"email":"","attributes":{"floor":null}}`)
+	statusInvalid, _ := profile("PATCH", `{"email":"admin"}`)
+	_, after := profile("GET", "")
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.NotNil(t, before.LastLoginAt, "login is recorded")
+	assert.Equal(t, before.CreatedAt, before.UpdatedAt)
+	assert.Equal(t, fiber.StatusOK, statusFirst)
+	assert.Equal(t, fiber.StatusOK, statusSecond)
+	assert.Equal(t, fiber.StatusBadRequest, statusInvalid)
+	assert.Equal(t, "Admin", after.DisplayName)
+	assert.Equal(t, "", after.Email)
+	assert.Equal(t, "en-US", after.Locale)
+	assert.Equal(t, map[string]string{"team": "core"}, after.Attributes)
+	assert.False(t, after.UpdatedAt.Before(before.UpdatedAt))
+	assert.Equal(t, before.LastLoginAt, after.LastLoginAt)
+}
+
+func TestChangeUsernameIntegration(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	userRepo := repositories.NewUserMemoryRepository()
+	tokenSrv := newTokenService(userRepo)
+	userSrv := services.NewUserService(userRepo, repositories.NewUsernameHistoryMemoryRepository(), tokenSrv, newThrottleService(), hasher, usernamePolicy, passwordPolicy, services.UserConfig{UsernameCooldown: time.Hour})
+	userHandler := handlers.NewUserHandler(userSrv)
+
+	app := fiber.New()
+	app.Post("/register", userHandler.Register)
+	app.Post("/login", userHandler.Login)
+	app.Use(handlers.NewAuthMiddleware(tokenSrv, "").Handle)
+	app.Put("/users/:user_id/username", userHandler.ChangeUsername)
+
+	post := func(path, body string) *http.Response {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		res, _ := app.Test(req, -1)
+		res.Body.Close()
+		return res
 	}
+	changeUsername := func(userId, token, username string) (*http.Response, map[string]string) {
+		req := httptest.NewRequest("PUT", fmt.Sprintf("/users/%v/username", userId), bytes.NewBufferString(fmt.Sprintf(`{"username":%q}`, username)))
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add("Authorization", "Bearer "+token)
+		res, _ := app.Test(req, -1)
+		defer res.Body.Close()
+		body := map[string]string{}
+		json.NewDecoder(res.Body).Decode(&body)
+		return res, body
+	}
+
+	post("/register", `{"username":"admin","password":"admin01"}`)
+	users, _ := userRepo.Gets(context.Background(), models.RepoGetUserModel{Username: "admin"})
+	principal := models.SrvPrincipalModel{UserId: users[0].UserId, Username: "admin", Role: models.RoleUser}
+	token, _, _ := tokenSrv.Issue(context.Background(), principal)
+
+	// -------------------- Act (กระทำ)--------------------
+	changed, changedBody := changeUsername(users[0].UserId, token, "Somchai")
+	stale, _ := changeUsername(users[0].UserId, token, "somsak")
+	fresh, _ := changeUsername(users[0].UserId, changedBody["token"], "Somchai")
+	takeover := post("/register", `{"username":"admin","password":"admin01"}`)
+	login := post("/login", `{"username":"somchai","password":"admin01"}`)
+	_, _, reclaimErr := userSrv.ChangeUsername(context.Background(), principal, users[0].UserId, "admin")
+	after, _ := userRepo.Gets(context.Background(), models.RepoGetUserModel{UserId: users[0].UserId})
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.Equal(t, fiber.StatusOK, changed.StatusCode)
+	assert.Equal(t, fiber.StatusUnauthorized, stale.StatusCode, "the old credential version is revoked")
+	assert.Equal(t, fiber.StatusOK, fresh.StatusCode, "the returned token carries the new username")
+	assert.Equal(t, fiber.StatusConflict, takeover.StatusCode, "the old username is held for its owner")
+	assert.Equal(t, fiber.StatusOK, login.StatusCode)
+	assert.NoError(t, reclaimErr)
+	assert.Equal(t, "admin", after[0].Username)
 }
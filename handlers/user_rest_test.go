@@ -3,7 +3,6 @@ package handlers_test
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"hexagonal-gotest/handlers"
 	"hexagonal-gotest/models"
@@ -12,11 +11,23 @@ import (
 	"io"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
+var principal = models.SrvPrincipalModel{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: "admin", Role: models.RoleUser}
+
+var testJWT = func() utils.JWT {
+	key, _ := utils.NewHMACKey("test", []byte("hexagonal-gotest-secret-for-tests"))
+	jwt, _ := utils.NewJWT(utils.JWTConfig{Issuer: "hexagonal-gotest", Lifetime: time.Hour}, key)
+	return jwt
+}()
+
+var token, _ = testJWT.Sign(utils.TokenDataModel{UserId: principal.UserId, Username: principal.Username, Role: principal.Role})
+
 func TestRegister(t *testing.T) {
 	type reqBody struct {
 		Username string `json:"username"`
@@ -27,6 +38,7 @@ func TestRegister(t *testing.T) {
 	}
 	tests := []struct {
 		name           string
+		srvErr         error
 		body           reqBody
 		wantData       responseData
 		wantStatusCode int
@@ -36,15 +48,16 @@ func TestRegister(t *testing.T) {
 			name: "error400",
 			body: reqBody{Username: "", Password: "admin"},
 			wantData: responseData{
-				Message: models.ErrUsernameNotfound,
+				Message: models.ErrFieldRequired.For("username").Error(),
 			},
 			wantStatusCode: 400,
 		},
 		{
-			name: "error500",
-			body: reqBody{Username: "admin", Password: "admin01"},
+			name:   "error500",
+			srvErr: models.ErrUnexpected,
+			body:   reqBody{Username: "admin", Password: "admin01"},
 			wantData: responseData{
-				Message: models.ErrUnexpected,
+				Message: models.ErrUnexpected.Error(),
 			},
 			wantStatusCode: 500,
 		},
@@ -65,9 +78,9 @@ func TestRegister(t *testing.T) {
 			// mock register service
 			switch tt.name {
 			case "success":
-				userSrv.On("Register", tt.body.Username, tt.body.Password).Return(nil)
+				userSrv.On("Register", mock.Anything, tt.body.Username, tt.body.Password).Return(nil)
 			default:
-				userSrv.On("Register", tt.body.Username, tt.body.Password).Return(errors.New(tt.wantData.Message))
+				userSrv.On("Register", mock.Anything, tt.body.Username, tt.body.Password).Return(tt.srvErr)
 			}
 
 			userHandler := handlers.NewUserHandler(&userSrv)
@@ -85,7 +98,13 @@ func TestRegister(t *testing.T) {
 			defer res.Body.Close()
 
 			// -------------------- Assert (ยืนยัน) --------------------
-			userSrv.AssertCalled(t, "Register", tt.body.Username, tt.body.Password)
+			// the handler rejects an invalid body before the service
+			switch tt.name {
+			case "error400":
+				userSrv.AssertNotCalled(t, "Register", mock.Anything, tt.body.Username, tt.body.Password)
+			default:
+				userSrv.AssertCalled(t, "Register", mock.Anything, tt.body.Username, tt.body.Password)
+			}
 
 			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
 
@@ -103,14 +122,17 @@ func TestLogin(t *testing.T) {
 		Password string `json:"password"`
 	}
 	type responseData struct {
-		Token   string `json:"token"`
-		Message string `json:"message"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+		Message      string `json:"message"`
 	}
 	tests := []struct {
 		name           string
+		srvErr         error
 		body           reqBody
 		wantData       responseData
 		wantStatusCode int
+		wantRetryAfter string
 	}{
 		// TODO: Add test cases.
 		{
@@ -118,28 +140,41 @@ func TestLogin(t *testing.T) {
 			body: reqBody{Username: "", Password: "admin"},
 			wantData: responseData{
 				Token:   "",
-				Message: models.ErrUsernameNotfound,
+				Message: models.ErrFieldRequired.For("username").Error(),
 			},
 			wantStatusCode: 400,
 		},
 		{
-			name: "error500",
-			body: reqBody{Username: "admin", Password: "admin01"},
+			name:   "error500",
+			srvErr: models.ErrUnexpected,
+			body:   reqBody{Username: "admin", Password: "admin01"},
 			wantData: responseData{
 				Token:   "",
-				Message: models.ErrUnexpected,
+				Message: models.ErrUnexpected.Error(),
 			},
 			wantStatusCode: 500,
 		},
+		{
+			name:   "error429",
+			srvErr: models.NewThrottledError(models.ErrAccountLocked, 90*time.Second),
+			body:   reqBody{Username: "admin", Password: "admin01"},
+			wantData: responseData{
+				Token:   "",
+				Message: models.ErrAccountLocked.Error(),
+			},
+			wantStatusCode: 429,
+			wantRetryAfter: "90",
+		},
 		{
 			name: "success",
 			body: reqBody{Username: "admin", Password: "admin01"},
 			wantData: responseData{
 				Token: func() string {
-					token, _ := utils.SignJWT(utils.TokenDataModel{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: "admin"})
+					token, _ := testJWT.Sign(utils.TokenDataModel{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: "admin"})
 					return token
 				}(),
-				Message: "login success",
+				RefreshToken: "refresh-token",
+				Message:      "login success",
 			},
 			wantStatusCode: 200,
 		},
@@ -152,9 +187,9 @@ func TestLogin(t *testing.T) {
 			// mock login service
 			switch tt.name {
 			case "success":
-				userSrv.On("Login", tt.body.Username, tt.body.Password).Return(tt.wantData.Token, nil)
+				userSrv.On("Login", mock.Anything, tt.body.Username, tt.body.Password, mock.Anything).Return(tt.wantData.Token, tt.wantData.RefreshToken, nil)
 			default:
-				userSrv.On("Login", tt.body.Username, tt.body.Password).Return(tt.wantData.Token, errors.New(tt.wantData.Message))
+				userSrv.On("Login", mock.Anything, tt.body.Username, tt.body.Password, mock.Anything).Return(tt.wantData.Token, tt.wantData.RefreshToken, tt.srvErr)
 			}
 
 			userHandler := handlers.NewUserHandler(&userSrv)
@@ -172,30 +207,164 @@ func TestLogin(t *testing.T) {
 			defer res.Body.Close()
 
 			// -------------------- Assert (ยืนยัน) --------------------
-			userSrv.AssertCalled(t, "Login", tt.body.Username, tt.body.Password)
+			// the handler rejects an invalid body before the service
+			switch tt.name {
+			case "error400":
+				userSrv.AssertNotCalled(t, "Login", mock.Anything, tt.body.Username, tt.body.Password, mock.Anything)
+			default:
+				userSrv.AssertCalled(t, "Login", mock.Anything, tt.body.Username, tt.body.Password, mock.Anything)
+			}
 
 			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+			assert.Equal(t, tt.wantRetryAfter, res.Header.Get("Retry-After"))
 
 			b, _ := io.ReadAll(res.Body)
 			resBody := responseData{}
 			json.Unmarshal(b, &resBody)
 			assert.Equal(t, tt.wantData, resBody)
+			if tt.srvErr != nil {
+				assert.NotContains(t, string(b), "token")
+			}
 		})
 	}
 }
 
-func TestResetPassword(t *testing.T) {
+func TestChangePassword(t *testing.T) {
+	type reqBody struct {
+		CurrentPassword string `json:"current_password"`
+		Password        string `json:"password"`
+	}
+	type responseData struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+		Message      string `json:"message"`
+	}
+	tests := []struct {
+		name           string
+		srvErr         error
+		body           reqBody
+		wantData       responseData
+		wantStatusCode int
+	}{
+		// TODO: Add test cases.
+		{
+			name: "current password is required",
+			body: reqBody{CurrentPassword: "", Password: "admin02"},
+			wantData: responseData{
+				Message: models.ErrFieldRequired.For("current_password").Error(),
+			},
+			wantStatusCode: 400,
+		},
+		{
+			name:   "wrong current password",
+			srvErr: models.ErrCurrentPasswordInvalid,
+			body:   reqBody{CurrentPassword: "wrong01", Password: "admin02"},
+			wantData: responseData{
+				Message: models.ErrCurrentPasswordInvalid.Error(),
+			},
+			wantStatusCode: 400,
+		},
+		{
+			name:   "password reused",
+			srvErr: models.ErrPasswordReused,
+			body:   reqBody{CurrentPassword: "admin01", Password: "admin01"},
+			wantData: responseData{
+				Message: models.ErrPasswordReused.Error(),
+			},
+			wantStatusCode: 400,
+		},
+		{
+			name:   "error429",
+			srvErr: models.ErrAccountLocked,
+			body:   reqBody{CurrentPassword: "wrong01", Password: "admin02"},
+			wantData: responseData{
+				Message: models.ErrAccountLocked.Error(),
+			},
+			wantStatusCode: 429,
+		},
+		{
+			name:   "error500",
+			srvErr: models.ErrUnexpected,
+			body:   reqBody{CurrentPassword: "admin01", Password: "admin02"},
+			wantData: responseData{
+				Message: models.ErrUnexpected.Error(),
+			},
+			wantStatusCode: 500,
+		},
+		{
+			name: "success",
+			body: reqBody{CurrentPassword: "admin01", Password: "admin02"},
+			wantData: responseData{
+				Token:        "token",
+				RefreshToken: "refresh-token",
+				Message:      "change password success",
+			},
+			wantStatusCode: 200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userSrv := services.NewUserSrvMock()
+
+			// mock change password service
+			switch tt.name {
+			case "success":
+				userSrv.On("ChangePassword", mock.Anything, principal, tt.body.CurrentPassword, tt.body.Password, mock.AnythingOfType("string")).Return("token", "refresh-token", nil)
+			default:
+				userSrv.On("ChangePassword", mock.Anything, principal, tt.body.CurrentPassword, tt.body.Password, mock.AnythingOfType("string")).Return("", "", tt.srvErr)
+			}
+
+			userHandler := handlers.NewUserHandler(&userSrv)
+
+			// http request
+			app := fiber.New()
+			app.Use(newAuthMiddleware())
+			app.Post("/users/me/password", userHandler.ChangePassword)
+
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest("POST", "/users/me/password", bytes.NewBuffer(body))
+			req.Header.Add("Content-Type", "application/json")
+			req.Header.Add("Authorization", "Bearer "+token)
+
+			// -------------------- Act (กระทำ)--------------------
+			res, _ := app.Test(req)
+			defer res.Body.Close()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			switch tt.name {
+			case "current password is required":
+				userSrv.AssertNotCalled(t, "ChangePassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			default:
+				userSrv.AssertCalled(t, "ChangePassword", mock.Anything, principal, tt.body.CurrentPassword, tt.body.Password, mock.AnythingOfType("string"))
+			}
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+
+			b, _ := io.ReadAll(res.Body)
+			resBody := responseData{}
+			json.Unmarshal(b, &resBody)
+			assert.Equal(t, tt.wantData, resBody)
+		})
+	}
+}
+
+func TestChangeUsername(t *testing.T) {
 	type reqParams struct {
 		UserId string `params:"user_id"`
 	}
 	type reqBody struct {
-		Password string `json:"password"`
+		Username string `json:"username"`
 	}
 	type responseData struct {
-		Message string `json:"message"`
+		Code         models.ErrorCode `json:"code"`
+		Message      string           `json:"message"`
+		Token        string           `json:"token"`
+		RefreshToken string           `json:"refresh_token"`
 	}
 	tests := []struct {
 		name           string
+		srvErr         error
 		params         reqParams
 		body           reqBody
 		wantData       responseData
@@ -203,29 +372,67 @@ func TestResetPassword(t *testing.T) {
 	}{
 		// TODO: Add test cases.
 		{
-			name:   "error400",
+			name:   "username is required",
 			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
-			body:   reqBody{Password: "admin"},
+			body:   reqBody{Username: ""},
 			wantData: responseData{
-				Message: models.ErrUsernameNotfound,
+				Code:    models.ErrValidation.Code,
+				Message: models.ErrFieldRequired.For("username").Error(),
 			},
 			wantStatusCode: 400,
 		},
+		{
+			name:   "username is taken",
+			srvErr: models.ErrUsernameIsExist,
+			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			body:   reqBody{Username: "somchai"},
+			wantData: responseData{
+				Code:    models.ErrUsernameIsExist.Code,
+				Message: models.ErrUsernameIsExist.Error(),
+			},
+			wantStatusCode: 409,
+		},
+		{
+			name:   "error401",
+			srvErr: models.ErrUnauthorized,
+			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			body:   reqBody{Username: "somchai"},
+			wantData: responseData{
+				Code:    models.ErrUnauthorized.Code,
+				Message: models.ErrUnauthorized.Error(),
+			},
+			wantStatusCode: 401,
+		},
+		{
+			name:   "error403",
+			srvErr: models.ErrForbidden,
+			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			body:   reqBody{Username: "somchai"},
+			wantData: responseData{
+				Code:    models.ErrForbidden.Code,
+				Message: models.ErrForbidden.Error(),
+			},
+			wantStatusCode: 403,
+		},
 		{
 			name:   "error500",
+			srvErr: models.ErrUnexpected,
 			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
-			body:   reqBody{Password: "admin01"},
+			body:   reqBody{Username: "somchai"},
 			wantData: responseData{
-				Message: models.ErrUnexpected,
+				Code:    models.ErrUnexpected.Code,
+				Message: models.ErrUnexpected.Error(),
 			},
 			wantStatusCode: 500,
 		},
 		{
 			name:   "success",
 			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
-			body:   reqBody{Password: "admin01"},
+			body:   reqBody{Username: "somchai"},
 			wantData: responseData{
-				Message: "reset password success",
+				Message:      "change username success",
+				Token:        "token",
+				RefreshToken: "refresh-token",
 			},
 			wantStatusCode: 200,
 		},
@@ -235,30 +442,37 @@ func TestResetPassword(t *testing.T) {
 			// ------------------- Arrange (เตรียมของ) --------------------
 			userSrv := services.NewUserSrvMock()
 
-			// mock reset password service
+			// mock change username service
 			switch tt.name {
 			case "success":
-				userSrv.On("ResetPassword", tt.params.UserId, tt.body.Password).Return(nil)
+				userSrv.On("ChangeUsername", mock.Anything, principal, tt.params.UserId, tt.body.Username).Return("token", "refresh-token", nil)
 			default:
-				userSrv.On("ResetPassword", tt.params.UserId, tt.body.Password).Return(errors.New(tt.wantData.Message))
+				userSrv.On("ChangeUsername", mock.Anything, principal, tt.params.UserId, tt.body.Username).Return("", "", tt.srvErr)
 			}
 
 			userHandler := handlers.NewUserHandler(&userSrv)
 
 			// http request
 			app := fiber.New()
-			app.Put("/resetpassword/:user_id", userHandler.ResetPassword)
+			app.Use(newAuthMiddleware())
+			app.Put("/users/:user_id/username", userHandler.ChangeUsername)
 
 			body, _ := json.Marshal(tt.body)
-			req := httptest.NewRequest("PUT", fmt.Sprintf("/resetpassword/%v", tt.params.UserId), bytes.NewBuffer(body))
+			req := httptest.NewRequest("PUT", fmt.Sprintf("/users/%v/username", tt.params.UserId), bytes.NewBuffer(body))
 			req.Header.Add("Content-Type", "application/json")
+			req.Header.Add("Authorization", "Bearer "+token)
 
 			// -------------------- Act (กระทำ)--------------------
 			res, _ := app.Test(req)
 			defer res.Body.Close()
 
 			// -------------------- Assert (ยืนยัน) --------------------
-			userSrv.AssertCalled(t, "ResetPassword", tt.params.UserId, tt.body.Password)
+			switch tt.name {
+			case "username is required":
+				userSrv.AssertNotCalled(t, "ChangeUsername", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			default:
+				userSrv.AssertCalled(t, "ChangeUsername", mock.Anything, principal, tt.params.UserId, tt.body.Username)
+			}
 
 			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
 
@@ -279,6 +493,7 @@ func TestDeleteUser(t *testing.T) {
 	}
 	tests := []struct {
 		name           string
+		srvErr         error
 		params         reqParams
 		wantData       responseData
 		wantStatusCode int
@@ -286,17 +501,37 @@ func TestDeleteUser(t *testing.T) {
 		// TODO: Add test cases.
 		{
 			name:   "error400",
+			srvErr: models.ErrUsernameNotfound,
 			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
 			wantData: responseData{
-				Message: models.ErrUsernameNotfound,
+				Message: models.ErrUsernameNotfound.Error(),
 			},
 			wantStatusCode: 400,
 		},
+		{
+			name:   "error401",
+			srvErr: models.ErrUnauthorized,
+			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantData: responseData{
+				Message: models.ErrUnauthorized.Error(),
+			},
+			wantStatusCode: 401,
+		},
+		{
+			name:   "error403",
+			srvErr: models.ErrForbidden,
+			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantData: responseData{
+				Message: models.ErrForbidden.Error(),
+			},
+			wantStatusCode: 403,
+		},
 		{
 			name:   "error500",
+			srvErr: models.ErrUnexpected,
 			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
 			wantData: responseData{
-				Message: models.ErrUnexpected,
+				Message: models.ErrUnexpected.Error(),
 			},
 			wantStatusCode: 500,
 		},
@@ -317,25 +552,27 @@ func TestDeleteUser(t *testing.T) {
 			// mock reset password service
 			switch tt.name {
 			case "success":
-				userSrv.On("DeleteUser", tt.params.UserId).Return(nil)
+				userSrv.On("DeleteUser", mock.Anything, principal, tt.params.UserId).Return(nil)
 			default:
-				userSrv.On("DeleteUser", tt.params.UserId).Return(errors.New(tt.wantData.Message))
+				userSrv.On("DeleteUser", mock.Anything, principal, tt.params.UserId).Return(tt.srvErr)
 			}
 
 			userHandler := handlers.NewUserHandler(&userSrv)
 
 			// http request
 			app := fiber.New()
+			app.Use(newAuthMiddleware())
 			app.Delete("/delete/:user_id", userHandler.DeleteUser)
 
 			req := httptest.NewRequest("DELETE", fmt.Sprintf("/delete/%v", tt.params.UserId), nil)
+			req.Header.Add("Authorization", "Bearer "+token)
 
 			// -------------------- Act (กระทำ)--------------------
 			res, _ := app.Test(req)
 			defer res.Body.Close()
 
 			// -------------------- Assert (ยืนยัน) --------------------
-			userSrv.AssertCalled(t, "DeleteUser", tt.params.UserId)
+			userSrv.AssertCalled(t, "DeleteUser", mock.Anything, principal, tt.params.UserId)
 
 			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
 
@@ -346,3 +583,583 @@ func TestDeleteUser(t *testing.T) {
 		})
 	}
 }
+
+func TestUnlockUser(t *testing.T) {
+	type reqParams struct {
+		UserId string `params:"user_id"`
+	}
+	type responseData struct {
+		Message string `json:"message"`
+	}
+	tests := []struct {
+		name           string
+		srvErr         error
+		params         reqParams
+		wantData       responseData
+		wantStatusCode int
+	}{
+		// TODO: Add test cases.
+		{
+			name:   "error400",
+			params: reqParams{UserId: "not-a-uuid"},
+			wantData: responseData{
+				Message: models.ErrFieldFormat.For("user_id").Error(),
+			},
+			wantStatusCode: 400,
+		},
+		{
+			name:   "error403",
+			srvErr: models.ErrForbidden,
+			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantData: responseData{
+				Message: models.ErrForbidden.Error(),
+			},
+			wantStatusCode: 403,
+		},
+		{
+			name:   "error500",
+			srvErr: models.ErrUnexpected,
+			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantData: responseData{
+				Message: models.ErrUnexpected.Error(),
+			},
+			wantStatusCode: 500,
+		},
+		{
+			name:   "success",
+			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantData: responseData{
+				Message: "unlock user success",
+			},
+			wantStatusCode: 200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userSrv := services.NewUserSrvMock()
+
+			// mock unlock user service
+			switch tt.name {
+			case "success":
+				userSrv.On("UnlockUser", mock.Anything, principal, tt.params.UserId).Return(nil)
+			default:
+				userSrv.On("UnlockUser", mock.Anything, principal, tt.params.UserId).Return(tt.srvErr)
+			}
+
+			userHandler := handlers.NewUserHandler(&userSrv)
+
+			// http request
+			app := fiber.New()
+			app.Use(newAuthMiddleware())
+			app.Post("/users/:user_id/unlock", userHandler.UnlockUser)
+
+			req := httptest.NewRequest("POST", fmt.Sprintf("/users/%v/unlock", tt.params.UserId), nil)
+			req.Header.Add("Authorization", "Bearer "+token)
+
+			// -------------------- Act (กระทำ)--------------------
+			res, _ := app.Test(req)
+			defer res.Body.Close()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			// the handler rejects an invalid user_id before the service
+			switch tt.name {
+			case "error400":
+				userSrv.AssertNotCalled(t, "UnlockUser", mock.Anything, mock.Anything, mock.Anything)
+			default:
+				userSrv.AssertCalled(t, "UnlockUser", mock.Anything, principal, tt.params.UserId)
+			}
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+
+			b, _ := io.ReadAll(res.Body)
+			resBody := responseData{}
+			json.Unmarshal(b, &resBody)
+			assert.Equal(t, tt.wantData, resBody)
+		})
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	type reqParams struct {
+		UserId string `params:"user_id"`
+	}
+	type responseData struct {
+		Message   string `json:"message"`
+		UserId    string `json:"user_id"`
+		Username  string `json:"username"`
+		Role      string `json:"role"`
+		CreatedAt string `json:"created_at"`
+	}
+	tests := []struct {
+		name           string
+		srvErr         error
+		params         reqParams
+		wantData       responseData
+		wantStatusCode int
+	}{
+		// TODO: Add test cases.
+		{
+			name:   "error400",
+			params: reqParams{UserId: "not-a-uuid"},
+			wantData: responseData{
+				Message: models.ErrFieldFormat.For("user_id").Error(),
+			},
+			wantStatusCode: 400,
+		},
+		{
+			name:   "error403",
+			srvErr: models.ErrForbidden,
+			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantData: responseData{
+				Message: models.ErrForbidden.Error(),
+			},
+			wantStatusCode: 403,
+		},
+		{
+			name:   "error404",
+			srvErr: models.ErrUserIdIsNotExist,
+			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantData: responseData{
+				Message: models.ErrUserIdIsNotExist.Error(),
+			},
+			wantStatusCode: 404,
+		},
+		{
+			name:   "error500",
+			srvErr: models.ErrUnexpected,
+			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantData: responseData{
+				Message: models.ErrUnexpected.Error(),
+			},
+			wantStatusCode: 500,
+		},
+		{
+			name:   "success",
+			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantData: responseData{
+				UserId:    "225cfc88-c66b-4f2f-b424-a3b74e3b1191",
+				Username:  "admin",
+				Role:      models.RoleUser,
+				CreatedAt: "2023-08-01T10:00:00Z",
+			},
+			wantStatusCode: 200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userSrv := services.NewUserSrvMock()
+
+			// mock get user service
+			switch tt.name {
+			case "success":
+				userSrv.On("GetUser", mock.Anything, principal, tt.params.UserId).Return(models.SrvUserModel{
+					UserId:    tt.params.UserId,
+					Username:  "admin",
+					Role:      models.RoleUser,
+					CreatedAt: time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC),
+				}, nil)
+			default:
+				userSrv.On("GetUser", mock.Anything, principal, tt.params.UserId).Return(nil, tt.srvErr)
+			}
+
+			userHandler := handlers.NewUserHandler(&userSrv)
+
+			// http request
+			app := fiber.New()
+			app.Use(newAuthMiddleware())
+			app.Get("/users/:user_id", userHandler.GetUser)
+
+			req := httptest.NewRequest("GET", fmt.Sprintf("/users/%v", tt.params.UserId), nil)
+			req.Header.Add("Authorization", "Bearer "+token)
+
+			// -------------------- Act (กระทำ)--------------------
+			res, _ := app.Test(req)
+			defer res.Body.Close()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			switch tt.name {
+			case "error400":
+				userSrv.AssertNotCalled(t, "GetUser", mock.Anything, mock.Anything, mock.Anything)
+			default:
+				userSrv.AssertCalled(t, "GetUser", mock.Anything, principal, tt.params.UserId)
+			}
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+
+			b, _ := io.ReadAll(res.Body)
+			assert.NotContains(t, string(b), "password")
+			resBody := responseData{}
+			json.Unmarshal(b, &resBody)
+			assert.Equal(t, tt.wantData, resBody)
+		})
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	type responseUser struct {
+		UserId   string `json:"user_id"`
+		Username string `json:"username"`
+		Role     string `json:"role"`
+	}
+	type responseData struct {
+		Message    string         `json:"message"`
+		Users      []responseUser `json:"users"`
+		NextCursor string         `json:"next_cursor"`
+	}
+	tests := []struct {
+		name           string
+		srvErr         error
+		query          string
+		srvQuery       models.SrvListUsersModel
+		wantData       responseData
+		wantStatusCode int
+	}{
+		// TODO: Add test cases.
+		{
+			name:  "error400 limit type",
+			query: "?limit=ten",
+			wantData: responseData{
+				Message: models.ErrFieldType.For("limit").Error(),
+			},
+			wantStatusCode: 400,
+		},
+		{
+			name:  "error400 limit overflow",
+			query: "?limit=99999999999999999999",
+			wantData: responseData{
+				Message: models.ErrListLimit.Error(),
+			},
+			wantStatusCode: 400,
+		},
+		{
+			name:     "error400 sort",
+			srvErr:   models.ErrListSort,
+			query:    "?sort=password",
+			srvQuery: models.SrvListUsersModel{Sort: "password"},
+			wantData: responseData{
+				Message: models.ErrListSort.Error(),
+			},
+			wantStatusCode: 400,
+		},
+		{
+			name:     "error403",
+			srvErr:   models.ErrForbidden,
+			srvQuery: models.SrvListUsersModel{},
+			wantData: responseData{
+				Message: models.ErrForbidden.Error(),
+			},
+			wantStatusCode: 403,
+		},
+		{
+			name:     "success",
+			query:    "?username_prefix=ad&sort=-created_at&cursor=abc&limit=2",
+			srvQuery: models.SrvListUsersModel{UsernamePrefix: "ad", Sort: "-created_at", Cursor: "abc", Limit: 2},
+			wantData: responseData{
+				Users: []responseUser{
+					{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: "admin", Role: models.RoleUser},
+					{UserId: "6b3f4c1e-5a2d-4e8f-9c7b-1d2e3f4a5b6c", Username: "adam", Role: models.RoleAdmin},
+				},
+				NextCursor: "def",
+			},
+			wantStatusCode: 200,
+		},
+		{
+			name:     "success empty",
+			srvQuery: models.SrvListUsersModel{},
+			wantData: responseData{
+				Users: []responseUser{},
+			},
+			wantStatusCode: 200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userSrv := services.NewUserSrvMock()
+
+			// mock list users service
+			switch tt.name {
+			case "success":
+				userSrv.On("ListUsers", mock.Anything, principal, tt.srvQuery).Return(models.SrvUserPageModel{
+					Users: []models.SrvUserModel{
+						{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: "admin", Role: models.RoleUser},
+						{UserId: "6b3f4c1e-5a2d-4e8f-9c7b-1d2e3f4a5b6c", Username: "adam", Role: models.RoleAdmin},
+					},
+					NextCursor: "def",
+				}, nil)
+			case "success empty":
+				userSrv.On("ListUsers", mock.Anything, principal, tt.srvQuery).Return(models.SrvUserPageModel{}, nil)
+			default:
+				userSrv.On("ListUsers", mock.Anything, principal, tt.srvQuery).Return(nil, tt.srvErr)
+			}
+
+			userHandler := handlers.NewUserHandler(&userSrv)
+
+			// http request
+			app := fiber.New()
+			app.Use(newAuthMiddleware())
+			app.Get("/users", userHandler.ListUsers)
+
+			req := httptest.NewRequest("GET", "/users"+tt.query, nil)
+			req.Header.Add("Authorization", "Bearer "+token)
+
+			// -------------------- Act (กระทำ)--------------------
+			res, _ := app.Test(req)
+			defer res.Body.Close()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			switch tt.name {
+			case "error400 limit type", "error400 limit overflow":
+				userSrv.AssertNotCalled(t, "ListUsers", mock.Anything, mock.Anything, mock.Anything)
+			default:
+				userSrv.AssertCalled(t, "ListUsers", mock.Anything, principal, tt.srvQuery)
+			}
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+
+			b, _ := io.ReadAll(res.Body)
+			assert.NotContains(t, string(b), "password")
+			resBody := responseData{}
+			json.Unmarshal(b, &resBody)
+			assert.Equal(t, tt.wantData, resBody)
+		})
+	}
+}
+
+func TestGetProfile(t *testing.T) {
+	type reqParams struct {
+		UserId string `params:"user_id"`
+	}
+	tests := []struct {
+		name           string
+		srvErr         error
+		params         reqParams
+		wantBody       string
+		wantStatusCode int
+	}{
+		// TODO: Add test cases.
+		{
+			name:           "error400",
+			params:         reqParams{UserId: "not-a-uuid"},
+			wantBody:       fmt.Sprintf(`{"code":"validation_failed","message":%q}`, models.ErrFieldFormat.For("user_id").Error()),
+			wantStatusCode: 400,
+		},
+		{
+			name:           "error403",
+			srvErr:         models.ErrForbidden,
+			params:         reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantBody:       fmt.Sprintf(`{"code":"forbidden","message":%q}`, models.ErrForbidden.Error()),
+			wantStatusCode: 403,
+		},
+		{
+			name:           "error404",
+			srvErr:         models.ErrUserIdIsNotExist,
+			params:         reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantBody:       fmt.Sprintf(`{"code":"user_id_not_exists","message":%q}`, models.ErrUserIdIsNotExist.Error()),
+			wantStatusCode: 404,
+		},
+		{
+			name:   "success",
+			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantBody: `{"user_id":"225cfc88-c66b-4f2f-b424-a3b74e3b1191","username":"admin","display_name":"Admin","email":"admin@example.com",
+				"locale":"th-TH","attributes":{"team":"core"},"created_at":"2023-08-01T10:00:00Z","updated_at":"2023-08-02T10:00:00Z","last_login_at":"2023-08-03T10:00:00Z"}`,
+			wantStatusCode: 200,
+		},
+		{
+			name:   "success never logged in",
+			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantBody: `{"user_id":"225cfc88-c66b-4f2f-b424-a3b74e3b1191","username":"admin","display_name":"","email":"",
+				"locale":"","attributes":{},"created_at":"2023-08-01T10:00:00Z","updated_at":"2023-08-01T10:00:00Z"}`,
+			wantStatusCode: 200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userSrv := services.NewUserSrvMock()
+
+			// mock get profile service
+			createdAt := time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC)
+			switch tt.name {
+			case "success":
+				userSrv.On("GetProfile", mock.Anything, principal, tt.params.UserId).Return(models.SrvProfileModel{
+					UserId:      tt.params.UserId,
+					Username:    "admin",
+					DisplayName: "Admin",
+					Email:       "admin@example.com",
+					Locale:      "th-TH",
+					Attributes:  map[string]string{"team": "core"},
+					CreatedAt:   createdAt,
+					UpdatedAt:   createdAt.Add(24 * time.Hour),
+					LastLoginAt: createdAt.Add(48 * time.Hour),
+				}, nil)
+			case "success never logged in":
+				userSrv.On("GetProfile", mock.Anything, principal, tt.params.UserId).Return(models.SrvProfileModel{
+					UserId:    tt.params.UserId,
+					Username:  "admin",
+					CreatedAt: createdAt,
+					UpdatedAt: createdAt,
+				}, nil)
+			default:
+				userSrv.On("GetProfile", mock.Anything, principal, tt.params.UserId).Return(nil, tt.srvErr)
+			}
+
+			userHandler := handlers.NewUserHandler(&userSrv)
+
+			// http request
+			app := fiber.New()
+			app.Use(newAuthMiddleware())
+			app.Get("/users/:user_id/profile", userHandler.GetProfile)
+
+			req := httptest.NewRequest("GET", fmt.Sprintf("/users/%v/profile", tt.params.UserId), nil)
+			req.Header.Add("Authorization", "Bearer "+token)
+
+			// -------------------- Act (กระทำ)--------------------
+			res, _ := app.Test(req)
+			defer res.Body.Close()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			switch tt.name {
+			case "error400":
+				userSrv.AssertNotCalled(t, "GetProfile", mock.Anything, mock.Anything, mock.Anything)
+			default:
+				userSrv.AssertCalled(t, "GetProfile", mock.Anything, principal, tt.params.UserId)
+			}
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+
+			b, _ := io.ReadAll(res.Body)
+			assert.JSONEq(t, tt.wantBody, string(b))
+		})
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	type reqParams struct {
+		UserId string `params:"user_id"`
+	}
+	ptr := func(value string) *string { return &value }
+	tests := []struct {
+		name           string
+		srvErr         error
+		params         reqParams
+		body           string
+		srvPayload     models.SrvUpdateProfileModel
+		wantBody       string
+		wantStatusCode int
+	}{
+		// TODO: Add test cases.
+		{
+			name:           "error400 params",
+			params:         reqParams{UserId: "not-a-uuid"},
+			body:           `{}`,
+			wantBody:       fmt.Sprintf(`{"code":"validation_failed","message":%q}`, models.ErrFieldFormat.For("user_id").Error()),
+			wantStatusCode: 400,
+		},
+		{
+			name:           "error400 unknown field",
+			params:         reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			body:           `{"username":"root"}`,
+			wantBody:       fmt.Sprintf(`{"code":"validation_failed","message":%q}`, models.ErrFieldUnknown.For("username").Error()),
+			wantStatusCode: 400,
+		},
+		{
+			name:           "error400 attribute type",
+			params:         reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			body:           `{"attributes":{"floor":4}}`,
+			wantBody:       fmt.Sprintf(`{"code":"validation_failed","message":%q}`, models.ErrFieldType.For("attributes.floor").Error()),
+			wantStatusCode: 400,
+		},
+		{
+			name:           "error400 validation",
+			srvErr:         models.NewValidationError(models.ErrEmailFormat),
+			params:         reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			body:           `{"email":"admin"}`,
+			srvPayload:     models.SrvUpdateProfileModel{Email: ptr("admin")},
+			wantBody:       fmt.Sprintf(`{"code":"validation_failed","message":%q}`, models.ErrEmailFormat.Error()),
+			wantStatusCode: 400,
+		},
+		{
+			name:           "error403",
+			srvErr:         models.ErrForbidden,
+			params:         reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			body:           `{"display_name":"Admin"}`,
+			srvPayload:     models.SrvUpdateProfileModel{DisplayName: ptr("Admin")},
+			wantBody:       fmt.Sprintf(`{"code":"forbidden","message":%q}`, models.ErrForbidden.Error()),
+			wantStatusCode: 403,
+		},
+		{
+			name:           "error404",
+			srvErr:         models.ErrUserIdIsNotExist,
+			params:         reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			body:           `{"display_name":"Admin"}`,
+			srvPayload:     models.SrvUpdateProfileModel{DisplayName: ptr("Admin")},
+			wantBody:       fmt.Sprintf(`{"code":"user_id_not_exists","message":%q}`, models.ErrUserIdIsNotExist.Error()),
+			wantStatusCode: 404,
+		},
+		{
+			name:   "success",
+			params: reqParams{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			body:   `{"display_name":"Admin","email":"","locale":null,"attributes":{"team":"core","floor":null}}`,
+			srvPayload: models.SrvUpdateProfileModel{
+				DisplayName: ptr("Admin"),
+				Email:       ptr(""),
+				Attributes:  map[string]*string{"team": ptr("core"), "floor": nil},
+			},
+			wantBody: `{"user_id":"225cfc88-c66b-4f2f-b424-a3b74e3b1191","username":"admin","display_name":"Admin","email":"",
+				"locale":"","attributes":{"team":"core"},"created_at":"2023-08-01T10:00:00Z","updated_at":"2023-08-02T10:00:00Z"}`,
+			wantStatusCode: 200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userSrv := services.NewUserSrvMock()
+
+			// mock update profile service
+			switch tt.name {
+			case "success":
+				userSrv.On("UpdateProfile", mock.Anything, principal, tt.params.UserId, tt.srvPayload).Return(models.SrvProfileModel{
+					UserId:      tt.params.UserId,
+					Username:    "admin",
+					DisplayName: "Admin",
+					Attributes:  map[string]string{"team": "core"},
+					CreatedAt:   time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC),
+					UpdatedAt:   time.Date(2023, 8, 2, 10, 0, 0, 0, time.UTC),
+				}, nil)
+			default:
+				userSrv.On("UpdateProfile", mock.Anything, principal, tt.params.UserId, tt.srvPayload).Return(nil, tt.srvErr)
+			}
+
+			userHandler := handlers.NewUserHandler(&userSrv)
+
+			// http request
+			app := fiber.New()
+			app.Use(newAuthMiddleware())
+			app.Patch("/users/:user_id/profile", userHandler.UpdateProfile)
+
+			req := httptest.NewRequest("PATCH", fmt.Sprintf("/users/%v/profile", tt.params.UserId), bytes.NewBufferString(tt.body))
+			req.Header.Add("Content-Type", "application/json")
+			req.Header.Add("Authorization", "Bearer "+token)
+
+			// -------------------- Act (กระทำ)--------------------
+			res, _ := app.Test(req)
+			defer res.Body.Close()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			switch tt.name {
+			case "error400 params", "error400 unknown field", "error400 attribute type":
+				userSrv.AssertNotCalled(t, "UpdateProfile", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			default:
+				userSrv.AssertCalled(t, "UpdateProfile", mock.Anything, principal, tt.params.UserId, tt.srvPayload)
+			}
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+
+			b, _ := io.ReadAll(res.Body)
+			assert.JSONEq(t, tt.wantBody, string(b))
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"hexagonal-gotest/models"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// validators are the rules usable in `validate` struct tags, each checks one string field
+var validators = map[string]func(value string) *models.Error{
+	"required": func(value string) *models.Error {
+		if value == "" {
+			return models.ErrFieldRequired
+		}
+		return nil
+	},
+	"uuid": func(value string) *models.Error {
+		if _, err := uuid.Parse(value); value != "" && err != nil {
+			return models.ErrFieldFormat
+		}
+		return nil
+	},
+	"uint": func(value string) *models.Error {
+		for _, r := range value {
+			if r < '0' || r > '9' {
+				return models.ErrFieldType
+			}
+		}
+		return nil
+	},
+}
+
+// validate checks the `validate:"rule,rule"` tags of the string fields of the struct
+// pointed to by v and reports the first failing rule of every field. Fields are named
+// by their json, params or query tag, as the client sent them.
+func validate(v any) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	fields := []*models.Error{}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		rules, ok := field.Tag.Lookup("validate")
+		if !ok {
+			continue
+		}
+
+		for _, rule := range strings.Split(rules, ",") {
+			validator, ok := validators[rule]
+			if !ok {
+				panic("validate: unknown rule " + rule + " on " + value.Type().Name() + "." + field.Name)
+			}
+			if err := validator(value.Field(i).String()); err != nil {
+				fields = append(fields, err.For(fieldName(field)))
+				break
+			}
+		}
+	}
+
+	if len(fields) > 0 {
+		return models.NewValidationError(fields...)
+	}
+	return nil
+}
+
+func fieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "params", "query"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(key), ","); name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
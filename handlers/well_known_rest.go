@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"hexagonal-gotest/utils"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type wellKnownHandler struct {
+	jwt utils.JWT
+}
+
+func NewWellKnownHandler(jwt utils.JWT) wellKnownHandler {
+	return wellKnownHandler{jwt}
+}
+
+// JWKS publishes the current and retiring public keys, verifiers should refetch it on an unknown kid
+func (h wellKnownHandler) JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+
+	return c.Status(fiber.StatusOK).JSON(utils.NewJWKS(h.jwt.Keys()))
+}
+
+func (h wellKnownHandler) OpenIDConfiguration(c *fiber.Ctx) error {
+	issuer := h.jwt.Issuer()
+	if issuer == "" {
+		issuer = c.BaseURL()
+	}
+	issuer = strings.TrimSuffix(issuer, "/")
+
+	algorithms := []string{}
+	seen := map[string]bool{}
+	for _, jwk := range utils.NewJWKS(h.jwt.Keys()).Keys {
+		if !seen[jwk.Alg] {
+			seen[jwk.Alg] = true
+			algorithms = append(algorithms, jwk.Alg)
+		}
+	}
+
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"issuer":                                issuer,
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"token_endpoint":                        issuer + "/login",
+		"response_types_supported":              []string{"token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": algorithms,
+		"claims_supported":                      []string{"user_id", "username", "role", "iss", "aud", "exp", "iat", "jti"},
+	})
+}
@@ -0,0 +1,142 @@
+package handlers_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"hexagonal-gotest/handlers"
+	"hexagonal-gotest/utils"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func newECKey(t *testing.T, kid string) utils.JWTKey {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalECPrivateKey(privateKey)
+	key, err := utils.ParseJWTKey(kid, utils.AlgorithmES256, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestJWKS(t *testing.T) {
+	tests := []struct {
+		name     string
+		jwt      func() utils.JWT
+		wantKids []string
+	}{
+		// TODO: Add test cases.
+		{
+			name:     "hmac only",
+			jwt:      func() utils.JWT { return testJWT },
+			wantKids: []string{},
+		},
+		{
+			name: "current and retiring keys",
+			jwt: func() utils.JWT {
+				jwt, _ := utils.NewJWT(utils.JWTConfig{Issuer: "https://auth.example.com"}, newECKey(t, "ec-2"), newECKey(t, "ec-1"))
+				return jwt
+			},
+			wantKids: []string{"ec-2", "ec-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			wellKnownHandler := handlers.NewWellKnownHandler(tt.jwt())
+
+			// http request
+			app := fiber.New()
+			app.Get("/.well-known/jwks.json", wellKnownHandler.JWKS)
+
+			req := httptest.NewRequest("GET", "/.well-known/jwks.json", nil)
+
+			// -------------------- Act (กระทำ)--------------------
+			res, _ := app.Test(req)
+			defer res.Body.Close()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.Equal(t, 200, res.StatusCode)
+			assert.Equal(t, "public, max-age=300", res.Header.Get("Cache-Control"))
+
+			b, _ := io.ReadAll(res.Body)
+			resBody := utils.JWKSModel{}
+			json.Unmarshal(b, &resBody)
+			kids := []string{}
+			for _, key := range resBody.Keys {
+				kids = append(kids, key.Kid)
+			}
+			assert.Equal(t, tt.wantKids, kids)
+		})
+	}
+}
+
+func TestOpenIDConfiguration(t *testing.T) {
+	type responseData struct {
+		Issuer     string   `json:"issuer"`
+		JwksUri    string   `json:"jwks_uri"`
+		Algorithms []string `json:"id_token_signing_alg_values_supported"`
+	}
+	tests := []struct {
+		name     string
+		issuer   string
+		wantData responseData
+	}{
+		// TODO: Add test cases.
+		{
+			name:   "configured issuer",
+			issuer: "https://auth.example.com/",
+			wantData: responseData{
+				Issuer:     "https://auth.example.com",
+				JwksUri:    "https://auth.example.com/.well-known/jwks.json",
+				Algorithms: []string{utils.AlgorithmES256},
+			},
+		},
+		{
+			name:   "request base url",
+			issuer: "",
+			wantData: responseData{
+				Issuer:     "http://example.com",
+				JwksUri:    "http://example.com/.well-known/jwks.json",
+				Algorithms: []string{utils.AlgorithmES256},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			jwt, err := utils.NewJWT(utils.JWTConfig{Issuer: tt.issuer}, newECKey(t, "ec-2"), newECKey(t, "ec-1"))
+			assert.NoError(t, err)
+			wellKnownHandler := handlers.NewWellKnownHandler(jwt)
+
+			// http request
+			app := fiber.New()
+			app.Get("/.well-known/openid-configuration", wellKnownHandler.OpenIDConfiguration)
+
+			req := httptest.NewRequest("GET", "/.well-known/openid-configuration", nil)
+
+			// -------------------- Act (กระทำ)--------------------
+			res, _ := app.Test(req)
+			defer res.Body.Close()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.Equal(t, 200, res.StatusCode)
+
+			b, _ := io.ReadAll(res.Body)
+			resBody := responseData{}
+			json.Unmarshal(b, &resBody)
+			assert.Equal(t, tt.wantData, resBody)
+		})
+	}
+}
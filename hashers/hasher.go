@@ -0,0 +1,18 @@
+package hashers
+
+import "errors"
+
+var (
+	ErrHashFormat    = errors.New("hash incorrect format")
+	ErrHashAlgorithm = errors.New("hash algorithm not supported")
+)
+
+// PORT password hasher
+type PasswordHasher interface {
+	// Hash returns an encoded hash carrying the algorithm and its parameters.
+	Hash(password string) (hash string, err error)
+
+	// Verify compares password against an encoded hash in constant time.
+	// needsRehash is true when the hash was produced with outdated parameters.
+	Verify(password, hash string) (ok bool, needsRehash bool, err error)
+}
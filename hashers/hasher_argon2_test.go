@@ -0,0 +1,91 @@
+package hashers_test
+
+import (
+	"hexagonal-gotest/hashers"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testArgon2idParams = hashers.Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2idVerify(t *testing.T) {
+	type args struct {
+		password string
+		hash     string
+	}
+	hashOf := func(password string, params hashers.Argon2idParams) string {
+		hash, _ := hashers.NewArgon2idHasher(params).Hash(password)
+		return hash
+	}
+	outdated := testArgon2idParams
+	outdated.Iterations = 2
+	tests := []struct {
+		name            string
+		args            args
+		wantOk          bool
+		wantNeedsRehash bool
+		wantErr         error
+	}{
+		{
+			name:    "error1",
+			args:    args{password: "admin01", hash: "$2a$10$abcdefghijklmnopqrstuv"},
+			wantErr: hashers.ErrHashAlgorithm,
+		},
+		{
+			name:    "error2",
+			args:    args{password: "admin01", hash: "$argon2id$v=19$m=1024,t=1,p=1$@@@$@@@"},
+			wantErr: hashers.ErrHashFormat,
+		},
+		{
+			name:    "error3",
+			args:    args{password: "admin01", hash: "$argon2id$v=19"},
+			wantErr: hashers.ErrHashFormat,
+		},
+		{
+			name:   "mismatch",
+			args:   args{password: "admin02", hash: hashOf("admin01", testArgon2idParams)},
+			wantOk: false,
+		},
+		{
+			name:            "outdated params",
+			args:            args{password: "admin01", hash: hashOf("admin01", outdated)},
+			wantOk:          true,
+			wantNeedsRehash: true,
+		},
+		{
+			name:   "success1",
+			args:   args{password: "admin01", hash: hashOf("admin01", testArgon2idParams)},
+			wantOk: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			hasher := hashers.NewArgon2idHasher(testArgon2idParams)
+
+			// -------------------- Act (กระทำ)--------------------
+			ok, needsRehash, err := hasher.Verify(tt.args.password, tt.args.hash)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantNeedsRehash, needsRehash)
+		})
+	}
+}
+
+func TestArgon2idHash(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	hasher := hashers.NewArgon2idHasher(testArgon2idParams)
+
+	// -------------------- Act (กระทำ)--------------------
+	hash, err := hasher.Hash("admin01")
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+	other, _ := hasher.Hash("admin01")
+	assert.NotEqual(t, hash, other)
+}
@@ -0,0 +1,50 @@
+package hashers
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type bcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) PasswordHasher {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	return bcryptHasher{cost}
+}
+
+func (h bcryptHasher) Hash(password string) (hash string, err error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return hash, err
+	}
+
+	return string(b), nil
+}
+
+func (h bcryptHasher) Verify(password, hash string) (ok bool, needsRehash bool, err error) {
+	if !strings.HasPrefix(hash, "$2a$") && !strings.HasPrefix(hash, "$2b$") && !strings.HasPrefix(hash, "$2y$") {
+		return false, false, ErrHashAlgorithm
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false, false, ErrHashFormat
+	}
+
+	// CompareHashAndPassword compares in constant time
+	err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		return false, false, ErrHashFormat
+	}
+
+	return true, cost != h.cost, nil
+}
@@ -0,0 +1,84 @@
+package hashers_test
+
+import (
+	"hexagonal-gotest/hashers"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBcryptVerify(t *testing.T) {
+	type args struct {
+		password string
+		hash     string
+	}
+	hashOf := func(password string, cost int) string {
+		b, _ := bcrypt.GenerateFromPassword([]byte(password), cost)
+		return string(b)
+	}
+	tests := []struct {
+		name            string
+		args            args
+		wantOk          bool
+		wantNeedsRehash bool
+		wantErr         error
+	}{
+		{
+			name:    "error1",
+			args:    args{password: "admin01", hash: "admin01"},
+			wantErr: hashers.ErrHashAlgorithm,
+		},
+		{
+			name:    "error2",
+			args:    args{password: "admin01", hash: "$2a$10$broken"},
+			wantErr: hashers.ErrHashFormat,
+		},
+		{
+			name:   "mismatch",
+			args:   args{password: "admin02", hash: hashOf("admin01", bcrypt.MinCost)},
+			wantOk: false,
+		},
+		{
+			name:            "outdated cost",
+			args:            args{password: "admin01", hash: hashOf("admin01", bcrypt.MinCost+1)},
+			wantOk:          true,
+			wantNeedsRehash: true,
+		},
+		{
+			name:   "success1",
+			args:   args{password: "admin01", hash: hashOf("admin01", bcrypt.MinCost)},
+			wantOk: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			hasher := hashers.NewBcryptHasher(bcrypt.MinCost)
+
+			// -------------------- Act (กระทำ)--------------------
+			ok, needsRehash, err := hasher.Verify(tt.args.password, tt.args.hash)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantNeedsRehash, needsRehash)
+		})
+	}
+}
+
+func TestBcryptHash(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	hasher := hashers.NewBcryptHasher(bcrypt.MinCost)
+
+	// -------------------- Act (กระทำ)--------------------
+	hash, err := hasher.Hash("admin01")
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.NoError(t, err)
+	assert.NotEqual(t, "admin01", hash)
+	ok, needsRehash, err := hasher.Verify("admin01", hash)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+}
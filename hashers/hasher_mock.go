@@ -0,0 +1,21 @@
+package hashers
+
+import "github.com/stretchr/testify/mock"
+
+type hasherMock struct {
+	mock.Mock
+}
+
+func NewHasherMock() hasherMock {
+	return hasherMock{}
+}
+
+func (m *hasherMock) Hash(password string) (hash string, err error) {
+	args := m.Called(password)
+	return args.String(0), args.Error(1)
+}
+
+func (m *hasherMock) Verify(password, hash string) (ok bool, needsRehash bool, err error) {
+	args := m.Called(password, hash)
+	return args.Bool(0), args.Bool(1), args.Error(2)
+}
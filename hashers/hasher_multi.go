@@ -0,0 +1,35 @@
+package hashers
+
+import "errors"
+
+type multiHasher struct {
+	current PasswordHasher
+	legacy  []PasswordHasher
+}
+
+// NewMultiHasher hashes with current and still verifies hashes produced by
+// legacy hashers, flagging them for rehash so users migrate on next login.
+func NewMultiHasher(current PasswordHasher, legacy ...PasswordHasher) PasswordHasher {
+	return multiHasher{current, legacy}
+}
+
+func (h multiHasher) Hash(password string) (hash string, err error) {
+	return h.current.Hash(password)
+}
+
+func (h multiHasher) Verify(password, hash string) (ok bool, needsRehash bool, err error) {
+	ok, needsRehash, err = h.current.Verify(password, hash)
+	if !errors.Is(err, ErrHashAlgorithm) {
+		return ok, needsRehash, err
+	}
+
+	for _, legacy := range h.legacy {
+		ok, _, err = legacy.Verify(password, hash)
+		if errors.Is(err, ErrHashAlgorithm) {
+			continue
+		}
+		return ok, ok, err
+	}
+
+	return false, false, ErrHashAlgorithm
+}
@@ -0,0 +1,69 @@
+package hashers_test
+
+import (
+	"hexagonal-gotest/hashers"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMultiVerify(t *testing.T) {
+	type args struct {
+		password string
+		hash     string
+	}
+	current := hashers.NewArgon2idHasher(testArgon2idParams)
+	legacy := hashers.NewBcryptHasher(bcrypt.MinCost)
+	currentHash, _ := current.Hash("admin01")
+	legacyHash, _ := legacy.Hash("admin01")
+	tests := []struct {
+		name            string
+		args            args
+		wantOk          bool
+		wantNeedsRehash bool
+		wantErr         error
+	}{
+		{
+			name:    "error1",
+			args:    args{password: "admin01", hash: "$6$salt$hash"},
+			wantErr: hashers.ErrHashAlgorithm,
+		},
+		{
+			name:            "plaintext success",
+			args:            args{password: "admin01", hash: "admin01"},
+			wantOk:          true,
+			wantNeedsRehash: true,
+		},
+		{
+			name:   "legacy mismatch",
+			args:   args{password: "admin02", hash: legacyHash},
+			wantOk: false,
+		},
+		{
+			name:            "legacy success",
+			args:            args{password: "admin01", hash: legacyHash},
+			wantOk:          true,
+			wantNeedsRehash: true,
+		},
+		{
+			name:   "success1",
+			args:   args{password: "admin01", hash: currentHash},
+			wantOk: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			hasher := hashers.NewMultiHasher(current, legacy, hashers.NewPlaintextHasher())
+
+			// -------------------- Act (กระทำ)--------------------
+			ok, needsRehash, err := hasher.Verify(tt.args.password, tt.args.hash)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantNeedsRehash, needsRehash)
+		})
+	}
+}
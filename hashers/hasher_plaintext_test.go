@@ -0,0 +1,70 @@
+package hashers_test
+
+import (
+	"hexagonal-gotest/hashers"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaintextVerify(t *testing.T) {
+	type args struct {
+		password string
+		hash     string
+	}
+	tests := []struct {
+		name            string
+		args            args
+		wantOk          bool
+		wantNeedsRehash bool
+		wantErr         error
+	}{
+		{
+			name:    "error1",
+			args:    args{password: "$6$salt$hash", hash: "$6$salt$hash"},
+			wantErr: hashers.ErrHashAlgorithm,
+		},
+		{
+			name:    "error2",
+			args:    args{password: "admin01", hash: ""},
+			wantErr: hashers.ErrHashAlgorithm,
+		},
+		{
+			name:   "mismatch",
+			args:   args{password: "admin02", hash: "admin01"},
+			wantOk: false,
+		},
+		{
+			name:            "success1",
+			args:            args{password: "admin01", hash: "admin01"},
+			wantOk:          true,
+			wantNeedsRehash: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			hasher := hashers.NewPlaintextHasher()
+
+			// -------------------- Act (กระทำ)--------------------
+			ok, needsRehash, err := hasher.Verify(tt.args.password, tt.args.hash)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantNeedsRehash, needsRehash)
+		})
+	}
+}
+
+func TestPlaintextHash(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	hasher := hashers.NewPlaintextHasher()
+
+	// -------------------- Act (กระทำ)--------------------
+	hash, err := hasher.Hash("admin01")
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.Equal(t, hashers.ErrHashAlgorithm, err)
+	assert.Empty(t, hash)
+}
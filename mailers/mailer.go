@@ -0,0 +1,21 @@
+package mailers
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrMailAddress = errors.New("mail address incorrect format")
+
+// MailModel is a plain text mail to a single recipient
+type MailModel struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// PORT mailer
+type Mailer interface {
+	// Send returns once the mail is handed over, delivery itself may still fail later
+	Send(ctx context.Context, mail MailModel) (err error)
+}
@@ -0,0 +1,22 @@
+package mailers
+
+import (
+	"context"
+	"log"
+)
+
+type logMailer struct {
+	logger *log.Logger
+}
+
+// NewLogMailer writes mails to logger instead of sending them, for development and tests,
+// anyone reading the log can use the links in them
+func NewLogMailer(logger *log.Logger) Mailer {
+	return logMailer{logger}
+}
+
+func (m logMailer) Send(ctx context.Context, mail MailModel) (err error) {
+	m.logger.Printf("mail to %v: %v\n%v\n", mail.To, mail.Subject, mail.Body)
+
+	return nil
+}
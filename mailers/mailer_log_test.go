@@ -0,0 +1,24 @@
+package mailers_test
+
+import (
+	"bytes"
+	"context"
+	"hexagonal-gotest/mailers"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogSend(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	out := bytes.Buffer{}
+	mailer := mailers.NewLogMailer(log.New(&out, "", 0))
+
+	// -------------------- Act (กระทำ)--------------------
+	err := mailer.Send(context.Background(), mailers.MailModel{To: "admin@example.com", Subject: "Reset your password", Body: "token abc"})
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.NoError(t, err)
+	assert.Equal(t, "mail to admin@example.com: Reset your password\ntoken abc\n", out.String())
+}
@@ -0,0 +1,20 @@
+package mailers
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type mailerMock struct {
+	mock.Mock
+}
+
+func NewMailerMock() mailerMock {
+	return mailerMock{}
+}
+
+func (m *mailerMock) Send(ctx context.Context, mail MailModel) (err error) {
+	args := m.Called(ctx, mail)
+	return args.Error(0)
+}
@@ -0,0 +1,115 @@
+package mailers
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+type smtpMailer struct {
+	addr     string
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPMailer sends through the server at addr, upgrading to tls when it offers
+// STARTTLS and authenticating when username is set
+func NewSMTPMailer(addr, username, password, from string) Mailer {
+	return smtpMailer{addr, username, password, from}
+}
+
+func (m smtpMailer) Send(ctx context.Context, mail MailModel) (err error) {
+	from, err := parseAddress(m.from)
+	if err != nil {
+		return err
+	}
+	to, err := parseAddress(mail.To)
+	if err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(m.addr)
+	if err != nil {
+		return err
+	}
+
+	// smtp.SendMail takes no context, dial ourselves so the request deadline bounds the whole exchange
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if m.username != "" {
+		// PlainAuth refuses to send the password over a connection without tls
+		if err = client.Auth(smtp.PlainAuth("", m.username, m.password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err = client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(message(from, to, mail, time.Now())); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// parseAddress also keeps line breaks out of the headers
+func parseAddress(address string) (result *mail.Address, err error) {
+	result, err = mail.ParseAddress(address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMailAddress, err)
+	}
+
+	return result, nil
+}
+
+func message(from, to *mail.Address, mail MailModel, now time.Time) []byte {
+	b := bytes.Buffer{}
+	fmt.Fprintf(&b, "From: %v\r\n", from)
+	fmt.Fprintf(&b, "To: %v\r\n", to)
+	fmt.Fprintf(&b, "Subject: %v\r\n", mime.QEncoding.Encode("utf-8", mail.Subject))
+	fmt.Fprintf(&b, "Date: %v\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(mail.Body)
+
+	return b.Bytes()
+}
@@ -0,0 +1,121 @@
+package mailers_test
+
+import (
+	"context"
+	"hexagonal-gotest/mailers"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// smtpServer accepts one session and returns the envelope and data it received, it
+// offers neither STARTTLS nor AUTH
+func smtpServer(t *testing.T) (addr string, received chan []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received = make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		text := textproto.NewConn(conn)
+
+		lines := []string{}
+		text.PrintfLine("220 localhost ready")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				break
+			}
+			command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch command {
+			case "EHLO", "HELO":
+				text.PrintfLine("250 localhost")
+			case "MAIL", "RCPT":
+				lines = append(lines, line)
+				text.PrintfLine("250 ok")
+			case "DATA":
+				text.PrintfLine("354 go ahead")
+				data, _ := text.ReadDotLines()
+				lines = append(lines, data...)
+				text.PrintfLine("250 ok")
+			case "QUIT":
+				text.PrintfLine("221 bye")
+				received <- lines
+				return
+			default:
+				text.PrintfLine("502 not implemented")
+			}
+		}
+		received <- lines
+	}()
+
+	return listener.Addr().String(), received
+}
+
+func TestSMTPSend(t *testing.T) {
+	type args struct {
+		from string
+		mail mailers.MailModel
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "error1",
+			args:    args{from: "no-reply@example.com", mail: mailers.MailModel{To: "admin@example.com\r\nBcc: victim@example.com", Subject: "Reset your password", Body: "token abc"}},
+			wantErr: true,
+		},
+		{
+			name:    "error2",
+			args:    args{from: "no-reply", mail: mailers.MailModel{To: "admin@example.com", Subject: "Reset your password", Body: "token abc"}},
+			wantErr: true,
+		},
+		{
+			name:    "success1",
+			args:    args{from: "no-reply@example.com", mail: mailers.MailModel{To: "admin@example.com", Subject: "Reset your password", Body: "token abc\n.\nend"}},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			addr, received := smtpServer(t)
+			mailer := mailers.NewSMTPMailer(addr, "", "", tt.args.from)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			// -------------------- Act (กระทำ)--------------------
+			err := mailer.Send(ctx, tt.args.mail)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			if tt.wantErr {
+				assert.ErrorIs(t, err, mailers.ErrMailAddress)
+				return
+			}
+			assert.NoError(t, err)
+
+			lines := <-received
+			assert.Contains(t, lines, "MAIL FROM:<no-reply@example.com>")
+			assert.Contains(t, lines, "RCPT TO:<admin@example.com>")
+			assert.Contains(t, lines, "To: <admin@example.com>")
+			assert.Contains(t, lines, "Subject: Reset your password")
+			// a lone dot in the body must not end the data early
+			assert.Equal(t, []string{"token abc", ".", "end"}, lines[len(lines)-3:])
+		})
+	}
+}
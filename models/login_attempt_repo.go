@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// RepoLoginAttemptModel is one failed login counted against Key, an account or a
+// client address, it may be dropped after ExpiresAt
+type RepoLoginAttemptModel struct {
+	Key       string    `bson:"key"`
+	At        time.Time `bson:"at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
@@ -1,13 +1,136 @@
 package models
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type ErrorCode string
+
+// Error is a domain error, Code is stable for clients and Error() is safe to show them.
+// Field names the request field at fault for input errors.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Field   string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Wrap keeps cause reachable with errors.Is/As for logs, the result still matches e
+func (e *Error) Wrap(cause error) error {
+	if cause == nil {
+		return e
+	}
+	return fmt.Errorf("%w: %w", e, cause)
+}
+
+// For reports e against field, the message is prefixed with the field name and the
+// result still matches e
+func (e *Error) For(field string) *Error {
+	return &Error{Code: e.Code, Message: field + " " + e.Message, Field: field}
+}
+
+// Is matches any error with the same code, so copies made by For match their sentinel
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// ValidationError reports every invalid field of a request at once, it matches
+// ErrValidation and each of its field errors with errors.Is
+type ValidationError struct {
+	Fields []*Error
+}
+
+func NewValidationError(fields ...*Error) *ValidationError {
+	return &ValidationError{Fields: fields}
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, ", ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := []error{ErrValidation}
+	for _, field := range e.Fields {
+		errs = append(errs, field)
+	}
+	return errs
+}
+
+// ThrottledError tells the client how long to wait before trying again, it matches Err
+type ThrottledError struct {
+	Err        *Error
+	RetryAfter time.Duration
+}
+
+func NewThrottledError(err *Error, retryAfter time.Duration) *ThrottledError {
+	return &ThrottledError{Err: err, RetryAfter: retryAfter}
+}
+
+func (e *ThrottledError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return e.Err
+}
+
 var (
-	ErrUsernameNotfound   = "username not found"
-	ErrPasswordNotfound   = "password not found"
-	ErrPasswordFormat     = "password must be between 6-16 characters"
-	ErrUsernameIsExist    = "username is exists"
-	ErrUsernameIsNotExist = "username is not exists"
-	ErrUserIdFormat       = "user_id incorrect format"
-	ErrUserIdIsNotExist   = "user_id is not exists"
-	ErrUnauthorized       = "unauthorized"
-	ErrUnexpected         = "unexpected"
+	ErrValidation               = &Error{Code: "validation_failed", Message: "request is invalid"}
+	ErrMalformedBody            = &Error{Code: "malformed_body", Message: "request body is not valid json"}
+	ErrUnsupportedMediaType     = &Error{Code: "unsupported_media_type", Message: "request body must be application/json"}
+	ErrBodyTooLarge             = &Error{Code: "body_too_large", Message: "request body is too large"}
+	ErrFieldRequired            = &Error{Code: "required", Message: "is required"}
+	ErrFieldFormat              = &Error{Code: "format", Message: "has an incorrect format"}
+	ErrFieldType                = &Error{Code: "type", Message: "has the wrong type"}
+	ErrFieldUnknown             = &Error{Code: "unknown_field", Message: "is not allowed"}
+	ErrUsernameNotfound         = &Error{Code: "username_not_found", Message: "username not found", Field: "username"}
+	ErrPasswordNotfound         = &Error{Code: "password_not_found", Message: "password not found", Field: "password"}
+	ErrPasswordTooShort         = &Error{Code: "password_too_short", Message: "password is too short", Field: "password"}
+	ErrPasswordTooLong          = &Error{Code: "password_too_long", Message: "password is too long", Field: "password"}
+	ErrPasswordCharacterClass   = &Error{Code: "password_character_class", Message: "password misses a required kind of character", Field: "password"}
+	ErrPasswordContainsUsername = &Error{Code: "password_contains_username", Message: "password must not contain the username", Field: "password"}
+	ErrPasswordBanned           = &Error{Code: "password_banned", Message: "password is too common", Field: "password"}
+	ErrPasswordWeak             = &Error{Code: "password_weak", Message: "password is too easy to guess", Field: "password"}
+	ErrPasswordReused           = &Error{Code: "password_reused", Message: "password was used recently", Field: "password"}
+	ErrCurrentPasswordNotfound  = &Error{Code: "current_password_not_found", Message: "current_password not found", Field: "current_password"}
+	ErrCurrentPasswordInvalid   = &Error{Code: "current_password_invalid", Message: "current_password is incorrect", Field: "current_password"}
+	ErrUsernameTooShort         = &Error{Code: "username_too_short", Message: "username is too short", Field: "username"}
+	ErrUsernameTooLong          = &Error{Code: "username_too_long", Message: "username is too long", Field: "username"}
+	ErrUsernameCharacters       = &Error{Code: "username_characters", Message: "username contains characters that are not allowed", Field: "username"}
+	ErrUsernameConfusable       = &Error{Code: "username_confusable", Message: "username mixes look-alike characters from different alphabets", Field: "username"}
+	ErrUsernameReserved         = &Error{Code: "username_reserved", Message: "username is reserved", Field: "username"}
+	ErrUsernameIsExist          = &Error{Code: "username_exists", Message: "username is exists", Field: "username"}
+	ErrUserIdFormat             = &Error{Code: "user_id_format", Message: "user_id incorrect format", Field: "user_id"}
+	ErrUserIdIsNotExist         = &Error{Code: "user_id_not_exists", Message: "user_id is not exists"}
+	ErrCredentialsChanged       = &Error{Code: "credentials_changed", Message: "password or username changed meanwhile, try again"}
+	ErrListLimit                = &Error{Code: "limit_range", Message: "limit must be between 1 and 100", Field: "limit"}
+	ErrListSort                 = &Error{Code: "sort_unsupported", Message: "sort must be username, created_at, -username or -created_at", Field: "sort"}
+	ErrListCursor               = &Error{Code: "cursor_invalid", Message: "cursor is invalid or belongs to another query", Field: "cursor"}
+	ErrDisplayNameInvalid       = &Error{Code: "display_name_invalid", Message: "display_name must be at most 64 printable characters", Field: "display_name"}
+	ErrEmailFormat              = &Error{Code: "email_format", Message: "email is not a valid address", Field: "email"}
+	ErrLocaleFormat             = &Error{Code: "locale_format", Message: "locale is not a valid language tag", Field: "locale"}
+	ErrAttributeName            = &Error{Code: "attribute_name", Message: "attribute names must be 1 to 32 lowercase letters, digits or underscores, starting with a letter", Field: "attributes"}
+	ErrAttributeTooLong         = &Error{Code: "attribute_too_long", Message: "attribute values must be at most 256 characters", Field: "attributes"}
+	ErrAttributesTooMany        = &Error{Code: "attributes_too_many", Message: "at most 16 attributes are allowed", Field: "attributes"}
+	ErrRefreshTokenNotfound     = &Error{Code: "refresh_token_not_found", Message: "refresh_token not found", Field: "refresh_token"}
+	ErrRefreshTokenIsNotExist   = &Error{Code: "refresh_token_not_exists", Message: "refresh_token is not exists"}
+	ErrRefreshTokenIsUsed       = &Error{Code: "refresh_token_used", Message: "refresh_token is used"}
+	ErrResetTokenNotfound       = &Error{Code: "reset_token_not_found", Message: "token not found", Field: "token"}
+	ErrResetTokenInvalid        = &Error{Code: "reset_token_invalid", Message: "token is invalid, used or expired", Field: "token"}
+	ErrInvalidCredentials       = &Error{Code: "invalid_credentials", Message: "username or password is incorrect"}
+	ErrTooManyAttempts          = &Error{Code: "too_many_attempts", Message: "too many login attempts, try again later"}
+	ErrAccountLocked            = &Error{Code: "account_locked", Message: "account is temporarily locked"}
+	ErrUnauthorized             = &Error{Code: "unauthorized", Message: "unauthorized"}
+	ErrForbidden                = &Error{Code: "forbidden", Message: "forbidden"}
+	ErrUnexpected               = &Error{Code: "unexpected", Message: "unexpected"}
 )
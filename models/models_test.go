@@ -0,0 +1,42 @@
+package models_test
+
+import (
+	"errors"
+	"hexagonal-gotest/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorWrap(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	cause := errors.New("connection refused")
+
+	// -------------------- Act (กระทำ)--------------------
+	err := models.ErrUnexpected.Wrap(cause)
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.ErrorIs(t, err, models.ErrUnexpected)
+	assert.ErrorIs(t, err, cause)
+	assert.NotErrorIs(t, err, models.ErrUnauthorized)
+
+	domainErr := &models.Error{}
+	assert.True(t, errors.As(err, &domainErr))
+	assert.Equal(t, models.ErrorCode("unexpected"), domainErr.Code)
+	assert.Equal(t, "unexpected: connection refused", err.Error())
+
+	assert.Same(t, models.ErrUnexpected, models.ErrUnexpected.Wrap(nil))
+}
+
+func TestErrorFor(t *testing.T) {
+	// -------------------- Act (กระทำ)--------------------
+	err := models.ErrFieldRequired.For("username")
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.ErrorIs(t, err, models.ErrFieldRequired)
+	assert.NotErrorIs(t, err, models.ErrFieldFormat)
+	assert.Equal(t, "username", err.Field)
+	assert.Equal(t, "username is required", err.Error())
+	assert.ErrorIs(t, models.NewValidationError(err), models.ErrValidation)
+	assert.ErrorIs(t, models.NewValidationError(err), models.ErrFieldRequired)
+}
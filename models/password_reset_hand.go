@@ -0,0 +1,11 @@
+package models
+
+type HandForgotPasswordBodyModel struct {
+	Username string `json:"username" validate:"required"`
+}
+
+// HandResetPasswordBodyModel Token is the one mailed by forgot password
+type HandResetPasswordBodyModel struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
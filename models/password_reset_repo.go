@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RepoPasswordResetModel is a reset token mailed to UserId, only the hash of the token is
+// stored so a leaked table cannot reset anyone's password
+type RepoPasswordResetModel struct {
+	TokenHash string    `bson:"token_hash"`
+	UserId    string    `bson:"user_id"`
+	CreatedAt time.Time `bson:"created_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
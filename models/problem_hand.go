@@ -0,0 +1,19 @@
+package models
+
+// HandProblemModel is an RFC 7807 application/problem+json body, Code extends it
+// with the stable domain error code
+type HandProblemModel struct {
+	Type     string                  `json:"type"`
+	Title    string                  `json:"title"`
+	Status   int                     `json:"status"`
+	Detail   string                  `json:"detail,omitempty"`
+	Instance string                  `json:"instance,omitempty"`
+	Code     ErrorCode               `json:"code"`
+	Errors   []HandProblemFieldModel `json:"errors,omitempty"`
+}
+
+type HandProblemFieldModel struct {
+	Field  string    `json:"field"`
+	Code   ErrorCode `json:"code"`
+	Detail string    `json:"detail"`
+}
@@ -0,0 +1,10 @@
+package models
+
+type HandRefreshTokenBodyModel struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// HandLogoutBodyModel RefreshToken is optional, without it only the access token is revoked
+type HandLogoutBodyModel struct {
+	RefreshToken string `json:"refresh_token"`
+}
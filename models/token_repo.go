@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// RepoRefreshTokenModel CredentialVersion is the password version of the user the token
+// was issued for
+type RepoRefreshTokenModel struct {
+	TokenHash         string     `bson:"token_hash"`
+	FamilyId          string     `bson:"family_id"`
+	UserId            string     `bson:"user_id"`
+	CredentialVersion int        `bson:"credential_version"`
+	ExpiresAt         time.Time  `bson:"expires_at"`
+	UsedAt            *time.Time `bson:"used_at"`
+	Revoked           bool       `bson:"revoked"`
+}
+
+// RepoRevokedTokenModel revokes a single token by Jti, or every token of UserId issued up to RevokedAt when Jti is empty
+type RepoRevokedTokenModel struct {
+	Jti       string    `bson:"jti,omitempty"`
+	UserId    string    `bson:"user_id,omitempty"`
+	RevokedAt time.Time `bson:"revoked_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
@@ -1,23 +1,86 @@
 package models
 
+import "time"
+
 type HandRegisterBodyModel struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
 }
 
 type HandLoginBodyModel struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// HandChangePasswordBodyModel Password is the new password, named like everywhere
+// else a password is set so policy errors point at it
+type HandChangePasswordBodyModel struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	Password        string `json:"password" validate:"required"`
 }
 
-type HandResetPasswordParamsModel struct {
-	UserId string `params:"user_id"`
+type HandChangeUsernameParamsModel struct {
+	UserId string `params:"user_id" validate:"required,uuid"`
 }
 
-type HandResetPasswordBodyModel struct {
-	Password string `json:"password"`
+type HandChangeUsernameBodyModel struct {
+	Username string `json:"username" validate:"required"`
 }
 
 type HandDeleteUserParamsModel struct {
-	UserId string `params:"user_id"`
+	UserId string `params:"user_id" validate:"required,uuid"`
+}
+
+type HandUnlockUserParamsModel struct {
+	UserId string `params:"user_id" validate:"required,uuid"`
+}
+
+type HandGetUserParamsModel struct {
+	UserId string `params:"user_id" validate:"required,uuid"`
+}
+
+type HandListUsersQueryModel struct {
+	UsernamePrefix string `query:"username_prefix"`
+	Sort           string `query:"sort"`
+	Cursor         string `query:"cursor"`
+	Limit          string `query:"limit" validate:"uint"`
+}
+
+// HandUserModel is the public shape of a user, it never carries the password
+type HandUserModel struct {
+	UserId    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type HandUserPageModel struct {
+	Users      []HandUserModel `json:"users"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+type HandProfileParamsModel struct {
+	UserId string `params:"user_id" validate:"required,uuid"`
+}
+
+// HandUpdateProfileBodyModel fields left out or null are unchanged and empty strings
+// clear them, an attribute set to null is removed
+type HandUpdateProfileBodyModel struct {
+	DisplayName *string            `json:"display_name"`
+	Email       *string            `json:"email"`
+	Locale      *string            `json:"locale"`
+	Attributes  map[string]*string `json:"attributes"`
+}
+
+// HandProfileModel LastLoginAt is left out for users who never logged in
+type HandProfileModel struct {
+	UserId      string            `json:"user_id"`
+	Username    string            `json:"username"`
+	DisplayName string            `json:"display_name"`
+	Email       string            `json:"email"`
+	Locale      string            `json:"locale"`
+	Attributes  map[string]string `json:"attributes"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	LastLoginAt *time.Time        `json:"last_login_at,omitempty"`
 }
@@ -1,9 +1,24 @@
 package models
 
+import "time"
+
+// RepoUserModel LastLoginAt is zero for users who never logged in. PasswordHistory
+// holds the hashes of earlier passwords, newest first, and CredentialVersion counts the
+// password changes so tokens from before the last one can be told apart.
 type RepoUserModel struct {
-	UserId   string `bson:"user_id"`
-	Username string `bson:"username"`
-	Password string `bson:"password"`
+	UserId            string            `bson:"user_id"`
+	Username          string            `bson:"username"`
+	Password          string            `bson:"password"`
+	PasswordHistory   []string          `bson:"password_history"`
+	CredentialVersion int               `bson:"credential_version"`
+	Role              string            `bson:"role"`
+	DisplayName       string            `bson:"display_name"`
+	Email             string            `bson:"email"`
+	Locale            string            `bson:"locale"`
+	Attributes        map[string]string `bson:"attributes"`
+	CreatedAt         time.Time         `bson:"created_at"`
+	UpdatedAt         time.Time         `bson:"updated_at"`
+	LastLoginAt       time.Time         `bson:"last_login_at"`
 }
 
 type RepoGetUserModel struct {
@@ -11,13 +26,51 @@ type RepoGetUserModel struct {
 	Username string `bson:"username,omitempty"`
 }
 
+// RepoCreateUserModel omits empty attributes so they are stored missing rather than
+// null, which mongo could not set keys inside
 type RepoCreateUserModel struct {
-	UserId   string `bson:"user_id"`
-	Username string `bson:"username"`
-	Password string `bson:"password"`
+	UserId      string            `bson:"user_id"`
+	Username    string            `bson:"username"`
+	Password    string            `bson:"password"`
+	Role        string            `bson:"role"`
+	DisplayName string            `bson:"display_name"`
+	Email       string            `bson:"email"`
+	Locale      string            `bson:"locale"`
+	Attributes  map[string]string `bson:"attributes,omitempty"`
+	CreatedAt   time.Time         `bson:"created_at"`
+	UpdatedAt   time.Time         `bson:"updated_at"`
 }
 
+const (
+	UserSortUsername  = "username"
+	UserSortCreatedAt = "created_at"
+)
+
+// RepoListUserModel pages through users ordered by SortBy then user_id, After is the
+// last user of the previous page
+type RepoListUserModel struct {
+	UsernamePrefix string
+	SortBy         string
+	Descending     bool
+	After          *RepoUserModel
+	Limit          int
+}
+
+// RepoUpdateUserModel leaves zero fields unchanged, profile fields are pointers so
+// they can be set to empty. Attributes are merged into the stored ones and a nil value
+// removes its key. PasswordHistory replaces the stored one. CredentialVersion must be
+// one more than the stored version, otherwise nothing is updated and the update fails
+// with ErrCredentialsChanged, so two concurrent credential changes cannot both end up
+// with the same version.
 type RepoUpdateUserModel struct {
-	Username string `bson:"username,omitempty"`
-	Password string `bson:"password,omitempty"`
+	Username          string             `bson:"username,omitempty"`
+	Password          string             `bson:"password,omitempty"`
+	PasswordHistory   []string           `bson:"password_history,omitempty"`
+	CredentialVersion int                `bson:"credential_version,omitempty"`
+	DisplayName       *string            `bson:"display_name,omitempty"`
+	Email             *string            `bson:"email,omitempty"`
+	Locale            *string            `bson:"locale,omitempty"`
+	Attributes        map[string]*string `bson:"-"`
+	UpdatedAt         time.Time          `bson:"updated_at,omitempty"`
+	LastLoginAt       time.Time          `bson:"last_login_at,omitempty"`
 }
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// SrvPrincipalModel is the authenticated caller taken from a validated token
+type SrvPrincipalModel struct {
+	UserId            string
+	Username          string
+	Role              string
+	CredentialVersion int
+}
+
+// SrvUserModel is a user as the service hands it out, the password hash never leaves the service
+type SrvUserModel struct {
+	UserId    string
+	Username  string
+	Role      string
+	CreatedAt time.Time
+}
+
+// SrvListUsersModel Sort is username or created_at, prefixed with - for descending.
+// Cursor is the NextCursor of the previous page and only fits the same Sort and
+// UsernamePrefix.
+type SrvListUsersModel struct {
+	UsernamePrefix string
+	Sort           string
+	Cursor         string
+	Limit          int
+}
+
+// SrvUserPageModel NextCursor is empty on the last page
+type SrvUserPageModel struct {
+	Users      []SrvUserModel
+	NextCursor string
+}
+
+// SrvProfileModel LastLoginAt is zero for users who never logged in
+type SrvProfileModel struct {
+	UserId      string
+	Username    string
+	DisplayName string
+	Email       string
+	Locale      string
+	Attributes  map[string]string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	LastLoginAt time.Time
+}
+
+// SrvUpdateProfileModel nil fields are left unchanged and empty ones are cleared,
+// an attribute set to nil is removed
+type SrvUpdateProfileModel struct {
+	DisplayName *string
+	Email       *string
+	Locale      *string
+	Attributes  map[string]*string
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RepoUsernameHistoryModel is a username UserId changed away from, it stays held for
+// UserId until ExpiresAt and may be dropped after
+type RepoUsernameHistoryModel struct {
+	Username   string    `bson:"username"`
+	UserId     string    `bson:"user_id"`
+	ReleasedAt time.Time `bson:"released_at"`
+	ExpiresAt  time.Time `bson:"expires_at"`
+}
@@ -0,0 +1,159 @@
+package policies
+
+import (
+	"bufio"
+	"fmt"
+	"hexagonal-gotest/models"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type CharacterClass string
+
+const (
+	ClassLower  CharacterClass = "lower"
+	ClassUpper  CharacterClass = "upper"
+	ClassDigit  CharacterClass = "digit"
+	ClassSymbol CharacterClass = "symbol"
+)
+
+// PORT password policy
+type PasswordPolicy interface {
+	// Check returns a *models.ValidationError listing every rule password breaks,
+	// username is the account the password is for.
+	Check(username, password string) error
+}
+
+// PasswordPolicyConfig lengths count characters (runes), not bytes. Zero values
+// turn a rule off, except MaxLength which is always enforced.
+type PasswordPolicyConfig struct {
+	MinLength        int
+	MaxLength        int
+	RequiredClasses  []CharacterClass
+	DisallowUsername bool
+	// Banned are matched case-insensitively against the whole password
+	Banned         []string
+	MinEntropyBits float64
+}
+
+// DefaultPasswordPolicyConfig follows NIST 800-63B, length over composition rules
+var DefaultPasswordPolicyConfig = PasswordPolicyConfig{
+	MinLength:        8,
+	MaxLength:        64,
+	DisallowUsername: true,
+	MinEntropyBits:   30,
+}
+
+type passwordPolicy struct {
+	config PasswordPolicyConfig
+	banned map[string]bool
+}
+
+func NewPasswordPolicy(config PasswordPolicyConfig) PasswordPolicy {
+	banned := map[string]bool{}
+	for _, password := range config.Banned {
+		banned[strings.ToLower(password)] = true
+	}
+	return passwordPolicy{config, banned}
+}
+
+// LoadBannedPasswords reads one password per line, blank lines and lines starting with # are skipped
+func LoadBannedPasswords(path string) (banned []string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		banned = append(banned, line)
+	}
+	return banned, scanner.Err()
+}
+
+func (p passwordPolicy) Check(username, password string) error {
+	violations := []*models.Error{}
+	length := utf8.RuneCountInString(password)
+
+	if length < p.config.MinLength {
+		violations = append(violations, violation(models.ErrPasswordTooShort, "password must be at least %v characters", p.config.MinLength))
+	}
+	if length > p.config.MaxLength {
+		violations = append(violations, violation(models.ErrPasswordTooLong, "password must be at most %v characters", p.config.MaxLength))
+	}
+
+	classes := characterClasses(password)
+	for _, class := range p.config.RequiredClasses {
+		if !classes[class] {
+			violations = append(violations, violation(models.ErrPasswordCharacterClass, "password must contain a %v character", class))
+		}
+	}
+
+	// very short usernames would match by accident
+	if p.config.DisallowUsername && utf8.RuneCountInString(username) >= 3 && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		violations = append(violations, models.ErrPasswordContainsUsername)
+	}
+
+	if p.banned[strings.ToLower(password)] {
+		violations = append(violations, models.ErrPasswordBanned)
+	}
+
+	if p.config.MinEntropyBits > 0 && entropyBits(password) < p.config.MinEntropyBits {
+		violations = append(violations, models.ErrPasswordWeak)
+	}
+
+	if len(violations) > 0 {
+		return models.NewValidationError(violations...)
+	}
+	return nil
+}
+
+func violation(err *models.Error, format string, args ...any) *models.Error {
+	return &models.Error{Code: err.Code, Message: fmt.Sprintf(format, args...), Field: err.Field}
+}
+
+func characterClasses(password string) map[CharacterClass]bool {
+	classes := map[CharacterClass]bool{}
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			classes[ClassLower] = true
+		case unicode.IsUpper(r):
+			classes[ClassUpper] = true
+		case unicode.IsDigit(r):
+			classes[ClassDigit] = true
+		default:
+			classes[ClassSymbol] = true
+		}
+	}
+	return classes
+}
+
+// entropyBits estimates the strength of password as if its distinct characters were
+// drawn at random from the alphabets of the classes it uses, repeats add one bit each
+func entropyBits(password string) float64 {
+	alphabets := map[CharacterClass]float64{ClassLower: 26, ClassUpper: 26, ClassDigit: 10, ClassSymbol: 33}
+	pool := 0.0
+	for class := range characterClasses(password) {
+		pool += alphabets[class]
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	distinct := map[rune]bool{}
+	for _, r := range password {
+		distinct[r] = true
+	}
+	repeats := utf8.RuneCountInString(password) - len(distinct)
+
+	return float64(len(distinct))*math.Log2(pool) + float64(repeats)
+}
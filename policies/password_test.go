@@ -0,0 +1,129 @@
+package policies_test
+
+import (
+	"errors"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/policies"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	type args struct {
+		username string
+		password string
+	}
+	config := policies.PasswordPolicyConfig{
+		MinLength:        8,
+		MaxLength:        64,
+		DisallowUsername: true,
+		Banned:           []string{"Password123"},
+		MinEntropyBits:   30,
+	}
+	tests := []struct {
+		name     string
+		config   policies.PasswordPolicyConfig
+		args     args
+		wantErrs []error
+	}{
+		// TODO: Add test cases.
+		{
+			name:     "too short",
+			config:   config,
+			args:     args{username: "somchai", password: "k9#Lp2"},
+			wantErrs: []error{models.ErrPasswordTooShort},
+		},
+		{
+			name:     "too long",
+			config:   policies.PasswordPolicyConfig{MinLength: 1, MaxLength: 16},
+			args:     args{username: "somchai", password: "correct horse battery staple"},
+			wantErrs: []error{models.ErrPasswordTooLong},
+		},
+		{
+			name:     "length counts characters not bytes",
+			config:   policies.PasswordPolicyConfig{MinLength: 8, MaxLength: 8},
+			args:     args{username: "somchai", password: "รหัสผ่าน"},
+			wantErrs: nil,
+		},
+		{
+			name:     "missing character classes",
+			config:   policies.PasswordPolicyConfig{MinLength: 1, MaxLength: 64, RequiredClasses: []policies.CharacterClass{policies.ClassUpper, policies.ClassDigit, policies.ClassSymbol}},
+			args:     args{username: "somchai", password: "lowercaseonly"},
+			wantErrs: []error{models.ErrPasswordCharacterClass, models.ErrPasswordCharacterClass, models.ErrPasswordCharacterClass},
+		},
+		{
+			name:     "contains username",
+			config:   config,
+			args:     args{username: "Somchai", password: "xx-somchai-2024!"},
+			wantErrs: []error{models.ErrPasswordContainsUsername},
+		},
+		{
+			name:     "banned ignores case",
+			config:   config,
+			args:     args{username: "somchai", password: "PASSWORD123"},
+			wantErrs: []error{models.ErrPasswordBanned},
+		},
+		{
+			name:     "repeated characters are weak",
+			config:   config,
+			args:     args{username: "somchai", password: "aaaaaaaaaaaa"},
+			wantErrs: []error{models.ErrPasswordWeak},
+		},
+		{
+			name:     "every violation at once",
+			config:   config,
+			args:     args{username: "abc", password: "abc"},
+			wantErrs: []error{models.ErrPasswordTooShort, models.ErrPasswordContainsUsername, models.ErrPasswordWeak},
+		},
+		{
+			name:     "passphrase",
+			config:   config,
+			args:     args{username: "somchai", password: "correct horse battery staple"},
+			wantErrs: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			policy := policies.NewPasswordPolicy(tt.config)
+
+			// -------------------- Act (กระทำ)--------------------
+			err := policy.Check(tt.args.username, tt.args.password)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			if tt.wantErrs == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, models.ErrValidation)
+			validationErr := &models.ValidationError{}
+			if assert.True(t, errors.As(err, &validationErr)) {
+				assert.Len(t, validationErr.Fields, len(tt.wantErrs))
+				for i, wantErr := range tt.wantErrs {
+					assert.ErrorIs(t, validationErr.Fields[i], wantErr)
+					assert.Equal(t, "password", validationErr.Fields[i].Field)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadBannedPasswords(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	path := filepath.Join(t.TempDir(), "banned.txt")
+	os.WriteFile(path, []byte("# common passwords\n123456\n\n  qwerty  \npassword\n"), 0o600)
+
+	// -------------------- Act (กระทำ)--------------------
+	banned, err := policies.LoadBannedPasswords(path)
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"123456", "qwerty", "password"}, banned)
+
+	_, err = policies.LoadBannedPasswords(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
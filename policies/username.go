@@ -0,0 +1,150 @@
+package policies
+
+import (
+	"hexagonal-gotest/models"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// PORT username policy
+type UsernamePolicy interface {
+	// Canonical maps username to the form it is stored and looked up by, it applies
+	// no rules so existing accounts can still be found.
+	Canonical(username string) string
+
+	// Check returns the canonical form of a new username, or a *models.ValidationError
+	// listing every rule it breaks.
+	Check(username string) (canonical string, err error)
+}
+
+// UsernamePolicyConfig lengths count characters of the canonical form. Reserved names
+// are also refused when spelled with other cases or look-alike characters.
+type UsernamePolicyConfig struct {
+	MinLength int
+	MaxLength int
+	// AllowUnicode accepts letters and digits of every script, otherwise only a-z and 0-9
+	AllowUnicode bool
+	Reserved     []string
+}
+
+// usernameSymbols may appear inside a username but not start it
+const usernameSymbols = "._-"
+
+var DefaultUsernamePolicyConfig = UsernamePolicyConfig{
+	MinLength:    3,
+	MaxLength:    32,
+	AllowUnicode: true,
+	Reserved:     []string{"admin", "root", "system"},
+}
+
+type usernamePolicy struct {
+	config   UsernamePolicyConfig
+	reserved map[string]bool
+}
+
+func NewUsernamePolicy(config UsernamePolicyConfig) UsernamePolicy {
+	p := usernamePolicy{config, map[string]bool{}}
+	for _, name := range config.Reserved {
+		p.reserved[skeleton(p.Canonical(name))] = true
+	}
+	return p
+}
+
+// Canonical is NFKC, so width and compatibility variants collapse, then case folded
+func (p usernamePolicy) Canonical(username string) string {
+	return norm.NFKC.String(cases.Fold().String(norm.NFKC.String(strings.TrimSpace(username))))
+}
+
+func (p usernamePolicy) Check(username string) (canonical string, err error) {
+	canonical = p.Canonical(username)
+	violations := []*models.Error{}
+	length := utf8.RuneCountInString(canonical)
+
+	if length < p.config.MinLength {
+		violations = append(violations, violation(models.ErrUsernameTooShort, "username must be at least %v characters", p.config.MinLength))
+	}
+	if length > p.config.MaxLength {
+		violations = append(violations, violation(models.ErrUsernameTooLong, "username must be at most %v characters", p.config.MaxLength))
+	}
+
+	if !p.allowedCharacters(canonical) {
+		violations = append(violations, violation(models.ErrUsernameCharacters, "username may only contain letters, digits and %q, and must start with a letter or digit", usernameSymbols))
+	} else if isConfusable(canonical) {
+		violations = append(violations, models.ErrUsernameConfusable)
+	}
+
+	if p.reserved[skeleton(canonical)] {
+		violations = append(violations, models.ErrUsernameReserved)
+	}
+
+	if len(violations) > 0 {
+		return canonical, models.NewValidationError(violations...)
+	}
+	return canonical, nil
+}
+
+func (p usernamePolicy) allowedCharacters(username string) bool {
+	for i, r := range username {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLower(r) || unicode.IsDigit(r)):
+		case p.config.AllowUnicode && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+		// combining marks, like Thai vowels and tone marks, only follow a letter
+		case p.config.AllowUnicode && unicode.IsMark(r) && i > 0:
+		case strings.ContainsRune(usernameSymbols, r) && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// confusables maps Cyrillic and Greek letters to the Latin letters they render like,
+// after case folding. It is the subset of Unicode TR39 that matters for ASCII names.
+var confusables = map[rune]rune{
+	'а': 'a', 'в': 'b', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'һ': 'h', 'і': 'i', 'ј': 'j',
+	'к': 'k', 'м': 'm', 'о': 'o', 'р': 'p', 'ԛ': 'q', 'г': 'r', 'ѕ': 's',
+	'т': 't', 'ѵ': 'v', 'ԝ': 'w', 'х': 'x', 'у': 'y', 'ӏ': 'l',
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'γ': 'y',
+}
+
+// skeleton replaces look-alike letters so that confusable names compare equal
+func skeleton(username string) string {
+	return strings.Map(func(r rune) rune {
+		if latin, ok := confusables[r]; ok {
+			return latin
+		}
+		return r
+	}, username)
+}
+
+// isConfusable reports names mixing Latin, Cyrillic and Greek letters, and names written
+// entirely in look-alikes of Latin letters, like Cyrillic "раураl"
+func isConfusable(username string) bool {
+	scripts := map[string]bool{}
+	for _, r := range username {
+		for name, table := range map[string]*unicode.RangeTable{"Latin": unicode.Latin, "Cyrillic": unicode.Cyrillic, "Greek": unicode.Greek} {
+			if unicode.Is(table, r) {
+				scripts[name] = true
+			}
+		}
+	}
+	if len(scripts) > 1 {
+		return true
+	}
+	if scripts["Latin"] || len(scripts) == 0 {
+		return false
+	}
+
+	latin := skeleton(username)
+	for _, r := range latin {
+		if r >= utf8.RuneSelf && unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
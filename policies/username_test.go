@@ -0,0 +1,128 @@
+package policies_test
+
+import (
+	"errors"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/policies"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsernamePolicyCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        policies.UsernamePolicyConfig
+		username      string
+		wantCanonical string
+		wantErrs      []error
+	}{
+		// TODO: Add test cases.
+		{
+			name:          "case and surrounding spaces",
+			config:        policies.DefaultUsernamePolicyConfig,
+			username:      " Somchai ",
+			wantCanonical: "somchai",
+		},
+		{
+			name:          "fullwidth letters are nfkc normalized",
+			config:        policies.DefaultUsernamePolicyConfig,
+			username:      "ｓｏｍｃｈａｉ",
+			wantCanonical: "somchai",
+		},
+		{
+			name:          "thai with combining marks",
+			config:        policies.DefaultUsernamePolicyConfig,
+			username:      "สมชาย_ใจดี",
+			wantCanonical: "สมชาย_ใจดี",
+		},
+		{
+			name:          "ascii only",
+			config:        policies.UsernamePolicyConfig{MinLength: 3, MaxLength: 32},
+			username:      "สมชาย",
+			wantCanonical: "สมชาย",
+			wantErrs:      []error{models.ErrUsernameCharacters},
+		},
+		{
+			name:          "too short and symbol first",
+			config:        policies.DefaultUsernamePolicyConfig,
+			username:      ".a",
+			wantCanonical: ".a",
+			wantErrs:      []error{models.ErrUsernameTooShort, models.ErrUsernameCharacters},
+		},
+		{
+			name:          "too long",
+			config:        policies.UsernamePolicyConfig{MinLength: 3, MaxLength: 8},
+			username:      "somchai.jaidee",
+			wantCanonical: "somchai.jaidee",
+			wantErrs:      []error{models.ErrUsernameTooLong},
+		},
+		{
+			name:          "spaces inside",
+			config:        policies.DefaultUsernamePolicyConfig,
+			username:      "som chai",
+			wantCanonical: "som chai",
+			wantErrs:      []error{models.ErrUsernameCharacters},
+		},
+		{
+			name:          "mixed latin and cyrillic",
+			config:        policies.DefaultUsernamePolicyConfig,
+			username:      "pаypal",
+			wantCanonical: "pаypal",
+			wantErrs:      []error{models.ErrUsernameConfusable},
+		},
+		{
+			name:          "cyrillic look-alike of a latin name",
+			config:        policies.DefaultUsernamePolicyConfig,
+			username:      "рор",
+			wantCanonical: "рор",
+			wantErrs:      []error{models.ErrUsernameConfusable},
+		},
+		{
+			name:          "cyrillic name",
+			config:        policies.DefaultUsernamePolicyConfig,
+			username:      "дмитрий",
+			wantCanonical: "дмитрий",
+		},
+		{
+			name:          "reserved",
+			config:        policies.DefaultUsernamePolicyConfig,
+			username:      "ROOT",
+			wantCanonical: "root",
+			wantErrs:      []error{models.ErrUsernameReserved},
+		},
+		{
+			name:          "reserved look-alike",
+			config:        policies.DefaultUsernamePolicyConfig,
+			username:      "аdmin",
+			wantCanonical: "аdmin",
+			wantErrs:      []error{models.ErrUsernameConfusable, models.ErrUsernameReserved},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			policy := policies.NewUsernamePolicy(tt.config)
+
+			// -------------------- Act (กระทำ)--------------------
+			canonical, err := policy.Check(tt.username)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.Equal(t, tt.wantCanonical, canonical)
+			assert.Equal(t, canonical, policy.Canonical(tt.username))
+			if tt.wantErrs == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			validationErr := &models.ValidationError{}
+			if assert.True(t, errors.As(err, &validationErr)) {
+				assert.Len(t, validationErr.Fields, len(tt.wantErrs))
+				for i, wantErr := range tt.wantErrs {
+					assert.ErrorIs(t, validationErr.Fields[i], wantErr)
+					assert.Equal(t, "username", validationErr.Fields[i].Field)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+	"time"
+)
+
+// PORT login attempt repository
+type LoginAttemptRepository interface {
+	Create(ctx context.Context, payload models.RepoLoginAttemptModel) (err error)
+
+	// Gets returns the attempts of key made since, oldest first
+	Gets(ctx context.Context, key string, since time.Time) (result []models.RepoLoginAttemptModel, err error)
+
+	Delete(ctx context.Context, key string) (err error)
+}
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+	"sync"
+	"time"
+)
+
+type loginAttemptMemory struct {
+	mu       *sync.Mutex
+	attempts map[string][]models.RepoLoginAttemptModel
+	sweep    memorySweep
+}
+
+func NewLoginAttemptMemoryRepository() LoginAttemptRepository {
+	return loginAttemptMemory{&sync.Mutex{}, map[string][]models.RepoLoginAttemptModel{}, newMemorySweep()}
+}
+
+func (r loginAttemptMemory) Create(ctx context.Context, payload models.RepoLoginAttemptModel) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if r.sweep.due(now) {
+		r.evictExpired(now)
+	}
+
+	r.attempts[payload.Key] = append(liveAttempts(r.attempts[payload.Key], now), payload)
+
+	return nil
+}
+
+func (r loginAttemptMemory) Gets(ctx context.Context, key string, since time.Time) (result []models.RepoLoginAttemptModel, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, attempt := range r.attempts[key] {
+		if !attempt.At.Before(since) && now.Before(attempt.ExpiresAt) {
+			result = append(result, attempt)
+		}
+	}
+
+	return result, nil
+}
+
+func (r loginAttemptMemory) Delete(ctx context.Context, key string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+
+	return nil
+}
+
+// evictExpired drops the keys of clients that never come back, caller holds mu
+func (r loginAttemptMemory) evictExpired(now time.Time) {
+	for key, attempts := range r.attempts {
+		if live := liveAttempts(attempts, now); len(live) == 0 {
+			delete(r.attempts, key)
+		} else {
+			r.attempts[key] = live
+		}
+	}
+}
+
+// liveAttempts filters attempts in place down to the unexpired ones
+func liveAttempts(attempts []models.RepoLoginAttemptModel, now time.Time) []models.RepoLoginAttemptModel {
+	live := attempts[:0]
+	for _, attempt := range attempts {
+		if now.Before(attempt.ExpiresAt) {
+			live = append(live, attempt)
+		}
+	}
+	return live
+}
@@ -0,0 +1,39 @@
+package repositories_test
+
+import (
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginAttemptMemory(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	loginAttemptRepo := repositories.NewLoginAttemptMemoryRepository()
+	now := time.Now()
+
+	// -------------------- Act (กระทำ)--------------------
+	loginAttemptRepo.Create(ctx, models.RepoLoginAttemptModel{Key: "user:admin", At: now.Add(-2 * time.Minute), ExpiresAt: now.Add(time.Hour)})
+	loginAttemptRepo.Create(ctx, models.RepoLoginAttemptModel{Key: "user:admin", At: now, ExpiresAt: now.Add(time.Hour)})
+	loginAttemptRepo.Create(ctx, models.RepoLoginAttemptModel{Key: "user:admin", At: now, ExpiresAt: now.Add(-time.Second)})
+	loginAttemptRepo.Create(ctx, models.RepoLoginAttemptModel{Key: "ip:10.0.0.1", At: now, ExpiresAt: now.Add(time.Hour)})
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	attempts, err := loginAttemptRepo.Gets(ctx, "user:admin", now.Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, attempts, 2)
+	assert.True(t, attempts[0].At.Before(attempts[1].At))
+
+	attempts, _ = loginAttemptRepo.Gets(ctx, "user:admin", now.Add(-time.Minute))
+	assert.Len(t, attempts, 1)
+
+	assert.NoError(t, loginAttemptRepo.Delete(ctx, "user:admin"))
+
+	attempts, _ = loginAttemptRepo.Gets(ctx, "user:admin", now.Add(-time.Hour))
+	assert.Empty(t, attempts)
+
+	attempts, _ = loginAttemptRepo.Gets(ctx, "ip:10.0.0.1", now.Add(-time.Hour))
+	assert.Len(t, attempts, 1)
+}
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type loginAttemptRepoMock struct {
+	mock.Mock
+}
+
+func NewLoginAttemptRepoMock() loginAttemptRepoMock {
+	return loginAttemptRepoMock{}
+}
+
+func (m *loginAttemptRepoMock) Create(ctx context.Context, payload models.RepoLoginAttemptModel) (err error) {
+	args := m.Called(ctx, payload)
+	return args.Error(0)
+}
+
+func (m *loginAttemptRepoMock) Gets(ctx context.Context, key string, since time.Time) (result []models.RepoLoginAttemptModel, err error) {
+	args := m.Called(ctx, key, since)
+	res, _ := args.Get(0).([]models.RepoLoginAttemptModel)
+	return res, args.Error(1)
+}
+
+func (m *loginAttemptRepoMock) Delete(ctx context.Context, key string) (err error) {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type loginAttemptRepo struct {
+	db         *mongo.Database
+	collection string
+	timeouts   Timeouts
+}
+
+func NewLoginAttemptRepository(db *mongo.Database, collection string, timeouts Timeouts) LoginAttemptRepository {
+	return loginAttemptRepo{db, collection, timeouts}
+}
+
+// EnsureLoginAttemptIndexes lets mongo drop attempts once they leave the throttling window
+func EnsureLoginAttemptIndexes(ctx context.Context, db *mongo.Database, collection string) (err error) {
+	_, err = db.Collection(collection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "key", Value: 1}, {Key: "at", Value: 1}}},
+	})
+
+	return err
+}
+
+func (r loginAttemptRepo) Create(ctx context.Context, payload models.RepoLoginAttemptModel) (err error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err = r.db.Collection(r.collection).InsertOne(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r loginAttemptRepo) Gets(ctx context.Context, key string, since time.Time) (result []models.RepoLoginAttemptModel, err error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	// the ttl monitor runs once a minute, expired attempts are filtered until it catches up
+	cursor, err := r.db.Collection(r.collection).Find(ctx, bson.D{
+		{Key: "key", Value: key},
+		{Key: "at", Value: bson.D{{Key: "$gte", Value: since}}},
+		{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: time.Now()}}},
+	}, options.Find().SetSort(bson.D{{Key: "at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r loginAttemptRepo) Delete(ctx context.Context, key string) (err error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err = r.db.Collection(r.collection).DeleteMany(ctx, bson.D{{Key: "key", Value: key}})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package repositories_test
+
+import (
+	"fmt"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestCreateLoginAttempt(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantResult bson.D
+		wantErr    bool
+	}{
+		// TODO: Add test cases.
+		{
+			name: "error1",
+			wantResult: mtest.CreateWriteErrorsResponse(mtest.WriteError{
+				Index:   1,
+				Code:    123,
+				Message: "some error",
+			}),
+			wantErr: true,
+		},
+		{
+			name:       "success1",
+			wantResult: mtest.CreateSuccessResponse(),
+			wantErr:    false,
+		},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt.Run(tt.name, func(mt *mtest.T) {
+				// ------------------- Arrange (เตรียมของ) --------------------
+
+				// mock InsertOne
+				mt.AddMockResponses(tt.wantResult)
+
+				loginAttemptRepo := repositories.NewLoginAttemptRepository(mt.DB, "login_attempts", repositories.DefaultTimeouts)
+
+				// -------------------- Act (กระทำ)--------------------
+				now := time.Now()
+				err := loginAttemptRepo.Create(ctx, models.RepoLoginAttemptModel{Key: "user:admin", At: now, ExpiresAt: now.Add(time.Hour)})
+
+				// -------------------- Assert (ยืนยัน) --------------------
+				assert.Equal(mt, tt.wantErr, err != nil)
+			})
+		})
+	}
+}
+
+func TestGetsLoginAttempt(t *testing.T) {
+	at := time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		wantResult []models.RepoLoginAttemptModel
+		wantErr    bool
+	}{
+		// TODO: Add test cases.
+		{
+			name:       "error1",
+			wantResult: nil,
+			wantErr:    true,
+		},
+		{
+			name: "success1",
+			wantResult: []models.RepoLoginAttemptModel{
+				{Key: "user:admin", At: at, ExpiresAt: at.Add(time.Hour)},
+			},
+			wantErr: false,
+		},
+	}
+
+	collection := "login_attempts"
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt.Run(tt.name, func(mt *mtest.T) {
+				// ------------------- Arrange (เตรียมของ) --------------------
+
+				// mock Find
+				if tt.wantErr {
+					mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+						Index:   1,
+						Code:    123,
+						Message: "some error",
+					}))
+				} else {
+					docs := []bson.D{}
+					for _, attempt := range tt.wantResult {
+						docs = append(docs, bson.D{{Key: "key", Value: attempt.Key}, {Key: "at", Value: attempt.At}, {Key: "expires_at", Value: attempt.ExpiresAt}})
+					}
+
+					first := mtest.CreateCursorResponse(1, fmt.Sprintf("%v.%v", "DBtest", collection), mtest.FirstBatch, docs...)
+					killCursors := mtest.CreateCursorResponse(0, fmt.Sprintf("%v.%v", "DBtest", collection), mtest.NextBatch)
+					mt.AddMockResponses(first, killCursors)
+				}
+
+				loginAttemptRepo := repositories.NewLoginAttemptRepository(mt.DB, collection, repositories.DefaultTimeouts)
+
+				// -------------------- Act (กระทำ)--------------------
+				gotResult, err := loginAttemptRepo.Gets(ctx, "user:admin", at.Add(-time.Hour))
+
+				// -------------------- Assert (ยืนยัน) --------------------
+				assert.Equal(mt, tt.wantErr, err != nil)
+				assert.Equal(mt, len(tt.wantResult), len(gotResult))
+				for i := range tt.wantResult {
+					assert.True(mt, tt.wantResult[i].At.Equal(gotResult[i].At))
+					assert.Equal(mt, tt.wantResult[i].Key, gotResult[i].Key)
+				}
+			})
+		})
+	}
+}
+
+func TestDeleteLoginAttempt(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantResult bson.D
+		wantErr    bool
+	}{
+		// TODO: Add test cases.
+		{
+			name: "error1",
+			wantResult: mtest.CreateWriteErrorsResponse(mtest.WriteError{
+				Index:   1,
+				Code:    123,
+				Message: "some error",
+			}),
+			wantErr: true,
+		},
+		{
+			name:       "success nothing to delete",
+			wantResult: mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}),
+			wantErr:    false,
+		},
+		{
+			name:       "success1",
+			wantResult: mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 3}),
+			wantErr:    false,
+		},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt.Run(tt.name, func(mt *mtest.T) {
+				// ------------------- Arrange (เตรียมของ) --------------------
+
+				// mock DeleteMany
+				mt.AddMockResponses(tt.wantResult)
+
+				loginAttemptRepo := repositories.NewLoginAttemptRepository(mt.DB, "login_attempts", repositories.DefaultTimeouts)
+
+				// -------------------- Act (กระทำ)--------------------
+				err := loginAttemptRepo.Delete(ctx, "user:admin")
+
+				// -------------------- Assert (ยืนยัน) --------------------
+				assert.Equal(mt, tt.wantErr, err != nil)
+			})
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	"time"
+)
+
+// memorySweepInterval is how often a memory store scans every entry for expired ones
+const memorySweepInterval = time.Minute
+
+// memorySweep keeps the full scan for expired entries off the hot path, it runs at most
+// once per memorySweepInterval instead of on every write while writes prune the key
+// they touch. Caller holds the store's mutex.
+type memorySweep struct {
+	sweptAt *time.Time
+}
+
+func newMemorySweep() memorySweep {
+	return memorySweep{&time.Time{}}
+}
+
+// due reports whether a full scan runs now, and if so counts it as done
+func (s memorySweep) due(now time.Time) bool {
+	if now.Sub(*s.sweptAt) < memorySweepInterval {
+		return false
+	}
+	*s.sweptAt = now
+	return true
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationNow in a migration statement is bound to the time the migration runs, encoded
+// like the adapter writes timestamps. CURRENT_TIMESTAMP would follow the session time
+// zone on PostgreSQL and use another text format on SQLite.
+const migrationNow = "{{now}}"
+
+// MigrateSQL applies the embedded migrations/NNNN_name.sql files that are not yet
+// recorded in schema_migrations, each in its own transaction. The SQL is written to
+// run unchanged on PostgreSQL and SQLite.
+func MigrateSQL(ctx context.Context, db *sql.DB, dialect SQLDialect) (err error) {
+	if _, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, name TEXT NOT NULL, applied_at TIMESTAMP NOT NULL)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		name := path.Base(file)
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return fmt.Errorf("migration %v: version prefix: %w", name, err)
+		}
+
+		if err := applyMigration(ctx, db, dialect, version, name, file); err != nil {
+			return fmt.Errorf("migration %v: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, d SQLDialect, version int, name, file string) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var applied int
+	if err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations WHERE version = `+d.placeholder(1), version).Scan(&applied); err != nil {
+		return err
+	}
+	if applied > 0 {
+		return nil
+	}
+
+	statements, err := migrations.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	now := d.timestamp(time.Now())
+	for _, statement := range strings.Split(string(statements), ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		args := []interface{}{}
+		for strings.Contains(statement, migrationNow) {
+			args = append(args, now)
+			statement = strings.Replace(statement, migrationNow, d.placeholder(len(args)), 1)
+		}
+		if _, err = tx.ExecContext(ctx, statement, args...); err != nil {
+			return err
+		}
+	}
+
+	// a concurrent runner that applied the same version fails here on the primary key and rolls back
+	if _, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (`+d.placeholder(1)+`, `+d.placeholder(2)+`, CURRENT_TIMESTAMP)`, version, name); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
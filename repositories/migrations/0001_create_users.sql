@@ -0,0 +1,8 @@
+CREATE TABLE users (
+    user_id  TEXT PRIMARY KEY,
+    username TEXT NOT NULL,
+    password TEXT NOT NULL,
+    role     TEXT NOT NULL DEFAULT 'user'
+);
+
+CREATE UNIQUE INDEX users_username_key ON users (username);
@@ -0,0 +1,4 @@
+-- usernames are unique ignoring case, rows differing only by case must be merged before this runs
+DROP INDEX users_username_key;
+
+CREATE UNIQUE INDEX users_username_lower_key ON users (LOWER(username));
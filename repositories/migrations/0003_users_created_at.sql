@@ -0,0 +1,4 @@
+-- rows from before creation times were recorded get the zero time, 0006 backfills them
+ALTER TABLE users ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT '0001-01-01 00:00:00.000000';
+
+CREATE INDEX users_created_at_key ON users (created_at, user_id);
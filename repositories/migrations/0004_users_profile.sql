@@ -0,0 +1,10 @@
+-- rows from before profiles were recorded count as last updated when they were created
+-- and as never logged in, attributes hold a json object
+ALTER TABLE users ADD COLUMN display_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN attributes TEXT NOT NULL DEFAULT '{}';
+ALTER TABLE users ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '0001-01-01 00:00:00.000000';
+ALTER TABLE users ADD COLUMN last_login_at TIMESTAMP;
+
+UPDATE users SET updated_at = created_at;
@@ -0,0 +1,4 @@
+-- password_history holds a json array of the hashes of earlier passwords, newest first,
+-- credential_version counts password changes and is carried by the tokens
+ALTER TABLE users ADD COLUMN password_history TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE users ADD COLUMN credential_version INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+-- rows stored before 0003 and 0004 were given the zero time, they count as created and
+-- last updated when this runs, the year 2 bound matches the zero time however it was written
+UPDATE users SET created_at = {{now}} WHERE created_at < '0002-01-01';
+UPDATE users SET updated_at = created_at WHERE updated_at < '0002-01-01';
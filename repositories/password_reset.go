@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+)
+
+// PORT password reset repository
+type PasswordResetRepository interface {
+	Create(ctx context.Context, payload models.RepoPasswordResetModel) (err error)
+
+	// Consume must be atomic, it removes the token and returns it so two requests cannot
+	// both redeem it, an unknown token returns ErrResetTokenInvalid
+	Consume(ctx context.Context, tokenHash string) (result models.RepoPasswordResetModel, err error)
+
+	// RevokeUser removes every outstanding token of userId
+	RevokeUser(ctx context.Context, userId string) (err error)
+}
@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+	"sync"
+	"time"
+)
+
+type passwordResetMemory struct {
+	mu     *sync.Mutex
+	tokens map[string]models.RepoPasswordResetModel
+	sweep  memorySweep
+}
+
+func NewPasswordResetMemoryRepository() PasswordResetRepository {
+	return passwordResetMemory{&sync.Mutex{}, map[string]models.RepoPasswordResetModel{}, newMemorySweep()}
+}
+
+func (r passwordResetMemory) Create(ctx context.Context, payload models.RepoPasswordResetModel) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := time.Now(); r.sweep.due(now) {
+		r.evictExpired(now)
+	}
+
+	r.tokens[payload.TokenHash] = payload
+
+	return nil
+}
+
+func (r passwordResetMemory) Consume(ctx context.Context, tokenHash string) (result models.RepoPasswordResetModel, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result, ok := r.tokens[tokenHash]
+	if !ok {
+		return result, models.ErrResetTokenInvalid
+	}
+	delete(r.tokens, tokenHash)
+
+	return result, nil
+}
+
+func (r passwordResetMemory) RevokeUser(ctx context.Context, userId string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for tokenHash, token := range r.tokens {
+		if token.UserId == userId {
+			delete(r.tokens, tokenHash)
+		}
+	}
+
+	return nil
+}
+
+// evictExpired drops tokens nobody redeems, caller holds mu
+func (r passwordResetMemory) evictExpired(now time.Time) {
+	for tokenHash, token := range r.tokens {
+		if !now.Before(token.ExpiresAt) {
+			delete(r.tokens, tokenHash)
+		}
+	}
+}
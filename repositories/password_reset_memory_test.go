@@ -0,0 +1,34 @@
+package repositories_test
+
+import (
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordResetMemory(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	passwordResetRepo := repositories.NewPasswordResetMemoryRepository()
+	now := time.Now()
+	passwordResetRepo.Create(ctx, models.RepoPasswordResetModel{TokenHash: "hash-1", UserId: "user-1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	passwordResetRepo.Create(ctx, models.RepoPasswordResetModel{TokenHash: "hash-2", UserId: "user-1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	passwordResetRepo.Create(ctx, models.RepoPasswordResetModel{TokenHash: "hash-3", UserId: "user-2", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+
+	// -------------------- Act (กระทำ)--------------------
+	token, err := passwordResetRepo.Consume(ctx, "hash-1")
+	_, errAgain := passwordResetRepo.Consume(ctx, "hash-1")
+	errRevoke := passwordResetRepo.RevokeUser(ctx, "user-1")
+	_, errRevoked := passwordResetRepo.Consume(ctx, "hash-2")
+	_, errOther := passwordResetRepo.Consume(ctx, "hash-3")
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", token.UserId)
+	assert.ErrorIs(t, errAgain, models.ErrResetTokenInvalid)
+	assert.NoError(t, errRevoke)
+	assert.ErrorIs(t, errRevoked, models.ErrResetTokenInvalid)
+	assert.NoError(t, errOther)
+}
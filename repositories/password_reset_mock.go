@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type passwordResetRepoMock struct {
+	mock.Mock
+}
+
+func NewPasswordResetRepoMock() passwordResetRepoMock {
+	return passwordResetRepoMock{}
+}
+
+func (m *passwordResetRepoMock) Create(ctx context.Context, payload models.RepoPasswordResetModel) (err error) {
+	args := m.Called(ctx, payload)
+	return args.Error(0)
+}
+
+func (m *passwordResetRepoMock) Consume(ctx context.Context, tokenHash string) (result models.RepoPasswordResetModel, err error) {
+	args := m.Called(ctx, tokenHash)
+	res, _ := args.Get(0).(models.RepoPasswordResetModel)
+	return res, args.Error(1)
+}
+
+func (m *passwordResetRepoMock) RevokeUser(ctx context.Context, userId string) (err error) {
+	args := m.Called(ctx, userId)
+	return args.Error(0)
+}
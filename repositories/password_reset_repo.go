@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"hexagonal-gotest/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type passwordResetRepo struct {
+	db         *mongo.Database
+	collection string
+	timeouts   Timeouts
+}
+
+func NewPasswordResetRepository(db *mongo.Database, collection string, timeouts Timeouts) PasswordResetRepository {
+	return passwordResetRepo{db, collection, timeouts}
+}
+
+// EnsurePasswordResetIndexes lets mongo drop tokens nobody redeemed once they expire
+func EnsurePasswordResetIndexes(ctx context.Context, db *mongo.Database, collection string) (err error) {
+	_, err = db.Collection(collection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+
+	return err
+}
+
+func (r passwordResetRepo) Create(ctx context.Context, payload models.RepoPasswordResetModel) (err error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err = r.db.Collection(r.collection).InsertOne(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r passwordResetRepo) Consume(ctx context.Context, tokenHash string) (result models.RepoPasswordResetModel, err error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	// deleting is the atomic step, only one of two concurrent requests gets the document
+	err = r.db.Collection(r.collection).FindOneAndDelete(ctx, bson.D{{Key: "token_hash", Value: tokenHash}}).Decode(&result)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return result, models.ErrResetTokenInvalid
+		}
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r passwordResetRepo) RevokeUser(ctx context.Context, userId string) (err error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err = r.db.Collection(r.collection).DeleteMany(ctx, bson.D{{Key: "user_id", Value: userId}})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package repositories_test
+
+import (
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestCreatePasswordReset(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantResult bson.D
+		wantErr    bool
+	}{
+		// TODO: Add test cases.
+		{
+			name: "error1",
+			wantResult: mtest.CreateWriteErrorsResponse(mtest.WriteError{
+				Index:   1,
+				Code:    123,
+				Message: "some error",
+			}),
+			wantErr: true,
+		},
+		{
+			name:       "success1",
+			wantResult: mtest.CreateSuccessResponse(),
+			wantErr:    false,
+		},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt.Run(tt.name, func(mt *mtest.T) {
+				// ------------------- Arrange (เตรียมของ) --------------------
+
+				// mock InsertOne
+				mt.AddMockResponses(tt.wantResult)
+
+				passwordResetRepo := repositories.NewPasswordResetRepository(mt.DB, "password_resets", repositories.DefaultTimeouts)
+
+				// -------------------- Act (กระทำ)--------------------
+				now := time.Now()
+				err := passwordResetRepo.Create(ctx, models.RepoPasswordResetModel{TokenHash: "hash-1", UserId: "user-1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+
+				// -------------------- Assert (ยืนยัน) --------------------
+				assert.Equal(mt, tt.wantErr, err != nil)
+			})
+		})
+	}
+}
+
+func TestConsumePasswordReset(t *testing.T) {
+	expiresAt := time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		wantResult bson.D
+		want       models.RepoPasswordResetModel
+		wantErr    error
+	}{
+		// TODO: Add test cases.
+		{
+			name: "error1",
+			wantResult: mtest.CreateCommandErrorResponse(mtest.CommandError{
+				Code:    123,
+				Message: "some error",
+			}),
+		},
+		{
+			name:       "error2",
+			wantResult: mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}),
+			wantErr:    models.ErrResetTokenInvalid,
+		},
+		{
+			name: "success1",
+			wantResult: mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+				{Key: "token_hash", Value: "hash-1"},
+				{Key: "user_id", Value: "user-1"},
+				{Key: "expires_at", Value: expiresAt},
+			}}),
+			want: models.RepoPasswordResetModel{TokenHash: "hash-1", UserId: "user-1", ExpiresAt: expiresAt},
+		},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt.Run(tt.name, func(mt *mtest.T) {
+				// ------------------- Arrange (เตรียมของ) --------------------
+
+				// mock FindOneAndDelete
+				mt.AddMockResponses(tt.wantResult)
+
+				passwordResetRepo := repositories.NewPasswordResetRepository(mt.DB, "password_resets", repositories.DefaultTimeouts)
+
+				// -------------------- Act (กระทำ)--------------------
+				got, err := passwordResetRepo.Consume(ctx, "hash-1")
+
+				// -------------------- Assert (ยืนยัน) --------------------
+				switch tt.name {
+				case "error1":
+					assert.Error(mt, err)
+					assert.NotErrorIs(mt, err, models.ErrResetTokenInvalid)
+				case "error2":
+					assert.ErrorIs(mt, err, tt.wantErr)
+				default:
+					assert.NoError(mt, err)
+					assert.Equal(mt, tt.want.UserId, got.UserId)
+					assert.True(mt, tt.want.ExpiresAt.Equal(got.ExpiresAt))
+				}
+			})
+		})
+	}
+}
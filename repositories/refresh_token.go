@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+)
+
+// PORT refresh token repository
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, payload models.RepoRefreshTokenModel) (err error)
+
+	Get(ctx context.Context, tokenHash string) (result models.RepoRefreshTokenModel, err error)
+
+	// MarkUsed must be atomic, a token already used returns ErrRefreshTokenIsUsed
+	MarkUsed(ctx context.Context, tokenHash string) (err error)
+
+	RevokeFamily(ctx context.Context, familyId string) (err error)
+
+	RevokeUser(ctx context.Context, userId string) (err error)
+}
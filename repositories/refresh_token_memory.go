@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+	"sync"
+	"time"
+)
+
+type refreshTokenMemory struct {
+	mu     *sync.Mutex
+	tokens map[string]models.RepoRefreshTokenModel
+	sweep  memorySweep
+}
+
+func NewRefreshTokenMemoryRepository() RefreshTokenRepository {
+	return refreshTokenMemory{&sync.Mutex{}, map[string]models.RepoRefreshTokenModel{}, newMemorySweep()}
+}
+
+func (r refreshTokenMemory) Create(ctx context.Context, payload models.RepoRefreshTokenModel) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := time.Now(); r.sweep.due(now) {
+		r.evictExpired(now)
+	}
+
+	r.tokens[payload.TokenHash] = payload
+
+	return nil
+}
+
+func (r refreshTokenMemory) Get(ctx context.Context, tokenHash string) (result models.RepoRefreshTokenModel, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result, ok := r.tokens[tokenHash]
+	if !ok {
+		return result, models.ErrRefreshTokenIsNotExist
+	}
+	// an expired token goes when it is touched, like the ttl index drops it in mongo
+	if !time.Now().Before(result.ExpiresAt) {
+		delete(r.tokens, tokenHash)
+		return models.RepoRefreshTokenModel{}, models.ErrRefreshTokenIsNotExist
+	}
+
+	return result, nil
+}
+
+func (r refreshTokenMemory) MarkUsed(ctx context.Context, tokenHash string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[tokenHash]
+	if !ok || token.UsedAt != nil {
+		return models.ErrRefreshTokenIsUsed
+	}
+
+	now := time.Now()
+	token.UsedAt = &now
+	r.tokens[tokenHash] = token
+
+	return nil
+}
+
+func (r refreshTokenMemory) RevokeFamily(ctx context.Context, familyId string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for tokenHash, token := range r.tokens {
+		if token.FamilyId == familyId {
+			token.Revoked = true
+			r.tokens[tokenHash] = token
+		}
+	}
+
+	return nil
+}
+
+func (r refreshTokenMemory) RevokeUser(ctx context.Context, userId string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for tokenHash, token := range r.tokens {
+		if token.UserId == userId {
+			token.Revoked = true
+			r.tokens[tokenHash] = token
+		}
+	}
+
+	return nil
+}
+
+// evictExpired drops rotated and revoked tokens, a used token stays until it expires so
+// presenting it again is still caught as reuse, caller holds mu
+func (r refreshTokenMemory) evictExpired(now time.Time) {
+	for tokenHash, token := range r.tokens {
+		if !now.Before(token.ExpiresAt) {
+			delete(r.tokens, tokenHash)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package repositories_test
+
+import (
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshTokenMemory(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	refreshTokenRepo := repositories.NewRefreshTokenMemoryRepository()
+	expiresAt := time.Now().Add(time.Hour)
+	refreshTokenRepo.Create(ctx, models.RepoRefreshTokenModel{TokenHash: "hash-1", FamilyId: "family-1", ExpiresAt: expiresAt})
+	refreshTokenRepo.Create(ctx, models.RepoRefreshTokenModel{TokenHash: "hash-2", FamilyId: "family-1", ExpiresAt: expiresAt})
+	refreshTokenRepo.Create(ctx, models.RepoRefreshTokenModel{TokenHash: "hash-3", FamilyId: "family-2", ExpiresAt: expiresAt})
+
+	// -------------------- Act (กระทำ)--------------------
+	var wins int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if refreshTokenRepo.MarkUsed(ctx, "hash-1") == nil {
+				atomic.AddInt32(&wins, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	errRevoke := refreshTokenRepo.RevokeFamily(ctx, "family-1")
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.Equal(t, int32(1), wins)
+	assert.Equal(t, models.ErrRefreshTokenIsUsed, refreshTokenRepo.MarkUsed(ctx, "hash-1"))
+	assert.NoError(t, errRevoke)
+
+	token1, _ := refreshTokenRepo.Get(ctx, "hash-1")
+	assert.NotNil(t, token1.UsedAt)
+	assert.True(t, token1.Revoked)
+
+	token2, _ := refreshTokenRepo.Get(ctx, "hash-2")
+	assert.True(t, token2.Revoked)
+
+	token3, _ := refreshTokenRepo.Get(ctx, "hash-3")
+	assert.False(t, token3.Revoked)
+
+	_, err := refreshTokenRepo.Get(ctx, "hash-4")
+	assert.Equal(t, models.ErrRefreshTokenIsNotExist, err)
+}
+
+func TestRefreshTokenMemoryEvictsExpired(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	refreshTokenRepo := repositories.NewRefreshTokenMemoryRepository()
+	now := time.Now()
+	refreshTokenRepo.Create(ctx, models.RepoRefreshTokenModel{TokenHash: "hash-expired", FamilyId: "family-1", ExpiresAt: now.Add(-time.Second)})
+	refreshTokenRepo.Create(ctx, models.RepoRefreshTokenModel{TokenHash: "hash-used", FamilyId: "family-2", ExpiresAt: now.Add(time.Hour)})
+	refreshTokenRepo.MarkUsed(ctx, "hash-used")
+
+	// -------------------- Act (กระทำ)--------------------
+	_, errExpired := refreshTokenRepo.Get(ctx, "hash-expired")
+	used, errUsed := refreshTokenRepo.Get(ctx, "hash-used")
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.Equal(t, models.ErrRefreshTokenIsNotExist, errExpired)
+	assert.NoError(t, errUsed, "a used token is kept until it expires to catch reuse")
+	assert.NotNil(t, used.UsedAt)
+}
@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type refreshTokenRepoMock struct {
+	mock.Mock
+}
+
+func NewRefreshTokenRepoMock() refreshTokenRepoMock {
+	return refreshTokenRepoMock{}
+}
+
+func (m *refreshTokenRepoMock) Create(ctx context.Context, payload models.RepoRefreshTokenModel) (err error) {
+	args := m.Called(ctx, payload)
+	return args.Error(0)
+}
+
+func (m *refreshTokenRepoMock) Get(ctx context.Context, tokenHash string) (result models.RepoRefreshTokenModel, err error) {
+	args := m.Called(ctx, tokenHash)
+	res, _ := args.Get(0).(models.RepoRefreshTokenModel)
+	return res, args.Error(1)
+}
+
+func (m *refreshTokenRepoMock) MarkUsed(ctx context.Context, tokenHash string) (err error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Error(0)
+}
+
+func (m *refreshTokenRepoMock) RevokeFamily(ctx context.Context, familyId string) (err error) {
+	args := m.Called(ctx, familyId)
+	return args.Error(0)
+}
+
+func (m *refreshTokenRepoMock) RevokeUser(ctx context.Context, userId string) (err error) {
+	args := m.Called(ctx, userId)
+	return args.Error(0)
+}
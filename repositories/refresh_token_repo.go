@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"hexagonal-gotest/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type refreshTokenRepo struct {
+	db         *mongo.Database
+	collection string
+	timeouts   Timeouts
+}
+
+func NewRefreshTokenRepository(db *mongo.Database, collection string, timeouts Timeouts) RefreshTokenRepository {
+	return refreshTokenRepo{db, collection, timeouts}
+}
+
+// EnsureRefreshTokenIndexes backs the lookups by hash, family and user, keeps two tokens
+// from sharing a hash and lets mongo drop tokens once they expire
+func EnsureRefreshTokenIndexes(ctx context.Context, db *mongo.Database, collection string) (err error) {
+	_, err = db.Collection(collection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "family_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+
+	return err
+}
+
+func (r refreshTokenRepo) Create(ctx context.Context, payload models.RepoRefreshTokenModel) (err error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err = r.db.Collection(r.collection).InsertOne(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r refreshTokenRepo) Get(ctx context.Context, tokenHash string) (result models.RepoRefreshTokenModel, err error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	err = r.db.Collection(r.collection).FindOne(ctx, bson.D{{Key: "token_hash", Value: tokenHash}}).Decode(&result)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return result, models.ErrRefreshTokenIsNotExist
+		}
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r refreshTokenRepo) MarkUsed(ctx context.Context, tokenHash string) (err error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	// filter on used_at so two concurrent rotations cannot both succeed
+	res, err := r.db.Collection(r.collection).UpdateOne(ctx,
+		bson.D{{Key: "token_hash", Value: tokenHash}, {Key: "used_at", Value: nil}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "used_at", Value: time.Now()}}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return models.ErrRefreshTokenIsUsed
+	}
+
+	return nil
+}
+
+func (r refreshTokenRepo) RevokeFamily(ctx context.Context, familyId string) (err error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err = r.db.Collection(r.collection).UpdateMany(ctx,
+		bson.D{{Key: "family_id", Value: familyId}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "revoked", Value: true}}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r refreshTokenRepo) RevokeUser(ctx context.Context, userId string) (err error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err = r.db.Collection(r.collection).UpdateMany(ctx,
+		bson.D{{Key: "user_id", Value: userId}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "revoked", Value: true}}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package repositories_test
+
+import (
+	"fmt"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestCreateRefreshToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantResult bson.D
+		wantErr    bool
+	}{
+		// TODO: Add test cases.
+		{
+			name: "error1",
+			wantResult: mtest.CreateWriteErrorsResponse(mtest.WriteError{
+				Index:   1,
+				Code:    123,
+				Message: "some error",
+			}),
+			wantErr: true,
+		},
+		{
+			name:       "success1",
+			wantResult: mtest.CreateSuccessResponse(),
+			wantErr:    false,
+		},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt.Run(tt.name, func(mt *mtest.T) {
+				// ------------------- Arrange (เตรียมของ) --------------------
+
+				// mock InsertOne
+				mt.AddMockResponses(tt.wantResult)
+
+				refreshTokenRepo := repositories.NewRefreshTokenRepository(mt.DB, "refresh_tokens", repositories.DefaultTimeouts)
+
+				// -------------------- Act (กระทำ)--------------------
+				err := refreshTokenRepo.Create(ctx, models.RepoRefreshTokenModel{TokenHash: "hash", FamilyId: "family-1", UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"})
+
+				// -------------------- Assert (ยืนยัน) --------------------
+				assert.Equal(mt, tt.wantErr, err != nil)
+			})
+		})
+	}
+}
+
+func TestGetRefreshToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantResult models.RepoRefreshTokenModel
+		wantErr    error
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "error1",
+			wantErr: models.ErrRefreshTokenIsNotExist,
+		},
+		{
+			name:       "success1",
+			wantResult: models.RepoRefreshTokenModel{TokenHash: "hash", FamilyId: "family-1", UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+		},
+	}
+
+	collection := "refresh_tokens"
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt.Run(tt.name, func(mt *mtest.T) {
+				// ------------------- Arrange (เตรียมของ) --------------------
+
+				// mock FindOne
+				ns := fmt.Sprintf("%v.%v", "DBtest", collection)
+				if tt.wantErr != nil {
+					mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch))
+				} else {
+					data, _ := bson.Marshal(tt.wantResult)
+					doc := bson.D{}
+					bson.Unmarshal(data, &doc)
+					mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, doc))
+				}
+
+				refreshTokenRepo := repositories.NewRefreshTokenRepository(mt.DB, collection, repositories.DefaultTimeouts)
+
+				// -------------------- Act (กระทำ)--------------------
+				gotResult, err := refreshTokenRepo.Get(ctx, "hash")
+
+				// -------------------- Assert (ยืนยัน) --------------------
+				assert.Equal(mt, tt.wantErr, err)
+				assert.Equal(mt, tt.wantResult.TokenHash, gotResult.TokenHash)
+				assert.Equal(mt, tt.wantResult.FamilyId, gotResult.FamilyId)
+			})
+		})
+	}
+}
+
+func TestMarkUsedRefreshToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantResult bson.D
+		wantErr    bool
+	}{
+		// TODO: Add test cases.
+		{
+			name: "error1",
+			wantResult: mtest.CreateWriteErrorsResponse(mtest.WriteError{
+				Index:   1,
+				Code:    123,
+				Message: "some error",
+			}),
+			wantErr: true,
+		},
+		{
+			name:       "error2",
+			wantResult: mtest.CreateSuccessResponse(bson.E{Key: "ok", Value: "0"}, bson.E{Key: "nModified", Value: 0}, bson.E{Key: "n", Value: 0}),
+			wantErr:    true,
+		},
+		{
+			name:       "success1",
+			wantResult: mtest.CreateSuccessResponse(bson.E{Key: "ok", Value: "1"}, bson.E{Key: "nModified", Value: 1}, bson.E{Key: "n", Value: 1}),
+			wantErr:    false,
+		},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt.Run(tt.name, func(mt *mtest.T) {
+				// ------------------- Arrange (เตรียมของ) --------------------
+
+				// mock UpdateOne
+				mt.AddMockResponses(tt.wantResult)
+
+				refreshTokenRepo := repositories.NewRefreshTokenRepository(mt.DB, "refresh_tokens", repositories.DefaultTimeouts)
+
+				// -------------------- Act (กระทำ)--------------------
+				err := refreshTokenRepo.MarkUsed(ctx, "hash")
+
+				// -------------------- Assert (ยืนยัน) --------------------
+				assert.Equal(mt, tt.wantErr, err != nil)
+				if tt.name == "error2" {
+					assert.Equal(mt, models.ErrRefreshTokenIsUsed, err)
+				}
+			})
+		})
+	}
+}
+
+func TestRevokeFamilyRefreshToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantResult bson.D
+		wantErr    bool
+	}{
+		// TODO: Add test cases.
+		{
+			name: "error1",
+			wantResult: mtest.CreateWriteErrorsResponse(mtest.WriteError{
+				Index:   1,
+				Code:    123,
+				Message: "some error",
+			}),
+			wantErr: true,
+		},
+		{
+			name:       "success1",
+			wantResult: mtest.CreateSuccessResponse(bson.E{Key: "ok", Value: "1"}, bson.E{Key: "nModified", Value: 2}, bson.E{Key: "n", Value: 2}),
+			wantErr:    false,
+		},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt.Run(tt.name, func(mt *mtest.T) {
+				// ------------------- Arrange (เตรียมของ) --------------------
+
+				// mock UpdateMany
+				mt.AddMockResponses(tt.wantResult)
+
+				refreshTokenRepo := repositories.NewRefreshTokenRepository(mt.DB, "refresh_tokens", repositories.DefaultTimeouts)
+
+				// -------------------- Act (กระทำ)--------------------
+				err := refreshTokenRepo.RevokeFamily(ctx, "family-1")
+
+				// -------------------- Assert (ยืนยัน) --------------------
+				assert.Equal(mt, tt.wantErr, err != nil)
+			})
+		})
+	}
+}
+
+func TestEnsureRefreshTokenIndexes(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("success1", func(mt *mtest.T) {
+		// ------------------- Arrange (เตรียมของ) --------------------
+
+		// mock createIndexes
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		// -------------------- Act (กระทำ)--------------------
+		err := repositories.EnsureRefreshTokenIndexes(ctx, mt.DB, "refresh_tokens")
+
+		// -------------------- Assert (ยืนยัน) --------------------
+		assert.NoError(mt, err)
+
+		indexes := map[string]bson.Raw{}
+		values, _ := mt.GetStartedEvent().Command.Lookup("indexes").Array().Values()
+		for _, value := range values {
+			index := value.Document()
+			indexes[index.Lookup("name").StringValue()] = index
+		}
+		assert.Equal(mt, true, indexes["token_hash_1"].Lookup("unique").Boolean())
+		assert.Contains(mt, indexes, "family_id_1")
+		assert.Contains(mt, indexes, "user_id_1")
+		assert.Equal(mt, int32(0), indexes["expires_at_1"].Lookup("expireAfterSeconds").Int32())
+	})
+}
@@ -0,0 +1,450 @@
+// Package repositoriestest holds conformance suites every repositories adapter must pass.
+package repositoriestest
+
+import (
+	"context"
+	"fmt"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// UserRepositoryFactory returns an empty repository, it is called once per subtest
+type UserRepositoryFactory func(t *testing.T) repositories.UserRepository
+
+var (
+	ctx = context.Background()
+
+	// whole seconds in UTC survive every adapter unchanged
+	admin = models.RepoCreateUserModel{
+		UserId:      "225cfc88-c66b-4f2f-b424-a3b74e3b1191",
+		Username:    "admin",
+		Password:    "hash-admin",
+		Role:        models.RoleAdmin,
+		DisplayName: "Admin",
+		Email:       "admin@example.com",
+		Locale:      "en-US",
+		Attributes:  map[string]string{"team": "core", "desk": "12"},
+		CreatedAt:   time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC),
+		UpdatedAt:   time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC),
+	}
+	user = models.RepoCreateUserModel{UserId: "6b3f4c1e-5a2d-4e8f-9c7b-1d2e3f4a5b6c", Username: "user", Password: "hash-user", Role: models.RoleUser, CreatedAt: time.Date(2023, 8, 2, 10, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2023, 8, 2, 10, 0, 0, 0, time.UTC)}
+)
+
+func repoUser(payload models.RepoCreateUserModel) models.RepoUserModel {
+	return models.RepoUserModel{
+		UserId:      payload.UserId,
+		Username:    payload.Username,
+		Password:    payload.Password,
+		Role:        payload.Role,
+		DisplayName: payload.DisplayName,
+		Email:       payload.Email,
+		Locale:      payload.Locale,
+		Attributes:  payload.Attributes,
+		CreatedAt:   payload.CreatedAt,
+		UpdatedAt:   payload.UpdatedAt,
+	}
+}
+
+func ptr(value string) *string {
+	return &value
+}
+
+func usernames(users []models.RepoUserModel) []string {
+	names := []string{}
+	for _, user := range users {
+		names = append(names, user.Username)
+	}
+	return names
+}
+
+// seed creates admin and user, failing the test when the adapter cannot store them
+func seed(t *testing.T, userRepo repositories.UserRepository) {
+	for _, payload := range []models.RepoCreateUserModel{admin, user} {
+		if err := userRepo.Create(ctx, payload); err != nil {
+			t.Fatalf("create %v: %v", payload.Username, err)
+		}
+	}
+}
+
+// RunUserRepositoryContract asserts the UserRepository semantics the services rely on
+func RunUserRepositoryContract(t *testing.T, newRepo UserRepositoryFactory) {
+	t.Run("gets filter", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+
+		tests := []struct {
+			name   string
+			filter models.RepoGetUserModel
+			want   []models.RepoUserModel
+		}{
+			{name: "empty filter matches all", filter: models.RepoGetUserModel{}, want: []models.RepoUserModel{repoUser(admin), repoUser(user)}},
+			{name: "user_id", filter: models.RepoGetUserModel{UserId: user.UserId}, want: []models.RepoUserModel{repoUser(user)}},
+			{name: "username", filter: models.RepoGetUserModel{Username: admin.Username}, want: []models.RepoUserModel{repoUser(admin)}},
+			{name: "user_id and username", filter: models.RepoGetUserModel{UserId: admin.UserId, Username: admin.Username}, want: []models.RepoUserModel{repoUser(admin)}},
+			{name: "fields are and-ed", filter: models.RepoGetUserModel{UserId: admin.UserId, Username: user.Username}},
+			{name: "no match", filter: models.RepoGetUserModel{Username: "nobody"}},
+			{name: "username is exact", filter: models.RepoGetUserModel{Username: "adm"}},
+			{name: "username ignores case", filter: models.RepoGetUserModel{Username: "ADMIN"}, want: []models.RepoUserModel{repoUser(admin)}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := userRepo.Gets(ctx, tt.filter)
+
+				assert.NoError(t, err)
+				assert.ElementsMatch(t, tt.want, got)
+			})
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+		// carol has the creation time of admin, user_id breaks the tie. al_x sorts the
+		// same whether or not a collation ignores punctuation
+		for i, username := range []string{"Bob", "amy", "al_x", "carol"} {
+			payload := models.RepoCreateUserModel{UserId: fmt.Sprintf("00000000-0000-4000-8000-%012d", i), Username: username, Password: "hash", Role: models.RoleUser, CreatedAt: admin.CreatedAt.Add(time.Duration(i) * time.Hour)}
+			if i == 3 {
+				payload.CreatedAt = admin.CreatedAt
+			}
+			if err := userRepo.Create(ctx, payload); err != nil {
+				t.Fatalf("create %v: %v", username, err)
+			}
+		}
+		after := func(username string) *models.RepoUserModel {
+			got, _ := userRepo.Gets(ctx, models.RepoGetUserModel{Username: username})
+			return &got[0]
+		}
+
+		tests := []struct {
+			name  string
+			query models.RepoListUserModel
+			want  []string
+		}{
+			{name: "username ignoring case", query: models.RepoListUserModel{SortBy: models.UserSortUsername, Limit: 10}, want: []string{"admin", "al_x", "amy", "Bob", "carol", "user"}},
+			{name: "username descending", query: models.RepoListUserModel{SortBy: models.UserSortUsername, Descending: true, Limit: 3}, want: []string{"user", "carol", "Bob"}},
+			{name: "limit", query: models.RepoListUserModel{SortBy: models.UserSortUsername, Limit: 2}, want: []string{"admin", "al_x"}},
+			{name: "after", query: models.RepoListUserModel{SortBy: models.UserSortUsername, After: after("amy"), Limit: 2}, want: []string{"Bob", "carol"}},
+			{name: "after descending", query: models.RepoListUserModel{SortBy: models.UserSortUsername, Descending: true, After: after("Bob"), Limit: 10}, want: []string{"amy", "al_x", "admin"}},
+			{name: "prefix ignoring case", query: models.RepoListUserModel{UsernamePrefix: "A", SortBy: models.UserSortUsername, Limit: 10}, want: []string{"admin", "al_x", "amy"}},
+			{name: "prefix is literal", query: models.RepoListUserModel{UsernamePrefix: "al_", SortBy: models.UserSortUsername, Limit: 10}, want: []string{"al_x"}},
+			{name: "prefix with after", query: models.RepoListUserModel{UsernamePrefix: "a", SortBy: models.UserSortUsername, After: after("al_x"), Limit: 10}, want: []string{"amy"}},
+			{name: "created_at then user_id", query: models.RepoListUserModel{SortBy: models.UserSortCreatedAt, Limit: 10}, want: []string{"Bob", "carol", "admin", "amy", "al_x", "user"}},
+			{name: "created_at after a tie", query: models.RepoListUserModel{SortBy: models.UserSortCreatedAt, After: after("carol"), Limit: 2}, want: []string{"admin", "amy"}},
+			{name: "created_at descending", query: models.RepoListUserModel{SortBy: models.UserSortCreatedAt, Descending: true, After: after("amy"), Limit: 10}, want: []string{"admin", "carol", "Bob"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := userRepo.List(ctx, tt.query)
+
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, usernames(got))
+			})
+		}
+
+		got, _ := userRepo.List(ctx, models.RepoListUserModel{UsernamePrefix: "user", SortBy: models.UserSortUsername, Limit: 1})
+		assert.Equal(t, []models.RepoUserModel{repoUser(user)}, got)
+	})
+
+	t.Run("create duplicated username", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+
+		err := userRepo.Create(ctx, models.RepoCreateUserModel{UserId: "c6d4f5a2-8f0e-4b8e-9c55-5f0e6b1e1a10", Username: admin.Username, Password: "hash", Role: models.RoleUser})
+
+		assert.ErrorIs(t, err, models.ErrUsernameIsExist)
+		got, _ := userRepo.Gets(ctx, models.RepoGetUserModel{Username: admin.Username})
+		assert.Equal(t, []models.RepoUserModel{repoUser(admin)}, got)
+	})
+
+	t.Run("usernames are unique ignoring case", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+
+		errCreate := userRepo.Create(ctx, models.RepoCreateUserModel{UserId: "c6d4f5a2-8f0e-4b8e-9c55-5f0e6b1e1a10", Username: "Admin", Password: "hash", Role: models.RoleUser})
+		errUpdate := userRepo.Update(ctx, user.UserId, models.RepoUpdateUserModel{Username: "ADMIN"})
+
+		assert.ErrorIs(t, errCreate, models.ErrUsernameIsExist)
+		assert.ErrorIs(t, errUpdate, models.ErrUsernameIsExist)
+		got, _ := userRepo.Gets(ctx, models.RepoGetUserModel{})
+		assert.ElementsMatch(t, []models.RepoUserModel{repoUser(admin), repoUser(user)}, got)
+	})
+
+	t.Run("update password keeps other fields", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+
+		err := userRepo.Update(ctx, user.UserId, models.RepoUpdateUserModel{Password: "hash-new"})
+
+		assert.NoError(t, err)
+		want := repoUser(user)
+		want.Password = "hash-new"
+		got, _ := userRepo.Gets(ctx, models.RepoGetUserModel{UserId: user.UserId})
+		assert.Equal(t, []models.RepoUserModel{want}, got)
+		got, _ = userRepo.Gets(ctx, models.RepoGetUserModel{UserId: admin.UserId})
+		assert.Equal(t, []models.RepoUserModel{repoUser(admin)}, got)
+	})
+
+	t.Run("update profile keeps other fields", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+		updatedAt := time.Date(2023, 8, 3, 10, 0, 0, 0, time.UTC)
+
+		err := userRepo.Update(ctx, user.UserId, models.RepoUpdateUserModel{
+			DisplayName: ptr("User"),
+			Email:       ptr("user@example.com"),
+			Locale:      ptr("th-TH"),
+			Attributes:  map[string]*string{"team": ptr("web"), "floor": ptr("3")},
+			UpdatedAt:   updatedAt,
+		})
+
+		assert.NoError(t, err)
+		want := repoUser(user)
+		want.DisplayName, want.Email, want.Locale = "User", "user@example.com", "th-TH"
+		want.Attributes = map[string]string{"team": "web", "floor": "3"}
+		want.UpdatedAt = updatedAt
+		got, _ := userRepo.Gets(ctx, models.RepoGetUserModel{UserId: user.UserId})
+		assert.Equal(t, []models.RepoUserModel{want}, got)
+		got, _ = userRepo.Gets(ctx, models.RepoGetUserModel{UserId: admin.UserId})
+		assert.Equal(t, []models.RepoUserModel{repoUser(admin)}, got)
+	})
+
+	t.Run("update profile clears fields and merges attributes", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+
+		err := userRepo.Update(ctx, admin.UserId, models.RepoUpdateUserModel{
+			DisplayName: ptr(""),
+			Attributes:  map[string]*string{"team": nil, "floor": ptr("4"), "missing": nil},
+		})
+
+		assert.NoError(t, err)
+		want := repoUser(admin)
+		want.DisplayName = ""
+		want.Attributes = map[string]string{"desk": "12", "floor": "4"}
+		got, _ := userRepo.Gets(ctx, models.RepoGetUserModel{UserId: admin.UserId})
+		assert.Equal(t, []models.RepoUserModel{want}, got)
+	})
+
+	t.Run("update removing every attribute", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+
+		err := userRepo.Update(ctx, admin.UserId, models.RepoUpdateUserModel{Attributes: map[string]*string{"team": nil, "desk": nil}})
+
+		assert.NoError(t, err)
+		got, _ := userRepo.Gets(ctx, models.RepoGetUserModel{UserId: admin.UserId})
+		if assert.Len(t, got, 1) {
+			assert.Empty(t, got[0].Attributes)
+		}
+	})
+
+	t.Run("update last login keeps updated_at", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+		lastLoginAt := time.Date(2023, 8, 3, 10, 0, 0, 0, time.UTC)
+
+		err := userRepo.Update(ctx, user.UserId, models.RepoUpdateUserModel{LastLoginAt: lastLoginAt})
+
+		assert.NoError(t, err)
+		want := repoUser(user)
+		want.LastLoginAt = lastLoginAt
+		got, _ := userRepo.Gets(ctx, models.RepoGetUserModel{UserId: user.UserId})
+		assert.Equal(t, []models.RepoUserModel{want}, got)
+	})
+
+	t.Run("update password with history and credential version", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+
+		err := userRepo.Update(ctx, user.UserId, models.RepoUpdateUserModel{Password: "hashed-second", PasswordHistory: []string{user.Password}, CredentialVersion: 1})
+		assert.NoError(t, err)
+		err = userRepo.Update(ctx, user.UserId, models.RepoUpdateUserModel{Password: "hashed-third", PasswordHistory: []string{"hashed-second", user.Password}, CredentialVersion: 2})
+		assert.NoError(t, err)
+
+		want := repoUser(user)
+		want.Password = "hashed-third"
+		want.PasswordHistory = []string{"hashed-second", user.Password}
+		want.CredentialVersion = 2
+		got, _ := userRepo.Gets(ctx, models.RepoGetUserModel{UserId: user.UserId})
+		assert.Equal(t, []models.RepoUserModel{want}, got)
+	})
+
+	t.Run("update username", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+
+		err := userRepo.Update(ctx, user.UserId, models.RepoUpdateUserModel{Username: "renamed"})
+
+		assert.NoError(t, err)
+		got, _ := userRepo.Gets(ctx, models.RepoGetUserModel{Username: "renamed"})
+		assert.Len(t, got, 1)
+		got, _ = userRepo.Gets(ctx, models.RepoGetUserModel{Username: user.Username})
+		assert.Empty(t, got)
+	})
+
+	t.Run("update credential version from a stale read", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+
+		// both read version 0, only the first one may claim version 1
+		errFirst := userRepo.Update(ctx, user.UserId, models.RepoUpdateUserModel{Password: "hash-first", CredentialVersion: 1})
+		errStale := userRepo.Update(ctx, user.UserId, models.RepoUpdateUserModel{Username: "renamed", CredentialVersion: 1})
+		errMissing := userRepo.Update(ctx, "c6d4f5a2-8f0e-4b8e-9c55-5f0e6b1e1a10", models.RepoUpdateUserModel{Password: "hash-new", CredentialVersion: 1})
+
+		assert.NoError(t, errFirst)
+		assert.ErrorIs(t, errStale, models.ErrCredentialsChanged)
+		assert.ErrorIs(t, errMissing, models.ErrUserIdIsNotExist)
+		want := repoUser(user)
+		want.Password = "hash-first"
+		want.CredentialVersion = 1
+		got, _ := userRepo.Gets(ctx, models.RepoGetUserModel{UserId: user.UserId})
+		assert.Equal(t, []models.RepoUserModel{want}, got, "the stale update changes nothing")
+	})
+
+	t.Run("concurrent credential changes", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+
+		errs := make([]error, 10)
+		var wg sync.WaitGroup
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = userRepo.Update(ctx, user.UserId, models.RepoUpdateUserModel{Password: fmt.Sprintf("hash-%d", i), CredentialVersion: 1})
+			}(i)
+		}
+		wg.Wait()
+
+		updated := 0
+		for _, err := range errs {
+			if err == nil {
+				updated++
+			} else {
+				assert.ErrorIs(t, err, models.ErrCredentialsChanged)
+			}
+		}
+		assert.Equal(t, 1, updated)
+	})
+
+	t.Run("update to taken username", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+
+		err := userRepo.Update(ctx, user.UserId, models.RepoUpdateUserModel{Username: admin.Username})
+
+		assert.ErrorIs(t, err, models.ErrUsernameIsExist)
+		got, _ := userRepo.Gets(ctx, models.RepoGetUserModel{UserId: user.UserId})
+		assert.Equal(t, []models.RepoUserModel{repoUser(user)}, got)
+	})
+
+	t.Run("update missing user", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+
+		err := userRepo.Update(ctx, "c6d4f5a2-8f0e-4b8e-9c55-5f0e6b1e1a10", models.RepoUpdateUserModel{Password: "hash-new"})
+
+		assert.ErrorIs(t, err, models.ErrUserIdIsNotExist)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+
+		err := userRepo.Delete(ctx, user.UserId)
+		errAgain := userRepo.Delete(ctx, user.UserId)
+
+		assert.NoError(t, err)
+		assert.ErrorIs(t, errAgain, models.ErrUserIdIsNotExist)
+		got, _ := userRepo.Gets(ctx, models.RepoGetUserModel{})
+		assert.Equal(t, []models.RepoUserModel{repoUser(admin)}, got)
+		assert.Equal(t, models.ErrUserIdIsNotExist, userRepo.Update(ctx, user.UserId, models.RepoUpdateUserModel{Password: "hash-new"}))
+	})
+
+	t.Run("username is free again after delete", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+
+		assert.NoError(t, userRepo.Delete(ctx, user.UserId))
+		err := userRepo.Create(ctx, models.RepoCreateUserModel{UserId: "c6d4f5a2-8f0e-4b8e-9c55-5f0e6b1e1a10", Username: user.Username, Password: "hash", Role: models.RoleUser})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		userRepo := newRepo(t)
+		seed(t, userRepo)
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, errGets := userRepo.Gets(canceled, models.RepoGetUserModel{})
+		_, errList := userRepo.List(canceled, models.RepoListUserModel{Limit: 1})
+		errCreate := userRepo.Create(canceled, models.RepoCreateUserModel{UserId: "c6d4f5a2-8f0e-4b8e-9c55-5f0e6b1e1a10", Username: "canceled", Password: "hash", Role: models.RoleUser})
+		errUpdate := userRepo.Update(canceled, user.UserId, models.RepoUpdateUserModel{Password: "hash-new"})
+		errDelete := userRepo.Delete(canceled, user.UserId)
+
+		assert.ErrorIs(t, errGets, context.Canceled)
+		assert.ErrorIs(t, errList, context.Canceled)
+		assert.ErrorIs(t, errCreate, context.Canceled)
+		assert.ErrorIs(t, errUpdate, context.Canceled)
+		assert.ErrorIs(t, errDelete, context.Canceled)
+		got, _ := userRepo.Gets(ctx, models.RepoGetUserModel{})
+		assert.ElementsMatch(t, []models.RepoUserModel{repoUser(admin), repoUser(user)}, got)
+	})
+
+	t.Run("concurrent create of one username", func(t *testing.T) {
+		userRepo := newRepo(t)
+
+		errs := make([]error, 10)
+		var wg sync.WaitGroup
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = userRepo.Create(ctx, models.RepoCreateUserModel{UserId: fmt.Sprintf("00000000-0000-4000-8000-%012d", i), Username: "admin", Password: "hash", Role: models.RoleUser})
+			}(i)
+		}
+		wg.Wait()
+
+		created := 0
+		for _, err := range errs {
+			if err == nil {
+				created++
+			} else {
+				assert.ErrorIs(t, err, models.ErrUsernameIsExist)
+			}
+		}
+		assert.Equal(t, 1, created)
+		got, _ := userRepo.Gets(ctx, models.RepoGetUserModel{Username: "admin"})
+		assert.Len(t, got, 1)
+	})
+
+	t.Run("concurrent writes to different users", func(t *testing.T) {
+		userRepo := newRepo(t)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				userId := fmt.Sprintf("00000000-0000-4000-8000-%012d", i)
+				assert.NoError(t, userRepo.Create(ctx, models.RepoCreateUserModel{UserId: userId, Username: fmt.Sprint("user-", i), Password: "hash", Role: models.RoleUser}))
+				assert.NoError(t, userRepo.Update(ctx, userId, models.RepoUpdateUserModel{Password: "hash-new"}))
+				_, err := userRepo.Gets(ctx, models.RepoGetUserModel{})
+				assert.NoError(t, err)
+			}(i)
+		}
+		wg.Wait()
+
+		got, _ := userRepo.Gets(ctx, models.RepoGetUserModel{})
+		assert.Len(t, got, 10)
+		for _, u := range got {
+			assert.Equal(t, "hash-new", u.Password)
+		}
+	})
+}
@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+	"time"
+)
+
+// PORT revoked token repository
+type RevokedTokenRepository interface {
+	// Revoke is keyed by Jti, or by UserId when Jti is empty, entries may be dropped after ExpiresAt
+	Revoke(ctx context.Context, payload models.RepoRevokedTokenModel) (err error)
+
+	IsTokenRevoked(ctx context.Context, jti string) (revoked bool, err error)
+
+	// UserRevokedAt is zero when the user never logged out everywhere
+	UserRevokedAt(ctx context.Context, userId string) (revokedAt time.Time, err error)
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+	"sync"
+	"time"
+)
+
+type revokedTokenMemory struct {
+	mu    *sync.Mutex
+	jtis  map[string]models.RepoRevokedTokenModel
+	users map[string]models.RepoRevokedTokenModel
+	sweep memorySweep
+}
+
+func NewRevokedTokenMemoryRepository() RevokedTokenRepository {
+	return revokedTokenMemory{&sync.Mutex{}, map[string]models.RepoRevokedTokenModel{}, map[string]models.RepoRevokedTokenModel{}, newMemorySweep()}
+}
+
+func (r revokedTokenMemory) Revoke(ctx context.Context, payload models.RepoRevokedTokenModel) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := time.Now(); r.sweep.due(now) {
+		r.evictExpired(now)
+	}
+
+	if payload.Jti != "" {
+		r.jtis[payload.Jti] = payload
+	} else {
+		r.users[payload.UserId] = payload
+	}
+
+	return nil
+}
+
+func (r revokedTokenMemory) IsTokenRevoked(ctx context.Context, jti string) (revoked bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.jtis[jti]
+	if !ok || !time.Now().Before(entry.ExpiresAt) {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (r revokedTokenMemory) UserRevokedAt(ctx context.Context, userId string) (revokedAt time.Time, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.users[userId]
+	if !ok || !time.Now().Before(entry.ExpiresAt) {
+		return revokedAt, nil
+	}
+
+	return entry.RevokedAt, nil
+}
+
+// evictExpired keeps the maps from outgrowing the live tokens, caller holds mu
+func (r revokedTokenMemory) evictExpired(now time.Time) {
+	for jti, entry := range r.jtis {
+		if !now.Before(entry.ExpiresAt) {
+			delete(r.jtis, jti)
+		}
+	}
+	for userId, entry := range r.users {
+		if !now.Before(entry.ExpiresAt) {
+			delete(r.users, userId)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package repositories_test
+
+import (
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevokedTokenMemory(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	revokedTokenRepo := repositories.NewRevokedTokenMemoryRepository()
+	now := time.Now()
+
+	// -------------------- Act (กระทำ)--------------------
+	revokedTokenRepo.Revoke(ctx, models.RepoRevokedTokenModel{Jti: "jti-1", RevokedAt: now, ExpiresAt: now.Add(time.Hour)})
+	revokedTokenRepo.Revoke(ctx, models.RepoRevokedTokenModel{Jti: "jti-2", RevokedAt: now, ExpiresAt: now.Add(-time.Second)})
+	revokedTokenRepo.Revoke(ctx, models.RepoRevokedTokenModel{UserId: "user-1", RevokedAt: now, ExpiresAt: now.Add(time.Hour)})
+	revokedTokenRepo.Revoke(ctx, models.RepoRevokedTokenModel{UserId: "user-2", RevokedAt: now, ExpiresAt: now.Add(-time.Second)})
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	revoked, err := revokedTokenRepo.IsTokenRevoked(ctx, "jti-1")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, _ = revokedTokenRepo.IsTokenRevoked(ctx, "jti-2")
+	assert.False(t, revoked)
+
+	revoked, _ = revokedTokenRepo.IsTokenRevoked(ctx, "jti-3")
+	assert.False(t, revoked)
+
+	revokedAt, err := revokedTokenRepo.UserRevokedAt(ctx, "user-1")
+	assert.NoError(t, err)
+	assert.True(t, revokedAt.Equal(now))
+
+	revokedAt, _ = revokedTokenRepo.UserRevokedAt(ctx, "user-2")
+	assert.True(t, revokedAt.IsZero())
+
+	revokedAt, _ = revokedTokenRepo.UserRevokedAt(ctx, "jti-1")
+	assert.True(t, revokedAt.IsZero())
+}
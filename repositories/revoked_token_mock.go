@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type revokedTokenRepoMock struct {
+	mock.Mock
+}
+
+func NewRevokedTokenRepoMock() revokedTokenRepoMock {
+	return revokedTokenRepoMock{}
+}
+
+func (m *revokedTokenRepoMock) Revoke(ctx context.Context, payload models.RepoRevokedTokenModel) (err error) {
+	args := m.Called(ctx, payload)
+	return args.Error(0)
+}
+
+func (m *revokedTokenRepoMock) IsTokenRevoked(ctx context.Context, jti string) (revoked bool, err error) {
+	args := m.Called(ctx, jti)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *revokedTokenRepoMock) UserRevokedAt(ctx context.Context, userId string) (revokedAt time.Time, err error) {
+	args := m.Called(ctx, userId)
+	res, _ := args.Get(0).(time.Time)
+	return res, args.Error(1)
+}
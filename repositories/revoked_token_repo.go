@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"hexagonal-gotest/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type revokedTokenRepo struct {
+	db         *mongo.Database
+	collection string
+	timeouts   Timeouts
+}
+
+func NewRevokedTokenRepository(db *mongo.Database, collection string, timeouts Timeouts) RevokedTokenRepository {
+	return revokedTokenRepo{db, collection, timeouts}
+}
+
+// EnsureRevokedTokenIndexes lets mongo drop entries once the tokens they revoke have expired
+func EnsureRevokedTokenIndexes(ctx context.Context, db *mongo.Database, collection string) (err error) {
+	_, err = db.Collection(collection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "jti", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+	})
+
+	return err
+}
+
+func (r revokedTokenRepo) Revoke(ctx context.Context, payload models.RepoRevokedTokenModel) (err error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	filter := bson.D{{Key: "jti", Value: payload.Jti}}
+	if payload.Jti == "" {
+		filter = bson.D{{Key: "user_id", Value: payload.UserId}, {Key: "jti", Value: bson.D{{Key: "$exists", Value: false}}}}
+	}
+
+	_, err = r.db.Collection(r.collection).ReplaceOne(ctx, filter, payload, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r revokedTokenRepo) IsTokenRevoked(ctx context.Context, jti string) (revoked bool, err error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	count, err := r.db.Collection(r.collection).CountDocuments(ctx, bson.D{
+		{Key: "jti", Value: jti},
+		{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: time.Now()}}},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r revokedTokenRepo) UserRevokedAt(ctx context.Context, userId string) (revokedAt time.Time, err error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	result := models.RepoRevokedTokenModel{}
+	err = r.db.Collection(r.collection).FindOne(ctx, bson.D{
+		{Key: "user_id", Value: userId},
+		{Key: "jti", Value: bson.D{{Key: "$exists", Value: false}}},
+		{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: time.Now()}}},
+	}).Decode(&result)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return revokedAt, nil
+		}
+		return revokedAt, err
+	}
+
+	return result.RevokedAt, nil
+}
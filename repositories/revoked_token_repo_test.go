@@ -0,0 +1,170 @@
+package repositories_test
+
+import (
+	"fmt"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestRevokeToken(t *testing.T) {
+	type args struct {
+		payload models.RepoRevokedTokenModel
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantResult bson.D
+		wantErr    bool
+	}{
+		// TODO: Add test cases.
+		{
+			name: "error1",
+			args: args{payload: models.RepoRevokedTokenModel{Jti: "jti-1"}},
+			wantResult: mtest.CreateWriteErrorsResponse(mtest.WriteError{
+				Index:   1,
+				Code:    123,
+				Message: "some error",
+			}),
+			wantErr: true,
+		},
+		{
+			name:       "success jti",
+			args:       args{payload: models.RepoRevokedTokenModel{Jti: "jti-1"}},
+			wantResult: mtest.CreateSuccessResponse(bson.E{Key: "ok", Value: "1"}, bson.E{Key: "nModified", Value: 0}, bson.E{Key: "n", Value: 1}),
+			wantErr:    false,
+		},
+		{
+			name:       "success user",
+			args:       args{payload: models.RepoRevokedTokenModel{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"}},
+			wantResult: mtest.CreateSuccessResponse(bson.E{Key: "ok", Value: "1"}, bson.E{Key: "nModified", Value: 1}, bson.E{Key: "n", Value: 1}),
+			wantErr:    false,
+		},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt.Run(tt.name, func(mt *mtest.T) {
+				// ------------------- Arrange (เตรียมของ) --------------------
+
+				// mock ReplaceOne
+				mt.AddMockResponses(tt.wantResult)
+
+				revokedTokenRepo := repositories.NewRevokedTokenRepository(mt.DB, "revoked_tokens", repositories.DefaultTimeouts)
+
+				// -------------------- Act (กระทำ)--------------------
+				err := revokedTokenRepo.Revoke(ctx, tt.args.payload)
+
+				// -------------------- Assert (ยืนยัน) --------------------
+				assert.Equal(mt, tt.wantErr, err != nil)
+			})
+		})
+	}
+}
+
+func TestIsTokenRevoked(t *testing.T) {
+	tests := []struct {
+		name        string
+		count       int32
+		wantRevoked bool
+	}{
+		// TODO: Add test cases.
+		{
+			name:        "not revoked",
+			count:       0,
+			wantRevoked: false,
+		},
+		{
+			name:        "revoked",
+			count:       1,
+			wantRevoked: true,
+		},
+	}
+
+	collection := "revoked_tokens"
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt.Run(tt.name, func(mt *mtest.T) {
+				// ------------------- Arrange (เตรียมของ) --------------------
+
+				// mock CountDocuments
+				ns := fmt.Sprintf("%v.%v", "DBtest", collection)
+				if tt.count > 0 {
+					mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: tt.count}}))
+				} else {
+					mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch))
+				}
+
+				revokedTokenRepo := repositories.NewRevokedTokenRepository(mt.DB, collection, repositories.DefaultTimeouts)
+
+				// -------------------- Act (กระทำ)--------------------
+				revoked, err := revokedTokenRepo.IsTokenRevoked(ctx, "jti-1")
+
+				// -------------------- Assert (ยืนยัน) --------------------
+				assert.NoError(mt, err)
+				assert.Equal(mt, tt.wantRevoked, revoked)
+			})
+		})
+	}
+}
+
+func TestUserRevokedAt(t *testing.T) {
+	revokedAt := time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		wantRevokedAt time.Time
+	}{
+		// TODO: Add test cases.
+		{
+			name:          "never revoked",
+			wantRevokedAt: time.Time{},
+		},
+		{
+			name:          "revoked",
+			wantRevokedAt: revokedAt,
+		},
+	}
+
+	collection := "revoked_tokens"
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt.Run(tt.name, func(mt *mtest.T) {
+				// ------------------- Arrange (เตรียมของ) --------------------
+
+				// mock FindOne
+				ns := fmt.Sprintf("%v.%v", "DBtest", collection)
+				if tt.wantRevokedAt.IsZero() {
+					mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch))
+				} else {
+					mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
+						{Key: "user_id", Value: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+						{Key: "revoked_at", Value: tt.wantRevokedAt},
+					}))
+				}
+
+				revokedTokenRepo := repositories.NewRevokedTokenRepository(mt.DB, collection, repositories.DefaultTimeouts)
+
+				// -------------------- Act (กระทำ)--------------------
+				gotRevokedAt, err := revokedTokenRepo.UserRevokedAt(ctx, "225cfc88-c66b-4f2f-b424-a3b74e3b1191")
+
+				// -------------------- Assert (ยืนยัน) --------------------
+				assert.NoError(mt, err)
+				assert.True(mt, tt.wantRevokedAt.Equal(gotRevokedAt))
+			})
+		})
+	}
+}
@@ -1,14 +1,20 @@
 package repositories
 
-import "hexagonal-gotest/models"
+import (
+	"context"
+	"hexagonal-gotest/models"
+)
 
 // PORT user repository
 type UserRepository interface {
-	Gets(filter models.RepoGetUserModel) (result []models.RepoUserModel, err error)
+	Gets(ctx context.Context, filter models.RepoGetUserModel) (result []models.RepoUserModel, err error)
 
-	Create(payload models.RepoCreateUserModel) (err error)
+	// List returns at most query.Limit users, usernames are matched and ordered ignoring case
+	List(ctx context.Context, query models.RepoListUserModel) (result []models.RepoUserModel, err error)
 
-	Update(userId string, payload models.RepoUpdateUserModel) (err error)
+	Create(ctx context.Context, payload models.RepoCreateUserModel) (err error)
 
-	Delete(userId string) (err error)
+	Update(ctx context.Context, userId string, payload models.RepoUpdateUserModel) (err error)
+
+	Delete(ctx context.Context, userId string) (err error)
 }
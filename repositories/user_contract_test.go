@@ -0,0 +1,151 @@
+package repositories_test
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"hexagonal-gotest/repositories/repositoriestest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ctx = context.Background()
+
+func TestUserMemoryContract(t *testing.T) {
+	repositoriestest.RunUserRepositoryContract(t, func(t *testing.T) repositories.UserRepository {
+		return repositories.NewUserMemoryRepository()
+	})
+}
+
+func TestUserSQLContract(t *testing.T) {
+	for _, dialect := range []repositories.SQLDialect{repositories.SQLDialectSQLite, repositories.SQLDialectPostgres} {
+		dialect := dialect
+		t.Run(string(dialect), func(t *testing.T) {
+			repositoriestest.RunUserRepositoryContract(t, func(t *testing.T) repositories.UserRepository {
+				return repositories.NewUserSQLRepository(newSQLDB(t, dialect), dialect, repositories.DefaultTimeouts)
+			})
+		})
+	}
+}
+
+// TestUserRepoContract needs a real server in TEST_MONGO_URI, mtest mock responses cannot prove semantics
+func TestUserRepoContract(t *testing.T) {
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+	db := client.Database("hexagonal_gotest_contract")
+
+	repositoriestest.RunUserRepositoryContract(t, func(t *testing.T) repositories.UserRepository {
+		collection := "users_" + uuid.NewString()
+		t.Cleanup(func() { db.Collection(collection).Drop(context.Background()) })
+		if err := repositories.EnsureUserIndexes(context.Background(), db, collection); err != nil {
+			t.Fatal(err)
+		}
+		return repositories.NewUserRepository(db, collection, repositories.DefaultTimeouts)
+	})
+}
+
+// TestUserRepoIndexUse explains the finds Gets sends, a lookup the unique indexes cannot
+// serve scans the whole collection on every authenticated request
+func TestUserRepoIndexUse(t *testing.T) {
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI is not set")
+	}
+
+	// ------------------- Arrange (เตรียมของ) --------------------
+	finds := make(chan bson.Raw, 1)
+	monitor := &event.CommandMonitor{Started: func(_ context.Context, e *event.CommandStartedEvent) {
+		if e.CommandName == "find" {
+			finds <- append(bson.Raw(nil), e.Command...)
+		}
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetMonitor(monitor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+	db := client.Database("hexagonal_gotest_contract")
+
+	collection := "users_" + uuid.NewString()
+	t.Cleanup(func() { db.Collection(collection).Drop(context.Background()) })
+	if err := repositories.EnsureUserIndexes(ctx, db, collection); err != nil {
+		t.Fatal(err)
+	}
+	userRepo := repositories.NewUserRepository(db, collection, repositories.DefaultTimeouts)
+	userId := uuid.NewString()
+	if err := userRepo.Create(ctx, models.RepoCreateUserModel{UserId: userId, Username: "admin", Role: models.RoleUser}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		filter    models.RepoGetUserModel
+		wantIndex string
+	}{
+		{name: "by user id", filter: models.RepoGetUserModel{UserId: userId}, wantIndex: "user_id_1"},
+		{name: "by username", filter: models.RepoGetUserModel{Username: "ADMIN"}, wantIndex: "username_1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// -------------------- Act (กระทำ)--------------------
+			users, err := userRepo.Gets(ctx, tt.filter)
+			command := <-finds
+
+			explain := bson.D{{Key: "find", Value: collection}, {Key: "filter", Value: command.Lookup("filter")}}
+			if collation, err := command.LookupErr("collation"); err == nil {
+				explain = append(explain, bson.E{Key: "collation", Value: collation})
+			}
+			plan := bson.Raw{}
+			errExplain := db.RunCommand(ctx, bson.D{{Key: "explain", Value: explain}}).Decode(&plan)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.NoError(t, err)
+			assert.Len(t, users, 1)
+			assert.NoError(t, errExplain)
+			assert.Contains(t, indexNames(plan.Lookup("queryPlanner", "winningPlan").Document()), tt.wantIndex)
+		})
+	}
+}
+
+// indexNames collects the indexes a plan stage and its input stages scan
+func indexNames(stage bson.Raw) (names []string) {
+	elements, _ := stage.Elements()
+	for _, element := range elements {
+		switch value := element.Value(); {
+		case element.Key() == "indexName":
+			names = append(names, value.StringValue())
+		case value.Type == bson.TypeEmbeddedDocument:
+			names = append(names, indexNames(value.Document())...)
+		case value.Type == bson.TypeArray:
+			values, _ := value.Array().Values()
+			for _, v := range values {
+				if v.Type == bson.TypeEmbeddedDocument {
+					names = append(names, indexNames(v.Document())...)
+				}
+			}
+		}
+	}
+
+	return names
+}
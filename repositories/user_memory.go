@@ -0,0 +1,234 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type userMemory struct {
+	mu    *sync.RWMutex
+	users *[]models.RepoUserModel
+}
+
+// NewUserMemoryRepository keeps users in process, usernames are unique and matched
+// ignoring case like the other adapters
+func NewUserMemoryRepository() UserRepository {
+	return userMemory{&sync.RWMutex{}, &[]models.RepoUserModel{}}
+}
+
+func (r userMemory) Gets(ctx context.Context, filter models.RepoGetUserModel) (result []models.RepoUserModel, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range *r.users {
+		if filter.UserId != "" && filter.UserId != user.UserId {
+			continue
+		}
+		if filter.Username != "" && !strings.EqualFold(filter.Username, user.Username) {
+			continue
+		}
+		result = append(result, user)
+	}
+
+	return result, nil
+}
+
+func (r userMemory) List(ctx context.Context, query models.RepoListUserModel) (result []models.RepoUserModel, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	prefix := strings.ToLower(query.UsernamePrefix)
+	for _, user := range *r.users {
+		if !strings.HasPrefix(strings.ToLower(user.Username), prefix) {
+			continue
+		}
+		if query.After != nil && !listsAfter(query, user, *query.After) {
+			continue
+		}
+		result = append(result, user)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return listsAfter(query, result[j], result[i])
+	})
+	if len(result) > query.Limit {
+		result = result[:query.Limit]
+	}
+
+	return result, nil
+}
+
+// listsAfter reports whether user comes after previous in the order of query
+func listsAfter(query models.RepoListUserModel, user, previous models.RepoUserModel) bool {
+	order := 0
+	switch query.SortBy {
+	case models.UserSortCreatedAt:
+		order = user.CreatedAt.Compare(previous.CreatedAt)
+	default:
+		order = strings.Compare(strings.ToLower(user.Username), strings.ToLower(previous.Username))
+	}
+	if order == 0 {
+		order = strings.Compare(user.UserId, previous.UserId)
+	}
+
+	if query.Descending {
+		return order < 0
+	}
+	return order > 0
+}
+
+func (r userMemory) Create(ctx context.Context, payload models.RepoCreateUserModel) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range *r.users {
+		if strings.EqualFold(user.Username, payload.Username) {
+			return models.ErrUsernameIsExist
+		}
+	}
+
+	*r.users = append(*r.users, models.RepoUserModel{
+		UserId:      payload.UserId,
+		Username:    payload.Username,
+		Password:    payload.Password,
+		Role:        payload.Role,
+		DisplayName: payload.DisplayName,
+		Email:       payload.Email,
+		Locale:      payload.Locale,
+		Attributes:  mergeAttributes(nil, payload.Attributes),
+		CreatedAt:   payload.CreatedAt,
+		UpdatedAt:   payload.UpdatedAt,
+	})
+
+	return nil
+}
+
+func (r userMemory) Update(ctx context.Context, userId string, payload models.RepoUpdateUserModel) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	index := r.indexOf(userId)
+	if index < 0 {
+		return models.ErrUserIdIsNotExist
+	}
+	if payload.CredentialVersion != 0 && (*r.users)[index].CredentialVersion != payload.CredentialVersion-1 {
+		return models.ErrCredentialsChanged
+	}
+
+	if payload.Username != "" {
+		for _, user := range *r.users {
+			if strings.EqualFold(user.Username, payload.Username) && user.UserId != userId {
+				return models.ErrUsernameIsExist
+			}
+		}
+		(*r.users)[index].Username = payload.Username
+	}
+	user := &(*r.users)[index]
+	if payload.Password != "" {
+		user.Password = payload.Password
+	}
+	if len(payload.PasswordHistory) > 0 {
+		// copied so users handed out by Gets never change under the caller
+		user.PasswordHistory = append([]string(nil), payload.PasswordHistory...)
+	}
+	if payload.CredentialVersion != 0 {
+		user.CredentialVersion = payload.CredentialVersion
+	}
+	if payload.DisplayName != nil {
+		user.DisplayName = *payload.DisplayName
+	}
+	if payload.Email != nil {
+		user.Email = *payload.Email
+	}
+	if payload.Locale != nil {
+		user.Locale = *payload.Locale
+	}
+	if len(payload.Attributes) > 0 {
+		patch := map[string]string{}
+		for key, value := range payload.Attributes {
+			if value != nil {
+				patch[key] = *value
+			}
+		}
+		attributes := mergeAttributes(user.Attributes, patch)
+		for key, value := range payload.Attributes {
+			if value == nil {
+				delete(attributes, key)
+			}
+		}
+		if len(attributes) == 0 {
+			attributes = nil
+		}
+		user.Attributes = attributes
+	}
+	if !payload.UpdatedAt.IsZero() {
+		user.UpdatedAt = payload.UpdatedAt
+	}
+	if !payload.LastLoginAt.IsZero() {
+		user.LastLoginAt = payload.LastLoginAt
+	}
+
+	return nil
+}
+
+// mergeAttributes returns a new map so users handed out by Gets never change under the caller
+func mergeAttributes(attributes, patch map[string]string) map[string]string {
+	if len(attributes) == 0 && len(patch) == 0 {
+		return nil
+	}
+
+	merged := make(map[string]string, len(attributes)+len(patch))
+	for key, value := range attributes {
+		merged[key] = value
+	}
+	for key, value := range patch {
+		merged[key] = value
+	}
+	return merged
+}
+
+func (r userMemory) Delete(ctx context.Context, userId string) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	index := r.indexOf(userId)
+	if index < 0 {
+		return models.ErrUserIdIsNotExist
+	}
+
+	*r.users = append((*r.users)[:index], (*r.users)[index+1:]...)
+
+	return nil
+}
+
+func (r userMemory) indexOf(userId string) int {
+	for i, user := range *r.users {
+		if user.UserId == userId {
+			return i
+		}
+	}
+	return -1
+}
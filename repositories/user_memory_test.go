@@ -0,0 +1,66 @@
+package repositories_test
+
+import (
+	"fmt"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserMemory(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	userRepo := repositories.NewUserMemoryRepository()
+	userRepo.Create(ctx, models.RepoCreateUserModel{UserId: "user-1", Username: "admin", Password: "hash-1", Role: models.RoleAdmin})
+	userRepo.Create(ctx, models.RepoCreateUserModel{UserId: "user-2", Username: "user", Password: "hash-2", Role: models.RoleUser})
+
+	// -------------------- Act (กระทำ)--------------------
+	errCreate := userRepo.Create(ctx, models.RepoCreateUserModel{UserId: "user-3", Username: "admin"})
+	errUpdate := userRepo.Update(ctx, "user-2", models.RepoUpdateUserModel{Password: "hash-3"})
+	errUpdateUsername := userRepo.Update(ctx, "user-2", models.RepoUpdateUserModel{Username: "admin"})
+	errUpdateMissing := userRepo.Update(ctx, "user-4", models.RepoUpdateUserModel{Password: "hash-4"})
+	errDelete := userRepo.Delete(ctx, "user-1")
+	errDeleteMissing := userRepo.Delete(ctx, "user-1")
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.Equal(t, models.ErrUsernameIsExist, errCreate)
+	assert.NoError(t, errUpdate)
+	assert.Equal(t, models.ErrUsernameIsExist, errUpdateUsername)
+	assert.Equal(t, models.ErrUserIdIsNotExist, errUpdateMissing)
+	assert.NoError(t, errDelete)
+	assert.Equal(t, models.ErrUserIdIsNotExist, errDeleteMissing)
+
+	users, err := userRepo.Gets(ctx, models.RepoGetUserModel{})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.RepoUserModel{{UserId: "user-2", Username: "user", Password: "hash-3", Role: models.RoleUser}}, users)
+
+	users, _ = userRepo.Gets(ctx, models.RepoGetUserModel{Username: "admin"})
+	assert.Empty(t, users)
+}
+
+func TestUserMemoryConcurrentCreate(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	userRepo := repositories.NewUserMemoryRepository()
+
+	// -------------------- Act (กระทำ)--------------------
+	var created int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if userRepo.Create(ctx, models.RepoCreateUserModel{UserId: fmt.Sprint("user-", i), Username: "admin"}) == nil {
+				atomic.AddInt32(&created, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.Equal(t, int32(1), created)
+	users, _ := userRepo.Gets(ctx, models.RepoGetUserModel{Username: "admin"})
+	assert.Len(t, users, 1)
+}
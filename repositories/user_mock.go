@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"hexagonal-gotest/models"
 
 	"github.com/stretchr/testify/mock"
@@ -14,8 +15,8 @@ func NewUserRepoMock() userRepoMock {
 	return userRepoMock{}
 }
 
-func (m *userRepoMock) Gets(filter models.RepoGetUserModel) (result []models.RepoUserModel, err error) {
-	args := m.Called(filter)
+func (m *userRepoMock) Gets(ctx context.Context, filter models.RepoGetUserModel) (result []models.RepoUserModel, err error) {
+	args := m.Called(ctx, filter)
 	res, ok := args.Get(0).([]models.RepoUserModel)
 	if !ok {
 		return nil, args.Error(1)
@@ -23,17 +24,26 @@ func (m *userRepoMock) Gets(filter models.RepoGetUserModel) (result []models.Rep
 	return res, args.Error(1)
 }
 
-func (m *userRepoMock) Create(payload models.RepoCreateUserModel) (err error) {
-	args := m.Called(payload)
+func (m *userRepoMock) List(ctx context.Context, query models.RepoListUserModel) (result []models.RepoUserModel, err error) {
+	args := m.Called(ctx, query)
+	res, ok := args.Get(0).([]models.RepoUserModel)
+	if !ok {
+		return nil, args.Error(1)
+	}
+	return res, args.Error(1)
+}
+
+func (m *userRepoMock) Create(ctx context.Context, payload models.RepoCreateUserModel) (err error) {
+	args := m.Called(ctx, payload)
 	return args.Error(0)
 }
 
-func (m *userRepoMock) Update(userId string, payload models.RepoUpdateUserModel) (err error) {
-	args := m.Called(userId, payload)
+func (m *userRepoMock) Update(ctx context.Context, userId string, payload models.RepoUpdateUserModel) (err error) {
+	args := m.Called(ctx, userId, payload)
 	return args.Error(0)
 }
 
-func (m *userRepoMock) Delete(userId string) (err error) {
-	args := m.Called(userId)
+func (m *userRepoMock) Delete(ctx context.Context, userId string) (err error) {
+	args := m.Called(ctx, userId)
 	return args.Error(0)
 }
@@ -2,28 +2,115 @@ package repositories
 
 import (
 	"context"
-	"errors"
 	"hexagonal-gotest/models"
-	"time"
+	"regexp"
+	"sort"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type userRepo struct {
 	db         *mongo.Database
 	collection string
+	timeouts   Timeouts
 }
 
-func NewUserRepository(db *mongo.Database, collection string) UserRepository {
-	return userRepo{db, collection}
+// usernameCollation compares usernames ignoring case, lookups must use the collation
+// of the unique index for the index to serve them
+var usernameCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// BackfillUsers gives users stored before creation times were recorded the time
+// their document id was generated, and users stored before updates were recorded
+// their creation time as last update
+func BackfillUsers(ctx context.Context, db *mongo.Database, collection string) (err error) {
+	_, err = db.Collection(collection).UpdateMany(ctx,
+		bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "created_at", Value: bson.D{{Key: "$exists", Value: false}}}},
+			bson.D{{Key: "updated_at", Value: bson.D{{Key: "$exists", Value: false}}}},
+		}}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.D{{Key: "created_at", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$created_at", bson.D{{Key: "$toDate", Value: "$_id"}}}}}}}}},
+			{{Key: "$set", Value: bson.D{{Key: "updated_at", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$updated_at", "$created_at"}}}}}}},
+		},
+	)
+
+	return err
 }
 
-func (r userRepo) Gets(filter models.RepoGetUserModel) (result []models.RepoUserModel, err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// EnsureUserIndexes makes the database reject a second user with the same user_id or
+// username, so concurrent registrations cannot both pass the service's existence check
+func EnsureUserIndexes(ctx context.Context, db *mongo.Database, collection string) (err error) {
+	_, err = db.Collection(collection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true).SetCollation(usernameCollation)},
+		{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetCollation(usernameCollation)},
+	})
+
+	return err
+}
+
+func NewUserRepository(db *mongo.Database, collection string, timeouts Timeouts) UserRepository {
+	return userRepo{db, collection, timeouts}
+}
+
+func (r userRepo) Gets(ctx context.Context, filter models.RepoGetUserModel) (result []models.RepoUserModel, err error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	// only username lookups take the collation, the user_id index has the simple one and
+	// a query with any other collation cannot use it
+	findOptions := options.Find()
+	if filter.Username != "" {
+		findOptions.SetCollation(usernameCollation)
+	}
+
+	cursor, err := r.db.Collection(r.collection).Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r userRepo) List(ctx context.Context, query models.RepoListUserModel) (result []models.RepoUserModel, err error) {
+	ctx, cancel := r.timeouts.read(ctx)
 	defer cancel()
 
-	cursor, err := r.db.Collection(r.collection).Find(ctx, filter)
+	field, direction, comparison := "username", 1, "$gt"
+	if query.SortBy == models.UserSortCreatedAt {
+		field = "created_at"
+	}
+	if query.Descending {
+		direction, comparison = -1, "$lt"
+	}
+
+	filter := bson.D{}
+	if query.UsernamePrefix != "" {
+		filter = append(filter, bson.E{Key: "username", Value: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(query.UsernamePrefix), Options: "i"}})
+	}
+	if query.After != nil {
+		var after interface{} = query.After.Username
+		if query.SortBy == models.UserSortCreatedAt {
+			after = query.After.CreatedAt
+		}
+		filter = append(filter, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: field, Value: bson.D{{Key: comparison, Value: after}}}},
+			bson.D{{Key: field, Value: after}, {Key: "user_id", Value: bson.D{{Key: comparison, Value: query.After.UserId}}}},
+		}})
+	}
+
+	// the collation makes username order and comparisons ignore case like the unique index
+	cursor, err := r.db.Collection(r.collection).Find(ctx, filter, options.Find().
+		SetCollation(usernameCollation).
+		SetSort(bson.D{{Key: field, Value: direction}, {Key: "user_id", Value: direction}}).
+		SetLimit(int64(query.Limit)))
 	if err != nil {
 		return nil, err
 	}
@@ -35,36 +122,100 @@ func (r userRepo) Gets(filter models.RepoGetUserModel) (result []models.RepoUser
 	return result, nil
 }
 
-func (r userRepo) Create(payload models.RepoCreateUserModel) (err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r userRepo) Create(ctx context.Context, payload models.RepoCreateUserModel) (err error) {
+	ctx, cancel := r.timeouts.write(ctx)
 	defer cancel()
 
 	_, err = r.db.Collection(r.collection).InsertOne(ctx, payload)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrUsernameIsExist
+		}
 		return err
 	}
 
 	return nil
 }
 
-func (r userRepo) Update(userId string, payload models.RepoUpdateUserModel) (err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r userRepo) Update(ctx context.Context, userId string, payload models.RepoUpdateUserModel) (err error) {
+	ctx, cancel := r.timeouts.write(ctx)
 	defer cancel()
 
-	res, err := r.db.Collection(r.collection).UpdateOne(ctx, bson.D{{Key: "user_id", Value: userId}}, bson.D{{Key: "$set", Value: payload}})
+	update, err := userUpdate(payload)
 	if err != nil {
 		return err
 	}
 
+	filter := bson.D{{Key: "user_id", Value: userId}}
+	if payload.CredentialVersion != 0 {
+		// users stored before credential versions have none, they count as version 0
+		previous := interface{}(payload.CredentialVersion - 1)
+		if payload.CredentialVersion == 1 {
+			previous = bson.D{{Key: "$in", Value: bson.A{0, nil}}}
+		}
+		filter = append(filter, bson.E{Key: "credential_version", Value: previous})
+	}
+
+	res, err := r.db.Collection(r.collection).UpdateOne(ctx, filter, update)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrUsernameIsExist
+		}
+		return err
+	}
+
 	if res.MatchedCount == 0 {
-		return errors.New(models.ErrUserIdIsNotExist)
+		if payload.CredentialVersion == 0 {
+			return models.ErrUserIdIsNotExist
+		}
+		count, err := r.db.Collection(r.collection).CountDocuments(ctx, bson.D{{Key: "user_id", Value: userId}})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return models.ErrCredentialsChanged
+		}
+		return models.ErrUserIdIsNotExist
 	}
 
 	return nil
 }
 
-func (r userRepo) Delete(userId string) (err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// userUpdate sets attributes key by key so the ones missing from payload are kept
+func userUpdate(payload models.RepoUpdateUserModel) (update bson.D, err error) {
+	data, err := bson.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	set := bson.D{}
+	if err := bson.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(payload.Attributes))
+	for key := range payload.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	unset := bson.D{}
+	for _, key := range keys {
+		if value := payload.Attributes[key]; value != nil {
+			set = append(set, bson.E{Key: "attributes." + key, Value: *value})
+		} else {
+			unset = append(unset, bson.E{Key: "attributes." + key, Value: ""})
+		}
+	}
+
+	update = bson.D{{Key: "$set", Value: set}}
+	if len(unset) > 0 {
+		update = append(update, bson.E{Key: "$unset", Value: unset})
+	}
+	return update, nil
+}
+
+func (r userRepo) Delete(ctx context.Context, userId string) (err error) {
+	ctx, cancel := r.timeouts.write(ctx)
 	defer cancel()
 
 	res, err := r.db.Collection(r.collection).DeleteOne(ctx, bson.D{{Key: "user_id", Value: userId}})
@@ -73,7 +224,7 @@ func (r userRepo) Delete(userId string) (err error) {
 	}
 
 	if res.DeletedCount == 0 {
-		return errors.New(models.ErrUserIdIsNotExist)
+		return models.ErrUserIdIsNotExist
 	}
 
 	return nil
@@ -5,9 +5,11 @@ import (
 	"hexagonal-gotest/models"
 	"hexagonal-gotest/repositories"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
 )
 
@@ -19,7 +21,10 @@ func TestGetsUser(t *testing.T) {
 		name       string
 		args       args
 		wantResult []models.RepoUserModel
-		wantErr    bool
+		// wantCollation is only set for username lookups, a user_id lookup with it
+		// could not use the user_id index
+		wantCollation bool
+		wantErr       bool
 	}{
 		{
 			name: "success1",
@@ -31,7 +36,21 @@ func TestGetsUser(t *testing.T) {
 			wantResult: []models.RepoUserModel{
 				{Username: "admin"},
 			},
-			wantErr: false,
+			wantCollation: true,
+			wantErr:       false,
+		},
+		{
+			name: "success by user id",
+			args: args{
+				filter: models.RepoGetUserModel{
+					UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191",
+				},
+			},
+			wantResult: []models.RepoUserModel{
+				{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			},
+			wantCollation: false,
+			wantErr:       false,
 		},
 		{
 			name: "error1",
@@ -82,14 +101,137 @@ func TestGetsUser(t *testing.T) {
 					mt.AddMockResponses(first, killCursors)
 				}
 
-				userRepo := repositories.NewUserRepository(mt.DB, collection)
+				userRepo := repositories.NewUserRepository(mt.DB, collection, repositories.DefaultTimeouts)
 
 				// -------------------- Act (กระทำ)--------------------
-				gotResult, err := userRepo.Gets(models.RepoGetUserModel{UserId: tt.args.filter.UserId, Username: tt.args.filter.Username})
+				gotResult, err := userRepo.Gets(ctx, models.RepoGetUserModel{UserId: tt.args.filter.UserId, Username: tt.args.filter.Username})
 
 				// -------------------- Assert (ยืนยัน) --------------------
 				assert.Equal(mt, tt.wantErr, err != nil)
 				assert.Equal(mt, tt.wantResult, gotResult)
+				if !tt.wantErr {
+					_, errCollation := mt.GetStartedEvent().Command.LookupErr("collation")
+					assert.Equal(mt, tt.wantCollation, errCollation == nil)
+				}
+			})
+		})
+	}
+}
+
+func TestListUser(t *testing.T) {
+	createdAt := time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC)
+	type args struct {
+		query models.RepoListUserModel
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantResult []models.RepoUserModel
+		wantErr    bool
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "error1",
+			args:    args{query: models.RepoListUserModel{SortBy: models.UserSortUsername, Limit: 10}},
+			wantErr: true,
+		},
+		{
+			name: "success username",
+			args: args{query: models.RepoListUserModel{UsernamePrefix: "ad", SortBy: models.UserSortUsername, Limit: 10}},
+			wantResult: []models.RepoUserModel{
+				{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: "admin", CreatedAt: createdAt},
+			},
+			wantErr: false,
+		},
+		{
+			name: "success created_at after",
+			args: args{query: models.RepoListUserModel{SortBy: models.UserSortCreatedAt, Descending: true, After: &models.RepoUserModel{UserId: "6b3f4c1e-5a2d-4e8f-9c7b-1d2e3f4a5b6c", CreatedAt: createdAt}, Limit: 10}},
+			wantResult: []models.RepoUserModel{
+				{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: "admin", CreatedAt: createdAt},
+			},
+			wantErr: false,
+		},
+	}
+
+	collection := "users"
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt.Run(tt.name, func(mt *mtest.T) {
+				// ------------------- Arrange (เตรียมของ) --------------------
+
+				// mock Find
+				if tt.wantErr {
+					mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+						Index:   1,
+						Code:    123,
+						Message: "some error",
+					}))
+				} else {
+					docs := []bson.D{}
+					for _, user := range tt.wantResult {
+						docs = append(docs, bson.D{{Key: "user_id", Value: user.UserId}, {Key: "username", Value: user.Username}, {Key: "created_at", Value: user.CreatedAt}})
+					}
+
+					first := mtest.CreateCursorResponse(1, fmt.Sprintf("%v.%v", "DBtest", collection), mtest.FirstBatch, docs...)
+					killCursors := mtest.CreateCursorResponse(0, fmt.Sprintf("%v.%v", "DBtest", collection), mtest.NextBatch)
+					mt.AddMockResponses(first, killCursors)
+				}
+
+				userRepo := repositories.NewUserRepository(mt.DB, collection, repositories.DefaultTimeouts)
+
+				// -------------------- Act (กระทำ)--------------------
+				gotResult, err := userRepo.List(ctx, tt.args.query)
+
+				// -------------------- Assert (ยืนยัน) --------------------
+				assert.Equal(mt, tt.wantErr, err != nil)
+				assert.Equal(mt, tt.wantResult, gotResult)
+			})
+		})
+	}
+}
+
+func TestBackfillUsers(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantResult bson.D
+		wantErr    bool
+	}{
+		// TODO: Add test cases.
+		{
+			name: "error1",
+			wantResult: mtest.CreateWriteErrorsResponse(mtest.WriteError{
+				Index:   1,
+				Code:    123,
+				Message: "some error",
+			}),
+			wantErr: true,
+		},
+		{
+			name:       "success1",
+			wantResult: mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}, bson.E{Key: "nModified", Value: 2}),
+			wantErr:    false,
+		},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt.Run(tt.name, func(mt *mtest.T) {
+				// ------------------- Arrange (เตรียมของ) --------------------
+
+				// mock UpdateMany
+				mt.AddMockResponses(tt.wantResult)
+
+				// -------------------- Act (กระทำ)--------------------
+				err := repositories.BackfillUsers(ctx, mt.DB, "users")
+
+				// -------------------- Assert (ยืนยัน) --------------------
+				assert.Equal(mt, tt.wantErr, err != nil)
 			})
 		})
 	}
@@ -104,6 +246,7 @@ func TestCreateUser(t *testing.T) {
 		args       args
 		wantResult bson.D
 		wantErr    bool
+		wantErrIs  error
 	}{
 		{
 			name: "error1",
@@ -121,6 +264,23 @@ func TestCreateUser(t *testing.T) {
 			}),
 			wantErr: true,
 		},
+		{
+			name: "duplicate username",
+			args: args{
+				payload: models.RepoCreateUserModel{
+					UserId:   "123",
+					Username: "admin",
+					Password: "admin01",
+				},
+			},
+			wantResult: mtest.CreateWriteErrorsResponse(mtest.WriteError{
+				Index:   0,
+				Code:    11000,
+				Message: "E11000 duplicate key error collection: DBtest.users index: username_1 dup key: { username: \"admin\" }",
+			}),
+			wantErr:   true,
+			wantErrIs: models.ErrUsernameIsExist,
+		},
 		{
 			name: "success1",
 			args: args{
@@ -147,13 +307,16 @@ func TestCreateUser(t *testing.T) {
 				// mock InsertOne
 				mt.AddMockResponses(tt.wantResult)
 
-				userRepo := repositories.NewUserRepository(mt.DB, "users")
+				userRepo := repositories.NewUserRepository(mt.DB, "users", repositories.DefaultTimeouts)
 
 				// -------------------- Act (กระทำ)--------------------
-				err := userRepo.Create(models.RepoCreateUserModel{UserId: tt.args.payload.UserId, Username: tt.args.payload.Username, Password: tt.args.payload.Password})
+				err := userRepo.Create(ctx, models.RepoCreateUserModel{UserId: tt.args.payload.UserId, Username: tt.args.payload.Username, Password: tt.args.payload.Password})
 
 				// -------------------- Assert (ยืนยัน) --------------------
 				assert.Equal(mt, tt.wantErr, err != nil)
+				if tt.wantErrIs != nil {
+					assert.ErrorIs(mt, err, tt.wantErrIs)
+				}
 			})
 		})
 	}
@@ -164,11 +327,18 @@ func TestUpdateUser(t *testing.T) {
 		userId  string
 		payload models.RepoUpdateUserModel
 	}
+	displayName, email, team := "Admin", "", "core"
+	updatedAt := time.Date(2023, 8, 3, 10, 0, 0, 0, time.UTC)
 	tests := []struct {
 		name       string
 		args       args
 		wantResult bson.D
 		wantErr    bool
+		wantErrIs  error
+		wantUpdate bson.M
+		wantFilter bson.M
+		// answers the count that tells a changed credential version from a missing user
+		countResult bson.D
 	}{
 		// TODO: Add test cases.
 		{
@@ -199,6 +369,53 @@ func TestUpdateUser(t *testing.T) {
 			wantResult: mtest.CreateSuccessResponse(bson.E{Key: "ok", Value: "0"}, bson.E{Key: "nModified", Value: 0}, bson.E{Key: "n", Value: 0}),
 			wantErr:    true,
 		},
+		{
+			name: "duplicate username",
+			args: args{
+				userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191",
+				payload: models.RepoUpdateUserModel{
+					Username: "admin",
+				},
+			},
+			wantResult: mtest.CreateWriteErrorsResponse(mtest.WriteError{
+				Index:   0,
+				Code:    11000,
+				Message: "E11000 duplicate key error collection: DBtest.users index: username_1 dup key: { username: \"admin\" }",
+			}),
+			wantErr:   true,
+			wantErrIs: models.ErrUsernameIsExist,
+		},
+		{
+			name: "credentials changed",
+			args: args{
+				userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191",
+				payload: models.RepoUpdateUserModel{
+					Password:          "admin01",
+					CredentialVersion: 3,
+				},
+			},
+			wantResult:  mtest.CreateSuccessResponse(bson.E{Key: "ok", Value: "1"}, bson.E{Key: "nModified", Value: 0}, bson.E{Key: "n", Value: 0}),
+			countResult: mtest.CreateCursorResponse(0, "DBtest.users", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			wantErr:     true,
+			wantErrIs:   models.ErrCredentialsChanged,
+			wantFilter:  bson.M{"user_id": "225cfc88-c66b-4f2f-b424-a3b74e3b1191", "credential_version": int32(2)},
+		},
+		{
+			name: "credential version missing user",
+			args: args{
+				userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191",
+				payload: models.RepoUpdateUserModel{
+					Password:          "admin01",
+					CredentialVersion: 1,
+				},
+			},
+			wantResult:  mtest.CreateSuccessResponse(bson.E{Key: "ok", Value: "1"}, bson.E{Key: "nModified", Value: 0}, bson.E{Key: "n", Value: 0}),
+			countResult: mtest.CreateCursorResponse(0, "DBtest.users", mtest.FirstBatch),
+			wantErr:     true,
+			wantErrIs:   models.ErrUserIdIsNotExist,
+			// users stored before credential versions have none
+			wantFilter: bson.M{"user_id": "225cfc88-c66b-4f2f-b424-a3b74e3b1191", "credential_version": bson.M{"$in": bson.A{int32(0), nil}}},
+		},
 		{
 			name: "success1",
 			args: args{
@@ -211,6 +428,30 @@ func TestUpdateUser(t *testing.T) {
 			wantResult: mtest.CreateSuccessResponse(bson.E{Key: "ok", Value: "1"}, bson.E{Key: "nModified", Value: 1}, bson.E{Key: "n", Value: 1}),
 			wantErr:    false,
 		},
+		{
+			name: "success profile",
+			args: args{
+				userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191",
+				payload: models.RepoUpdateUserModel{
+					DisplayName: &displayName,
+					Email:       &email,
+					Attributes:  map[string]*string{"team": &team, "floor": nil},
+					UpdatedAt:   updatedAt,
+				},
+			},
+			wantResult: mtest.CreateSuccessResponse(bson.E{Key: "ok", Value: "1"}, bson.E{Key: "nModified", Value: 1}, bson.E{Key: "n", Value: 1}),
+			wantErr:    false,
+			// the empty email is set, attributes are set and unset key by key
+			wantUpdate: bson.M{
+				"$set": bson.M{
+					"display_name":    "Admin",
+					"email":           "",
+					"updated_at":      primitive.NewDateTimeFromTime(updatedAt),
+					"attributes.team": "core",
+				},
+				"$unset": bson.M{"attributes.floor": ""},
+			},
+		},
 	}
 
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
@@ -222,15 +463,33 @@ func TestUpdateUser(t *testing.T) {
 				// ------------------- Arrange (เตรียมของ) --------------------
 
 				// mock UpdateOne
+				mt.ClearEvents()
 				mt.AddMockResponses(tt.wantResult)
+				if tt.countResult != nil {
+					mt.AddMockResponses(tt.countResult)
+				}
 
-				userRepo := repositories.NewUserRepository(mt.DB, "users")
+				userRepo := repositories.NewUserRepository(mt.DB, "users", repositories.DefaultTimeouts)
 
 				// -------------------- Act (กระทำ)--------------------
-				err := userRepo.Update(tt.args.userId, models.RepoUpdateUserModel{Username: tt.args.payload.Username, Password: tt.args.payload.Password})
+				err := userRepo.Update(ctx, tt.args.userId, tt.args.payload)
 
 				// -------------------- Assert (ยืนยัน) --------------------
 				assert.Equal(mt, tt.wantErr, err != nil)
+				if tt.wantErrIs != nil {
+					assert.ErrorIs(mt, err, tt.wantErrIs)
+				}
+				updateEvent := mt.GetStartedEvent()
+				if tt.wantUpdate != nil {
+					update := bson.M{}
+					bson.Unmarshal(updateEvent.Command.Lookup("updates", "0", "u").Document(), &update)
+					assert.Equal(mt, tt.wantUpdate, update)
+				}
+				if tt.wantFilter != nil {
+					filter := bson.M{}
+					bson.Unmarshal(updateEvent.Command.Lookup("updates", "0", "q").Document(), &filter)
+					assert.Equal(mt, tt.wantFilter, filter)
+				}
 			})
 		})
 	}
@@ -288,10 +547,10 @@ func TestDeleteUser(t *testing.T) {
 				// mock DeleteOne
 				mt.AddMockResponses(tt.wantResult)
 
-				userRepo := repositories.NewUserRepository(mt.DB, "users")
+				userRepo := repositories.NewUserRepository(mt.DB, "users", repositories.DefaultTimeouts)
 
 				// -------------------- Act (กระทำ)--------------------
-				err := userRepo.Delete(tt.args.userId)
+				err := userRepo.Delete(ctx, tt.args.userId)
 
 				// -------------------- Assert (ยืนยัน) --------------------
 				assert.Equal(mt, tt.wantErr, err != nil)
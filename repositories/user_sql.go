@@ -0,0 +1,323 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hexagonal-gotest/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLDialect values double as the database/sql driver names registered by lib/pq and modernc sqlite
+type SQLDialect string
+
+const (
+	SQLDialectPostgres SQLDialect = "postgres"
+	SQLDialectSQLite   SQLDialect = "sqlite"
+)
+
+func (d SQLDialect) placeholder(n int) string {
+	if d == SQLDialectPostgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// sqliteTimeFormat has a fixed width so sqlite, which stores timestamps as text, orders them by time
+const sqliteTimeFormat = "2006-01-02 15:04:05.000000"
+
+// timestamp converts t for a TIMESTAMP column, it is stored in UTC with microseconds
+func (d SQLDialect) timestamp(t time.Time) interface{} {
+	if d == SQLDialectSQLite {
+		return t.UTC().Format(sqliteTimeFormat)
+	}
+	return t.UTC()
+}
+
+// mergeJSON merges the json object in placeholder into the json text column, keys set
+// to null in it are removed like a json merge patch
+func (d SQLDialect) mergeJSON(column, placeholder string) string {
+	if d == SQLDialectPostgres {
+		return fmt.Sprintf("jsonb_strip_nulls(%v::jsonb || %v::jsonb)::text", column, placeholder)
+	}
+	return fmt.Sprintf("json_patch(%v, %v)", column, placeholder)
+}
+
+func (d SQLDialect) isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
+
+type userSQL struct {
+	db       *sql.DB
+	dialect  SQLDialect
+	timeouts Timeouts
+}
+
+// NewUserSQLRepository expects the schema from MigrateSQL
+func NewUserSQLRepository(db *sql.DB, dialect SQLDialect, timeouts Timeouts) UserRepository {
+	return userSQL{db, dialect, timeouts}
+}
+
+func (r userSQL) Gets(ctx context.Context, filter models.RepoGetUserModel) (result []models.RepoUserModel, err error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	conditions := []string{}
+	args := []interface{}{}
+	if filter.UserId != "" {
+		args = append(args, filter.UserId)
+		conditions = append(conditions, "user_id = "+r.dialect.placeholder(len(args)))
+	}
+	if filter.Username != "" {
+		args = append(args, filter.Username)
+		// sqlite LOWER only folds ASCII, services store usernames already case folded
+		conditions = append(conditions, "LOWER(username) = LOWER("+r.dialect.placeholder(len(args))+")")
+	}
+
+	query := "SELECT " + userSQLColumns + " FROM users"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return r.query(ctx, query, args...)
+}
+
+func (r userSQL) List(ctx context.Context, query models.RepoListUserModel) (result []models.RepoUserModel, err error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	column, direction, comparison := "LOWER(username)", "ASC", ">"
+	if query.SortBy == models.UserSortCreatedAt {
+		column = "created_at"
+	}
+	if query.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	conditions := []string{}
+	args := []interface{}{}
+	if query.UsernamePrefix != "" {
+		args = append(args, likePrefix(query.UsernamePrefix))
+		conditions = append(conditions, "LOWER(username) LIKE LOWER("+r.dialect.placeholder(len(args))+") ESCAPE '\\'")
+	}
+	if query.After != nil {
+		if query.SortBy == models.UserSortCreatedAt {
+			args = append(args, r.dialect.timestamp(query.After.CreatedAt))
+		} else {
+			args = append(args, strings.ToLower(query.After.Username))
+		}
+		args = append(args, query.After.UserId)
+		conditions = append(conditions, fmt.Sprintf("(%v, user_id) %v (%v, %v)", column, comparison, r.dialect.placeholder(len(args)-1), r.dialect.placeholder(len(args))))
+	}
+
+	statement := "SELECT " + userSQLColumns + " FROM users"
+	if len(conditions) > 0 {
+		statement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, query.Limit)
+	statement += fmt.Sprintf(" ORDER BY %v %v, user_id %v LIMIT %v", column, direction, direction, r.dialect.placeholder(len(args)))
+
+	return r.query(ctx, statement, args...)
+}
+
+const userSQLColumns = "user_id, username, password, password_history, credential_version, role, display_name, email, locale, attributes, created_at, updated_at, last_login_at"
+
+func (r userSQL) query(ctx context.Context, query string, args ...interface{}) (result []models.RepoUserModel, err error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user := models.RepoUserModel{}
+		passwordHistory := ""
+		attributes := ""
+		lastLoginAt := sql.NullTime{}
+		if err = rows.Scan(&user.UserId, &user.Username, &user.Password, &passwordHistory, &user.CredentialVersion, &user.Role, &user.DisplayName, &user.Email, &user.Locale, &attributes, &user.CreatedAt, &user.UpdatedAt, &lastLoginAt); err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal([]byte(passwordHistory), &user.PasswordHistory); err != nil {
+			return nil, err
+		}
+		if len(user.PasswordHistory) == 0 {
+			user.PasswordHistory = nil
+		}
+		if err = json.Unmarshal([]byte(attributes), &user.Attributes); err != nil {
+			return nil, err
+		}
+		if len(user.Attributes) == 0 {
+			user.Attributes = nil
+		}
+		user.CreatedAt = user.CreatedAt.UTC()
+		user.UpdatedAt = user.UpdatedAt.UTC()
+		if lastLoginAt.Valid {
+			user.LastLoginAt = lastLoginAt.Time.UTC()
+		}
+		result = append(result, user)
+	}
+
+	return result, rows.Err()
+}
+
+// likePrefix matches values starting with prefix, taking its % and _ literally
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%"
+}
+
+func (r userSQL) Create(ctx context.Context, payload models.RepoCreateUserModel) (err error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	attributes, err := json.Marshal(payload.Attributes)
+	if err != nil {
+		return err
+	}
+	if payload.Attributes == nil {
+		attributes = []byte("{}")
+	}
+
+	placeholders := make([]string, 10)
+	for i := range placeholders {
+		placeholders[i] = r.dialect.placeholder(i + 1)
+	}
+	query := "INSERT INTO users (user_id, username, password, role, display_name, email, locale, attributes, created_at, updated_at) VALUES (" + strings.Join(placeholders, ", ") + ")"
+	_, err = r.db.ExecContext(ctx, query, payload.UserId, payload.Username, payload.Password, payload.Role, payload.DisplayName, payload.Email, payload.Locale, string(attributes),
+		r.dialect.timestamp(payload.CreatedAt), r.dialect.timestamp(payload.UpdatedAt))
+	if err != nil {
+		if r.dialect.isUniqueViolation(err) {
+			return models.ErrUsernameIsExist
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r userSQL) Update(ctx context.Context, userId string, payload models.RepoUpdateUserModel) (err error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	// with nothing to set the no-op assignment still reports whether the user exists
+	assignments := []string{}
+	args := []interface{}{}
+	if payload.Username != "" {
+		args = append(args, payload.Username)
+		assignments = append(assignments, "username = "+r.dialect.placeholder(len(args)))
+	}
+	if payload.Password != "" {
+		args = append(args, payload.Password)
+		assignments = append(assignments, "password = "+r.dialect.placeholder(len(args)))
+	}
+	if len(payload.PasswordHistory) > 0 {
+		passwordHistory, err := json.Marshal(payload.PasswordHistory)
+		if err != nil {
+			return err
+		}
+		args = append(args, string(passwordHistory))
+		assignments = append(assignments, "password_history = "+r.dialect.placeholder(len(args)))
+	}
+	if payload.CredentialVersion != 0 {
+		args = append(args, payload.CredentialVersion)
+		assignments = append(assignments, "credential_version = "+r.dialect.placeholder(len(args)))
+	}
+	if payload.DisplayName != nil {
+		args = append(args, *payload.DisplayName)
+		assignments = append(assignments, "display_name = "+r.dialect.placeholder(len(args)))
+	}
+	if payload.Email != nil {
+		args = append(args, *payload.Email)
+		assignments = append(assignments, "email = "+r.dialect.placeholder(len(args)))
+	}
+	if payload.Locale != nil {
+		args = append(args, *payload.Locale)
+		assignments = append(assignments, "locale = "+r.dialect.placeholder(len(args)))
+	}
+	if len(payload.Attributes) > 0 {
+		// nil values marshal to null, which the merge removes
+		patch, err := json.Marshal(payload.Attributes)
+		if err != nil {
+			return err
+		}
+		args = append(args, string(patch))
+		assignments = append(assignments, "attributes = "+r.dialect.mergeJSON("attributes", r.dialect.placeholder(len(args))))
+	}
+	if !payload.UpdatedAt.IsZero() {
+		args = append(args, r.dialect.timestamp(payload.UpdatedAt))
+		assignments = append(assignments, "updated_at = "+r.dialect.placeholder(len(args)))
+	}
+	if !payload.LastLoginAt.IsZero() {
+		args = append(args, r.dialect.timestamp(payload.LastLoginAt))
+		assignments = append(assignments, "last_login_at = "+r.dialect.placeholder(len(args)))
+	}
+	if len(assignments) == 0 {
+		assignments = append(assignments, "user_id = user_id")
+	}
+	args = append(args, userId)
+	where := "user_id = " + r.dialect.placeholder(len(args))
+	if payload.CredentialVersion != 0 {
+		args = append(args, payload.CredentialVersion-1)
+		where += " AND credential_version = " + r.dialect.placeholder(len(args))
+	}
+
+	query := "UPDATE users SET " + strings.Join(assignments, ", ") + " WHERE " + where
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		if r.dialect.isUniqueViolation(err) {
+			return models.ErrUsernameIsExist
+		}
+		return err
+	}
+
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		if payload.CredentialVersion == 0 {
+			return models.ErrUserIdIsNotExist
+		}
+		var count int
+		if err = r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE user_id = "+r.dialect.placeholder(1), userId).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			return models.ErrCredentialsChanged
+		}
+		return models.ErrUserIdIsNotExist
+	}
+
+	return nil
+}
+
+func (r userSQL) Delete(ctx context.Context, userId string) (err error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	res, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE user_id = "+r.dialect.placeholder(1), userId)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return models.ErrUserIdIsNotExist
+	}
+
+	return nil
+}
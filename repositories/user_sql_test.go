@@ -0,0 +1,165 @@
+package repositories_test
+
+import (
+	"database/sql"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newSQLDB opens a migrated SQLite file, or the PostgreSQL database in TEST_POSTGRES_DSN
+// when dialect is postgres, skipping the test when it is not set
+func newSQLDB(t *testing.T, dialect repositories.SQLDialect) *sql.DB {
+	dsn := filepath.Join(t.TempDir(), "users.db")
+	if dialect == repositories.SQLDialectPostgres {
+		dsn = os.Getenv("TEST_POSTGRES_DSN")
+		if dsn == "" {
+			t.Skip("TEST_POSTGRES_DSN is not set")
+		}
+	}
+
+	db, err := sql.Open(string(dialect), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if dialect == repositories.SQLDialectSQLite {
+		// sqlite allows a single writer, concurrent connections fail with SQLITE_BUSY
+		db.SetMaxOpenConns(1)
+	}
+
+	if dialect == repositories.SQLDialectPostgres {
+		db.Exec("DROP TABLE IF EXISTS users, schema_migrations")
+	}
+	if err := repositories.MigrateSQL(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMigrateSQL(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	db := newSQLDB(t, repositories.SQLDialectSQLite)
+
+	// -------------------- Act (กระทำ)--------------------
+	err := repositories.MigrateSQL(ctx, db, repositories.SQLDialectSQLite)
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.NoError(t, err, "migrations must be idempotent")
+
+	files, _ := filepath.Glob("migrations/*.sql")
+	var versions int
+	db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&versions)
+	assert.Equal(t, len(files), versions)
+}
+
+func TestUserSQL(t *testing.T) {
+	for _, dialect := range []repositories.SQLDialect{repositories.SQLDialectSQLite, repositories.SQLDialectPostgres} {
+		t.Run(string(dialect), func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userRepo := repositories.NewUserSQLRepository(newSQLDB(t, dialect), dialect, repositories.DefaultTimeouts)
+			assert.NoError(t, userRepo.Create(ctx, models.RepoCreateUserModel{UserId: "user-1", Username: "admin", Password: "hash-1", Role: models.RoleAdmin}))
+			assert.NoError(t, userRepo.Create(ctx, models.RepoCreateUserModel{UserId: "user-2", Username: "user", Password: "hash-2", Role: models.RoleUser}))
+
+			// -------------------- Act (กระทำ)--------------------
+			errCreate := userRepo.Create(ctx, models.RepoCreateUserModel{UserId: "user-3", Username: "admin", Password: "hash-3", Role: models.RoleUser})
+			errUpdate := userRepo.Update(ctx, "user-2", models.RepoUpdateUserModel{Password: "hash-3"})
+			errUpdateUsername := userRepo.Update(ctx, "user-2", models.RepoUpdateUserModel{Username: "admin"})
+			errUpdateMissing := userRepo.Update(ctx, "user-4", models.RepoUpdateUserModel{Password: "hash-4"})
+			errDelete := userRepo.Delete(ctx, "user-1")
+			errDeleteMissing := userRepo.Delete(ctx, "user-1")
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.Equal(t, models.ErrUsernameIsExist, errCreate)
+			assert.NoError(t, errUpdate)
+			assert.Equal(t, models.ErrUsernameIsExist, errUpdateUsername)
+			assert.Equal(t, models.ErrUserIdIsNotExist, errUpdateMissing)
+			assert.NoError(t, errDelete)
+			assert.Equal(t, models.ErrUserIdIsNotExist, errDeleteMissing)
+
+			users, err := userRepo.Gets(ctx, models.RepoGetUserModel{})
+			assert.NoError(t, err)
+			assert.Equal(t, []models.RepoUserModel{{UserId: "user-2", Username: "user", Password: "hash-3", Role: models.RoleUser}}, users)
+
+			users, err = userRepo.Gets(ctx, models.RepoGetUserModel{UserId: "user-2", Username: "user"})
+			assert.NoError(t, err)
+			assert.Len(t, users, 1)
+
+			users, _ = userRepo.Gets(ctx, models.RepoGetUserModel{Username: "admin"})
+			assert.Empty(t, users)
+		})
+	}
+}
+
+func TestMigrateSQLBackfillsUsers(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	db, err := sql.Open(string(repositories.SQLDialectSQLite), filepath.Join(t.TempDir(), "users.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// a database migrated before profiles existed, holding one user
+	for _, statement := range []string{
+		"CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, name TEXT NOT NULL, applied_at TIMESTAMP NOT NULL)",
+		"INSERT INTO schema_migrations VALUES (1, '0001_create_users.sql', '2023-08-01 00:00:00'), (2, '0002_username_case_insensitive.sql', '2023-08-01 00:00:00'), (3, '0003_users_created_at.sql', '2023-08-01 00:00:00')",
+		"CREATE TABLE users (user_id TEXT PRIMARY KEY, username TEXT NOT NULL, password TEXT NOT NULL, role TEXT NOT NULL DEFAULT 'user', created_at TIMESTAMP NOT NULL DEFAULT '0001-01-01 00:00:00.000000')",
+		"INSERT INTO users (user_id, username, password, created_at) VALUES ('user-1', 'admin', 'hash-1', '2023-08-01 10:00:00.000000')",
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// -------------------- Act (กระทำ)--------------------
+	err = repositories.MigrateSQL(ctx, db, repositories.SQLDialectSQLite)
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.NoError(t, err)
+	users, err := repositories.NewUserSQLRepository(db, repositories.SQLDialectSQLite, repositories.DefaultTimeouts).Gets(ctx, models.RepoGetUserModel{})
+	assert.NoError(t, err)
+	createdAt := time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, []models.RepoUserModel{{UserId: "user-1", Username: "admin", Password: "hash-1", Role: models.RoleUser, CreatedAt: createdAt, UpdatedAt: createdAt}}, users)
+}
+
+func TestMigrateSQLBackfillsCreatedAt(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	db, err := sql.Open(string(repositories.SQLDialectSQLite), filepath.Join(t.TempDir(), "users.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// a database migrated before creation times existed, holding one user
+	for _, statement := range []string{
+		"CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, name TEXT NOT NULL, applied_at TIMESTAMP NOT NULL)",
+		"INSERT INTO schema_migrations VALUES (1, '0001_create_users.sql', '2023-08-01 00:00:00'), (2, '0002_username_case_insensitive.sql', '2023-08-01 00:00:00')",
+		"CREATE TABLE users (user_id TEXT PRIMARY KEY, username TEXT NOT NULL, password TEXT NOT NULL, role TEXT NOT NULL DEFAULT 'user')",
+		"INSERT INTO users (user_id, username, password) VALUES ('user-1', 'admin', 'hash-1')",
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := time.Now().Add(-time.Second)
+
+	// -------------------- Act (กระทำ)--------------------
+	err = repositories.MigrateSQL(ctx, db, repositories.SQLDialectSQLite)
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.NoError(t, err)
+	users, err := repositories.NewUserSQLRepository(db, repositories.SQLDialectSQLite, repositories.DefaultTimeouts).Gets(ctx, models.RepoGetUserModel{})
+	assert.NoError(t, err)
+	if assert.Len(t, users, 1) {
+		assert.True(t, users[0].CreatedAt.After(before), "the user counts as created when the migration ran, not in year 1")
+		assert.Equal(t, users[0].CreatedAt, users[0].UpdatedAt)
+	}
+	var createdAt string
+	db.QueryRow("SELECT CAST(created_at AS TEXT) FROM users WHERE user_id = 'user-1'").Scan(&createdAt)
+	assert.Len(t, createdAt, len("2006-01-02 15:04:05.000000"), "written like the adapter writes timestamps so they order by time")
+}
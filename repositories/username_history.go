@@ -0,0 +1,14 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+)
+
+// PORT username history repository
+type UsernameHistoryRepository interface {
+	Create(ctx context.Context, payload models.RepoUsernameHistoryModel) (err error)
+
+	// Gets returns the unexpired entries of username, which is compared as stored
+	Gets(ctx context.Context, username string) (result []models.RepoUsernameHistoryModel, err error)
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+type usernameHistoryMemory struct {
+	mu      *sync.Mutex
+	entries map[string][]models.RepoUsernameHistoryModel
+	sweep   memorySweep
+}
+
+func NewUsernameHistoryMemoryRepository() UsernameHistoryRepository {
+	return usernameHistoryMemory{&sync.Mutex{}, map[string][]models.RepoUsernameHistoryModel{}, newMemorySweep()}
+}
+
+func (r usernameHistoryMemory) Create(ctx context.Context, payload models.RepoUsernameHistoryModel) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if r.sweep.due(now) {
+		r.evictExpired(now)
+	}
+
+	// a database copies what it stores, the strings may alias a buffer the caller
+	// reuses, like the fiber params the user id comes from
+	payload.Username = strings.Clone(payload.Username)
+	payload.UserId = strings.Clone(payload.UserId)
+	r.entries[payload.Username] = append(liveUsernameHistory(r.entries[payload.Username], now), payload)
+
+	return nil
+}
+
+func (r usernameHistoryMemory) Gets(ctx context.Context, username string) (result []models.RepoUsernameHistoryModel, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, entry := range r.entries[username] {
+		if now.Before(entry.ExpiresAt) {
+			result = append(result, entry)
+		}
+	}
+
+	return result, nil
+}
+
+// evictExpired drops released usernames nobody asks about, caller holds mu
+func (r usernameHistoryMemory) evictExpired(now time.Time) {
+	for username, entries := range r.entries {
+		if live := liveUsernameHistory(entries, now); len(live) == 0 {
+			delete(r.entries, username)
+		} else {
+			r.entries[username] = live
+		}
+	}
+}
+
+// liveUsernameHistory filters entries in place down to the unexpired ones
+func liveUsernameHistory(entries []models.RepoUsernameHistoryModel, now time.Time) []models.RepoUsernameHistoryModel {
+	live := entries[:0]
+	for _, entry := range entries {
+		if now.Before(entry.ExpiresAt) {
+			live = append(live, entry)
+		}
+	}
+	return live
+}
@@ -0,0 +1,34 @@
+package repositories_test
+
+import (
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsernameHistoryMemory(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	usernameHistoryRepo := repositories.NewUsernameHistoryMemoryRepository()
+	now := time.Now()
+
+	// -------------------- Act (กระทำ)--------------------
+	usernameHistoryRepo.Create(ctx, models.RepoUsernameHistoryModel{Username: "admin", UserId: "user-1", ReleasedAt: now, ExpiresAt: now.Add(time.Hour)})
+	usernameHistoryRepo.Create(ctx, models.RepoUsernameHistoryModel{Username: "admin", UserId: "user-2", ReleasedAt: now, ExpiresAt: now.Add(-time.Second)})
+	usernameHistoryRepo.Create(ctx, models.RepoUsernameHistoryModel{Username: "root", UserId: "user-3", ReleasedAt: now, ExpiresAt: now.Add(time.Hour)})
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	entries, err := usernameHistoryRepo.Gets(ctx, "admin")
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "user-1", entries[0].UserId)
+	}
+
+	entries, _ = usernameHistoryRepo.Gets(ctx, "root")
+	assert.Len(t, entries, 1)
+
+	entries, _ = usernameHistoryRepo.Gets(ctx, "nobody")
+	assert.Empty(t, entries)
+}
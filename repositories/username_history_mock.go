@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type usernameHistoryRepoMock struct {
+	mock.Mock
+}
+
+func NewUsernameHistoryRepoMock() usernameHistoryRepoMock {
+	return usernameHistoryRepoMock{}
+}
+
+func (m *usernameHistoryRepoMock) Create(ctx context.Context, payload models.RepoUsernameHistoryModel) (err error) {
+	args := m.Called(ctx, payload)
+	return args.Error(0)
+}
+
+func (m *usernameHistoryRepoMock) Gets(ctx context.Context, username string) (result []models.RepoUsernameHistoryModel, err error) {
+	args := m.Called(ctx, username)
+	res, _ := args.Get(0).([]models.RepoUsernameHistoryModel)
+	return res, args.Error(1)
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type usernameHistoryRepo struct {
+	db         *mongo.Database
+	collection string
+	timeouts   Timeouts
+}
+
+func NewUsernameHistoryRepository(db *mongo.Database, collection string, timeouts Timeouts) UsernameHistoryRepository {
+	return usernameHistoryRepo{db, collection, timeouts}
+}
+
+// EnsureUsernameHistoryIndexes lets mongo drop released usernames once their cooldown is over
+func EnsureUsernameHistoryIndexes(ctx context.Context, db *mongo.Database, collection string) (err error) {
+	_, err = db.Collection(collection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "username", Value: 1}}},
+	})
+
+	return err
+}
+
+func (r usernameHistoryRepo) Create(ctx context.Context, payload models.RepoUsernameHistoryModel) (err error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err = r.db.Collection(r.collection).InsertOne(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r usernameHistoryRepo) Gets(ctx context.Context, username string) (result []models.RepoUsernameHistoryModel, err error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	// the ttl monitor runs once a minute, expired entries are filtered until it catches up
+	cursor, err := r.db.Collection(r.collection).Find(ctx, bson.D{
+		{Key: "username", Value: username},
+		{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: time.Now()}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,126 @@
+package repositories_test
+
+import (
+	"fmt"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestCreateUsernameHistory(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantResult bson.D
+		wantErr    bool
+	}{
+		// TODO: Add test cases.
+		{
+			name: "error1",
+			wantResult: mtest.CreateWriteErrorsResponse(mtest.WriteError{
+				Index:   1,
+				Code:    123,
+				Message: "some error",
+			}),
+			wantErr: true,
+		},
+		{
+			name:       "success1",
+			wantResult: mtest.CreateSuccessResponse(),
+			wantErr:    false,
+		},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt.Run(tt.name, func(mt *mtest.T) {
+				// ------------------- Arrange (เตรียมของ) --------------------
+
+				// mock InsertOne
+				mt.AddMockResponses(tt.wantResult)
+
+				usernameHistoryRepo := repositories.NewUsernameHistoryRepository(mt.DB, "username_history", repositories.DefaultTimeouts)
+
+				// -------------------- Act (กระทำ)--------------------
+				now := time.Now()
+				err := usernameHistoryRepo.Create(ctx, models.RepoUsernameHistoryModel{Username: "admin", UserId: "user-1", ReleasedAt: now, ExpiresAt: now.Add(time.Hour)})
+
+				// -------------------- Assert (ยืนยัน) --------------------
+				assert.Equal(mt, tt.wantErr, err != nil)
+			})
+		})
+	}
+}
+
+func TestGetsUsernameHistory(t *testing.T) {
+	releasedAt := time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		wantResult []models.RepoUsernameHistoryModel
+		wantErr    bool
+	}{
+		// TODO: Add test cases.
+		{
+			name:       "error1",
+			wantResult: nil,
+			wantErr:    true,
+		},
+		{
+			name: "success1",
+			wantResult: []models.RepoUsernameHistoryModel{
+				{Username: "admin", UserId: "user-1", ReleasedAt: releasedAt, ExpiresAt: releasedAt.Add(time.Hour)},
+			},
+			wantErr: false,
+		},
+	}
+
+	collection := "username_history"
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt.Run(tt.name, func(mt *mtest.T) {
+				// ------------------- Arrange (เตรียมของ) --------------------
+
+				// mock Find
+				if tt.wantErr {
+					mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+						Index:   1,
+						Code:    123,
+						Message: "some error",
+					}))
+				} else {
+					docs := []bson.D{}
+					for _, entry := range tt.wantResult {
+						docs = append(docs, bson.D{{Key: "username", Value: entry.Username}, {Key: "user_id", Value: entry.UserId}, {Key: "released_at", Value: entry.ReleasedAt}, {Key: "expires_at", Value: entry.ExpiresAt}})
+					}
+
+					first := mtest.CreateCursorResponse(1, fmt.Sprintf("%v.%v", "DBtest", collection), mtest.FirstBatch, docs...)
+					killCursors := mtest.CreateCursorResponse(0, fmt.Sprintf("%v.%v", "DBtest", collection), mtest.NextBatch)
+					mt.AddMockResponses(first, killCursors)
+				}
+
+				usernameHistoryRepo := repositories.NewUsernameHistoryRepository(mt.DB, collection, repositories.DefaultTimeouts)
+
+				// -------------------- Act (กระทำ)--------------------
+				gotResult, err := usernameHistoryRepo.Gets(ctx, "admin")
+
+				// -------------------- Assert (ยืนยัน) --------------------
+				assert.Equal(mt, tt.wantErr, err != nil)
+				assert.Equal(mt, len(tt.wantResult), len(gotResult))
+				for i := range tt.wantResult {
+					assert.Equal(mt, tt.wantResult[i].UserId, gotResult[i].UserId)
+					assert.True(mt, tt.wantResult[i].ExpiresAt.Equal(gotResult[i].ExpiresAt))
+				}
+			})
+		})
+	}
+}
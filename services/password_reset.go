@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+)
+
+// PORT password reset service
+type PasswordResetService interface {
+	// Forgot mails a single use reset token to the email on the profile of username. The
+	// mail goes out after Forgot returns, which answers the same for unknown usernames,
+	// accounts without an email and failed sends, so it cannot be used to find out which
+	// accounts exist. It fails with a models.ThrottledError when username or clientIp
+	// asked too often, or too many mails are already waiting.
+	Forgot(ctx context.Context, username, clientIp string) (err error)
+
+	// Wait blocks until the mails of earlier Forgot calls are sent or given up, the
+	// server calls it on shutdown so none are lost
+	Wait()
+
+	// Reset redeems token and sets newPassword through UserService.ResetPassword, which
+	// ends every session. A token refused only for its password can be tried again.
+	Reset(ctx context.Context, token, newPassword string) (err error)
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hexagonal-gotest/mailers"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/policies"
+	"hexagonal-gotest/repositories"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PasswordResetConfig TokenTTL is how long a mailed token can be redeemed. URL is the
+// client page that takes the token, it is added as the token query parameter, an empty
+// URL mails the bare token. SendTimeout bounds the work Forgot leaves running after it
+// answers, 0 gives it no deadline. MaxPending is how many mails may wait to be sent.
+type PasswordResetConfig struct {
+	TokenTTL    time.Duration
+	URL         string
+	SendTimeout time.Duration
+	MaxPending  int
+}
+
+// forgot requests are throttled under their own keys, so asking for mails never locks
+// the login of the account they name
+const forgotThrottleKeyPrefix = "forgot:"
+
+// forgotRetryAfter is what a request is told to wait when every pending mail slot is taken
+const forgotRetryAfter = time.Second
+
+type passwordResetSrv struct {
+	passwordResetRepo repositories.PasswordResetRepository
+	userRepo          repositories.UserRepository
+	userSrv           UserService
+	throttleSrv       ThrottleService
+	mailer            mailers.Mailer
+	usernamePolicy    policies.UsernamePolicy
+	config            PasswordResetConfig
+	slots             chan struct{}
+	pending           *sync.WaitGroup
+}
+
+func NewPasswordResetService(passwordResetRepo repositories.PasswordResetRepository, userRepo repositories.UserRepository, userSrv UserService, throttleSrv ThrottleService, mailer mailers.Mailer, usernamePolicy policies.UsernamePolicy, config PasswordResetConfig) PasswordResetService {
+	return passwordResetSrv{passwordResetRepo, userRepo, userSrv, throttleSrv, mailer, usernamePolicy, config, make(chan struct{}, config.MaxPending), &sync.WaitGroup{}}
+}
+
+func (s passwordResetSrv) Forgot(ctx context.Context, username, clientIp string) (err error) {
+	if username == "" {
+		return models.ErrUsernameNotfound
+	}
+	username = s.usernamePolicy.Canonical(username)
+
+	// the throttle only knows the username and the address, not whether the account
+	// exists, so it answers before the mail is queued
+	throttleIp := ""
+	if clientIp != "" {
+		throttleIp = forgotThrottleKeyPrefix + clientIp
+	}
+	// every request counts, also throttled ones and those that send no mail, and it counts
+	// before the check so a burst of concurrent requests cannot all pass it
+	if err = s.throttleSrv.Failure(ctx, forgotThrottleKeyPrefix+username, throttleIp); err != nil {
+		return err
+	}
+	if err = s.throttleSrv.Check(ctx, forgotThrottleKeyPrefix+username, throttleIp); err != nil {
+		return err
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		return models.NewThrottledError(models.ErrTooManyAttempts, forgotRetryAfter)
+	}
+
+	// everything that depends on the account runs after the answer, so neither its time
+	// nor its errors tell the client whether the username exists
+	s.pending.Add(1)
+	go func() {
+		defer func() {
+			<-s.slots
+			s.pending.Done()
+		}()
+
+		// the request context ends with the answer, the mail gets one of its own
+		ctx := context.Background()
+		if s.config.SendTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.config.SendTimeout)
+			defer cancel()
+		}
+
+		if err := s.forgot(ctx, username); err != nil {
+			log.Printf("forgot password of %q: %v", username, err)
+		}
+	}()
+
+	return nil
+}
+
+func (s passwordResetSrv) Wait() {
+	s.pending.Wait()
+}
+
+func (s passwordResetSrv) forgot(ctx context.Context, username string) (err error) {
+	resUsers, err := s.userRepo.Gets(ctx, models.RepoGetUserModel{Username: username})
+	if err != nil {
+		return models.ErrUnexpected.Wrap(err)
+	}
+	// the mail is only sent to accounts that have an address
+	if len(resUsers) == 0 || resUsers[0].Email == "" {
+		return nil
+	}
+	user := resUsers[0]
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return models.ErrUnexpected.Wrap(err)
+	}
+
+	now := time.Now()
+	payload := models.RepoPasswordResetModel{
+		TokenHash: tokenHash,
+		UserId:    user.UserId,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.config.TokenTTL),
+	}
+	if err = s.passwordResetRepo.Create(ctx, payload); err != nil {
+		return models.ErrUnexpected.Wrap(err)
+	}
+
+	mail, err := s.resetMail(user, token, payload.ExpiresAt)
+	if err != nil {
+		return models.ErrUnexpected.Wrap(err)
+	}
+	if err = s.mailer.Send(ctx, mail); err != nil {
+		return models.ErrUnexpected.Wrap(err)
+	}
+
+	return nil
+}
+
+func (s passwordResetSrv) Reset(ctx context.Context, token, newPassword string) (err error) {
+	if token == "" {
+		return models.ErrResetTokenNotfound
+	}
+
+	// a missing password is refused before the token is touched
+	if newPassword == "" {
+		return models.ErrPasswordNotfound
+	}
+
+	resToken, err := s.passwordResetRepo.Consume(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, models.ErrResetTokenInvalid) {
+			return err
+		}
+		return models.ErrUnexpected.Wrap(err)
+	}
+	if !time.Now().Before(resToken.ExpiresAt) {
+		return models.ErrResetTokenInvalid
+	}
+
+	if err = s.userSrv.ResetPassword(ctx, resToken.UserId, newPassword); err != nil {
+		if errors.Is(err, models.ErrUserIdIsNotExist) {
+			return models.ErrResetTokenInvalid
+		}
+		// consuming first keeps the token single use under concurrent requests, it is put
+		// back when the password is refused so the user can pick another one
+		if err := s.passwordResetRepo.Create(ctx, resToken); err != nil {
+			return models.ErrUnexpected.Wrap(err)
+		}
+		return err
+	}
+
+	// the password is already set, tokens that outlive a failed revoke cannot undo that
+	// and stop working when they expire
+	_ = s.passwordResetRepo.RevokeUser(ctx, resToken.UserId)
+
+	return nil
+}
+
+func (s passwordResetSrv) resetMail(user models.RepoUserModel, token string, expiresAt time.Time) (mail mailers.MailModel, err error) {
+	reset := "Your reset token is " + token
+	if s.config.URL != "" {
+		link, err := url.Parse(s.config.URL)
+		if err != nil {
+			return mail, err
+		}
+		query := link.Query()
+		query.Set("token", token)
+		link.RawQuery = query.Encode()
+		reset = "Open " + link.String() + " to choose a new password."
+	}
+
+	body := strings.Builder{}
+	fmt.Fprintf(&body, "Someone asked to reset the password of %v.\n\n", user.Username)
+	fmt.Fprintf(&body, "%v\n\n", reset)
+	fmt.Fprintf(&body, "It works once, until %v. If you did not ask for it you can ignore this mail, your password stays as it is.\n", expiresAt.UTC().Format("2006-01-02 15:04 MST"))
+
+	return mailers.MailModel{To: user.Email, Subject: "Reset your password", Body: body.String()}, nil
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type passwordResetSrvMock struct {
+	mock.Mock
+}
+
+func NewPasswordResetSrvMock() passwordResetSrvMock {
+	return passwordResetSrvMock{}
+}
+
+func (m *passwordResetSrvMock) Forgot(ctx context.Context, username, clientIp string) (err error) {
+	args := m.Called(ctx, username, clientIp)
+	return args.Error(0)
+}
+
+func (m *passwordResetSrvMock) Wait() {
+	m.Called()
+}
+
+func (m *passwordResetSrvMock) Reset(ctx context.Context, token, newPassword string) (err error) {
+	args := m.Called(ctx, token, newPassword)
+	return args.Error(0)
+}
@@ -0,0 +1,326 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"hexagonal-gotest/mailers"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"hexagonal-gotest/services"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestForgot(t *testing.T) {
+	type args struct {
+		username string
+		clientIp string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		config  services.PasswordResetConfig
+		wantErr error
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "error1",
+			args:    args{username: ""},
+			wantErr: models.ErrUsernameNotfound,
+		},
+		{
+			name:    "unknown username",
+			args:    args{username: "nobody", clientIp: "0.0.0.0"},
+			config:  services.PasswordResetConfig{TokenTTL: time.Hour, MaxPending: 1},
+			wantErr: nil,
+		},
+		{
+			name:    "no email",
+			args:    args{username: "admin", clientIp: "0.0.0.0"},
+			config:  services.PasswordResetConfig{TokenTTL: time.Hour, MaxPending: 1},
+			wantErr: nil,
+		},
+		{
+			name:    "throttled",
+			args:    args{username: "admin", clientIp: "0.0.0.0"},
+			config:  services.PasswordResetConfig{TokenTTL: time.Hour, MaxPending: 1},
+			wantErr: models.ErrTooManyAttempts,
+		},
+		{
+			name:    "unexpected throttle",
+			args:    args{username: "admin", clientIp: "0.0.0.0"},
+			config:  services.PasswordResetConfig{TokenTTL: time.Hour, MaxPending: 1},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "no mail slot",
+			args:    args{username: "admin", clientIp: "0.0.0.0"},
+			config:  services.PasswordResetConfig{TokenTTL: time.Hour, MaxPending: 0},
+			wantErr: models.ErrTooManyAttempts,
+		},
+		{
+			name:    "unexpected gets user",
+			args:    args{username: "admin", clientIp: "0.0.0.0"},
+			config:  services.PasswordResetConfig{TokenTTL: time.Hour, MaxPending: 1},
+			wantErr: nil,
+		},
+		{
+			name:    "unexpected create token",
+			args:    args{username: "admin", clientIp: "0.0.0.0"},
+			config:  services.PasswordResetConfig{TokenTTL: time.Hour, MaxPending: 1},
+			wantErr: nil,
+		},
+		{
+			name:    "unexpected send mail",
+			args:    args{username: "admin", clientIp: "0.0.0.0"},
+			config:  services.PasswordResetConfig{TokenTTL: time.Hour, MaxPending: 1},
+			wantErr: nil,
+		},
+		{
+			name:    "success1",
+			args:    args{username: "Admin", clientIp: "0.0.0.0"},
+			config:  services.PasswordResetConfig{TokenTTL: time.Hour, SendTimeout: time.Second, MaxPending: 1},
+			wantErr: nil,
+		},
+		{
+			name:    "success without client ip",
+			args:    args{username: "admin", clientIp: ""},
+			config:  services.PasswordResetConfig{TokenTTL: time.Hour, MaxPending: 1},
+			wantErr: nil,
+		},
+		{
+			name:    "success with url",
+			args:    args{username: "admin", clientIp: "0.0.0.0"},
+			config:  services.PasswordResetConfig{TokenTTL: time.Hour, URL: "https://example.com/reset?lang=en", MaxPending: 1},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			passwordResetRepo := repositories.NewPasswordResetRepoMock()
+			userRepo := repositories.NewUserRepoMock()
+			userSrv := services.NewUserSrvMock()
+			throttleSrv := services.NewThrottleSrvMock()
+			mailer := mailers.NewMailerMock()
+
+			// mock Failure and Check throttle, under keys of their own
+			throttleIp := ""
+			if tt.args.clientIp != "" {
+				throttleIp = "forgot:" + tt.args.clientIp
+			}
+			throttleUsername := "forgot:" + usernamePolicy.Canonical(tt.args.username)
+			switch tt.name {
+			case "unexpected throttle":
+				throttleSrv.On("Failure", mock.Anything, throttleUsername, throttleIp).Return(models.ErrUnexpected.Wrap(errors.New("")))
+			default:
+				throttleSrv.On("Failure", mock.Anything, throttleUsername, throttleIp).Return(nil)
+			}
+			switch tt.name {
+			case "throttled":
+				throttleSrv.On("Check", mock.Anything, throttleUsername, throttleIp).Return(models.NewThrottledError(models.ErrTooManyAttempts, time.Second))
+			default:
+				throttleSrv.On("Check", mock.Anything, throttleUsername, throttleIp).Return(nil)
+			}
+
+			// mock Get user
+			switch tt.name {
+			case "unknown username":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{Username: "nobody"}).Return([]models.RepoUserModel{}, nil)
+			case "no email":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{Username: "admin"}).Return([]models.RepoUserModel{{UserId: owner.UserId, Username: "admin"}}, nil)
+			case "unexpected gets user":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{Username: "admin"}).Return(nil, errors.New(""))
+			default:
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{Username: "admin"}).Return([]models.RepoUserModel{{UserId: owner.UserId, Username: "admin", Email: "admin@example.com"}}, nil)
+			}
+
+			// mock Create token, the hash is kept to check the mailed token against it
+			tokenHash := ""
+			switch tt.name {
+			case "unexpected create token":
+				passwordResetRepo.On("Create", mock.Anything, mock.AnythingOfType("models.RepoPasswordResetModel")).Return(errors.New(""))
+			default:
+				passwordResetRepo.On("Create", mock.Anything, mock.MatchedBy(func(payload models.RepoPasswordResetModel) bool {
+					tokenHash = payload.TokenHash
+					return payload.UserId == owner.UserId && payload.ExpiresAt.Equal(payload.CreatedAt.Add(tt.config.TokenTTL))
+				})).Return(nil)
+			}
+
+			// mock Send mail, the request context is over by the time it runs
+			var sent mailers.MailModel
+			var sendCtxErr error
+			sendHasDeadline := false
+			sendCtx := mock.MatchedBy(func(ctx context.Context) bool {
+				sendCtxErr = ctx.Err()
+				_, sendHasDeadline = ctx.Deadline()
+				return true
+			})
+			switch tt.name {
+			case "unexpected send mail":
+				mailer.On("Send", mock.Anything, mock.AnythingOfType("mailers.MailModel")).Return(errors.New(""))
+			default:
+				mailer.On("Send", sendCtx, mock.MatchedBy(func(mail mailers.MailModel) bool {
+					sent = mail
+					return mail.To == "admin@example.com"
+				})).Return(nil)
+			}
+
+			passwordResetService := services.NewPasswordResetService(&passwordResetRepo, &userRepo, &userSrv, &throttleSrv, &mailer, usernamePolicy, tt.config)
+
+			// the request is answered before the mail goes out, its context can be over by then
+			reqCtx, cancel := context.WithCancel(ctx)
+
+			// -------------------- Act (กระทำ)--------------------
+			err := passwordResetService.Forgot(reqCtx, tt.args.username, tt.args.clientIp)
+			cancel()
+			passwordResetService.Wait()
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.ErrorIs(t, err, tt.wantErr)
+			switch tt.name {
+			case "error1":
+				throttleSrv.AssertNotCalled(t, "Check", mock.Anything, mock.Anything, mock.Anything)
+				userRepo.AssertNotCalled(t, "Gets", mock.Anything, mock.Anything)
+			case "throttled", "no mail slot":
+				throttleSrv.AssertCalled(t, "Failure", mock.Anything, throttleUsername, throttleIp)
+				userRepo.AssertNotCalled(t, "Gets", mock.Anything, mock.Anything)
+				mailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+			case "unexpected throttle":
+				throttleSrv.AssertNotCalled(t, "Check", mock.Anything, mock.Anything, mock.Anything)
+				userRepo.AssertNotCalled(t, "Gets", mock.Anything, mock.Anything)
+			case "unknown username", "no email", "unexpected gets user":
+				throttleSrv.AssertCalled(t, "Failure", mock.Anything, throttleUsername, throttleIp)
+				passwordResetRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				mailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+			case "unexpected create token":
+				mailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+			case "success1":
+				token := strings.TrimPrefix(strings.SplitN(sent.Body, "\n", 4)[2], "Your reset token is ")
+				assert.Equal(t, tokenHash, hashOf(token))
+				assert.NoError(t, sendCtxErr, "the mail outlives the request")
+				assert.True(t, sendHasDeadline)
+			case "success without client ip":
+				mailer.AssertCalled(t, "Send", mock.Anything, mock.AnythingOfType("mailers.MailModel"))
+			case "success with url":
+				assert.Contains(t, sent.Body, "https://example.com/reset?lang=en&token=")
+			}
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	type args struct {
+		token       string
+		newPassword string
+	}
+	resToken := models.RepoPasswordResetModel{TokenHash: hashOf("reset-token"), UserId: owner.UserId, ExpiresAt: time.Now().Add(time.Hour)}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "error1",
+			args:    args{token: "", newPassword: "admin01"},
+			wantErr: models.ErrResetTokenNotfound,
+		},
+		{
+			name:    "error2",
+			args:    args{token: "reset-token", newPassword: ""},
+			wantErr: models.ErrPasswordNotfound,
+		},
+		{
+			name:    "unknown token",
+			args:    args{token: "reset-token", newPassword: "admin01"},
+			wantErr: models.ErrResetTokenInvalid,
+		},
+		{
+			name:    "expired token",
+			args:    args{token: "reset-token", newPassword: "admin01"},
+			wantErr: models.ErrResetTokenInvalid,
+		},
+		{
+			name:    "deleted user",
+			args:    args{token: "reset-token", newPassword: "admin01"},
+			wantErr: models.ErrResetTokenInvalid,
+		},
+		{
+			name:    "password refused",
+			args:    args{token: "reset-token", newPassword: "123"},
+			wantErr: models.ErrPasswordTooShort,
+		},
+		{
+			name:    "unexpected consume token",
+			args:    args{token: "reset-token", newPassword: "admin01"},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "success1",
+			args:    args{token: "reset-token", newPassword: "admin01"},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			passwordResetRepo := repositories.NewPasswordResetRepoMock()
+			userRepo := repositories.NewUserRepoMock()
+			userSrv := services.NewUserSrvMock()
+			throttleSrv := services.NewThrottleSrvMock()
+			mailer := mailers.NewMailerMock()
+
+			// mock Consume token
+			switch tt.name {
+			case "unknown token":
+				passwordResetRepo.On("Consume", mock.Anything, hashOf(tt.args.token)).Return(models.RepoPasswordResetModel{}, models.ErrResetTokenInvalid)
+			case "expired token":
+				expired := resToken
+				expired.ExpiresAt = time.Now().Add(-time.Second)
+				passwordResetRepo.On("Consume", mock.Anything, hashOf(tt.args.token)).Return(expired, nil)
+			case "unexpected consume token":
+				passwordResetRepo.On("Consume", mock.Anything, hashOf(tt.args.token)).Return(models.RepoPasswordResetModel{}, errors.New(""))
+			default:
+				passwordResetRepo.On("Consume", mock.Anything, hashOf(tt.args.token)).Return(resToken, nil)
+			}
+
+			// mock Reset password
+			switch tt.name {
+			case "deleted user":
+				userSrv.On("ResetPassword", mock.Anything, owner.UserId, tt.args.newPassword).Return(models.ErrUserIdIsNotExist)
+			case "password refused":
+				userSrv.On("ResetPassword", mock.Anything, owner.UserId, tt.args.newPassword).Return(models.ErrPasswordTooShort)
+			default:
+				userSrv.On("ResetPassword", mock.Anything, owner.UserId, tt.args.newPassword).Return(nil)
+			}
+
+			// mock Create token, a refused password puts the token back
+			passwordResetRepo.On("Create", mock.Anything, resToken).Return(nil)
+
+			// mock Revoke user tokens
+			passwordResetRepo.On("RevokeUser", mock.Anything, owner.UserId).Return(nil)
+
+			passwordResetService := services.NewPasswordResetService(&passwordResetRepo, &userRepo, &userSrv, &throttleSrv, &mailer, usernamePolicy, services.PasswordResetConfig{TokenTTL: time.Hour})
+
+			// -------------------- Act (กระทำ)--------------------
+			err := passwordResetService.Reset(ctx, tt.args.token, tt.args.newPassword)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.ErrorIs(t, err, tt.wantErr)
+			switch tt.name {
+			case "password refused":
+				passwordResetRepo.AssertCalled(t, "Create", mock.Anything, resToken)
+			case "success1":
+				passwordResetRepo.AssertCalled(t, "RevokeUser", mock.Anything, owner.UserId)
+				passwordResetRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			case "error1", "error2":
+				passwordResetRepo.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
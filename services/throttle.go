@@ -0,0 +1,17 @@
+package services
+
+import (
+	"context"
+)
+
+// PORT throttle service
+type ThrottleService interface {
+	// Check fails with a models.ThrottledError when username or clientIp must wait before logging in again
+	Check(ctx context.Context, username, clientIp string) (err error)
+
+	// Failure counts a failed login against username and clientIp
+	Failure(ctx context.Context, username, clientIp string) (err error)
+
+	// Reset forgets the failures of username, which unlocks the account
+	Reset(ctx context.Context, username string) (err error)
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"time"
+)
+
+// ThrottleConfig sets how failed logins slow down an account and a client address.
+// A zero DelayAfter, LockoutThreshold or IPLimit turns that check off.
+type ThrottleConfig struct {
+	// Window is how long a failure counts towards delays and limits
+	Window time.Duration
+
+	// DelayAfter failures in the window make every further attempt wait BaseDelay,
+	// doubling per failure up to MaxDelay
+	DelayAfter int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+
+	// LockoutThreshold failures in the window lock the account for LockoutDuration
+	// from the last one
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+
+	// IPLimit failures in the window block the address whatever account it tries
+	IPLimit int
+}
+
+const (
+	throttleUserKeyPrefix = "user:"
+	throttleIpKeyPrefix   = "ip:"
+)
+
+type throttleSrv struct {
+	loginAttemptRepo repositories.LoginAttemptRepository
+	config           ThrottleConfig
+}
+
+func NewThrottleService(loginAttemptRepo repositories.LoginAttemptRepository, config ThrottleConfig) ThrottleService {
+	return throttleSrv{loginAttemptRepo, config}
+}
+
+func (s throttleSrv) Check(ctx context.Context, username, clientIp string) (err error) {
+	now := time.Now()
+
+	if clientIp != "" && s.config.IPLimit > 0 {
+		attempts, err := s.loginAttemptRepo.Gets(ctx, throttleIpKeyPrefix+clientIp, now.Add(-s.config.Window))
+		if err != nil {
+			return models.ErrUnexpected.Wrap(err)
+		}
+
+		// the address is let in again once enough of its failures leave the window
+		if len(attempts) >= s.config.IPLimit {
+			freedAt := attempts[len(attempts)-s.config.IPLimit].At.Add(s.config.Window)
+			return models.NewThrottledError(models.ErrTooManyAttempts, freedAt.Sub(now))
+		}
+	}
+
+	// failures older than the window still matter while the lockout they caused lasts
+	attempts, err := s.loginAttemptRepo.Gets(ctx, throttleUserKeyPrefix+username, now.Add(-s.config.Window-s.config.LockoutDuration))
+	if err != nil {
+		return models.ErrUnexpected.Wrap(err)
+	}
+	if len(attempts) == 0 {
+		return nil
+	}
+	last := attempts[len(attempts)-1].At
+
+	if s.config.LockoutThreshold > 0 && countSince(attempts, last.Add(-s.config.Window)) >= s.config.LockoutThreshold {
+		if unlockAt := last.Add(s.config.LockoutDuration); now.Before(unlockAt) {
+			return models.NewThrottledError(models.ErrAccountLocked, unlockAt.Sub(now))
+		}
+	}
+
+	if failures := countSince(attempts, now.Add(-s.config.Window)); s.config.DelayAfter > 0 && failures >= s.config.DelayAfter {
+		if nextAt := last.Add(s.delay(failures)); now.Before(nextAt) {
+			return models.NewThrottledError(models.ErrTooManyAttempts, nextAt.Sub(now))
+		}
+	}
+
+	return nil
+}
+
+func (s throttleSrv) Failure(ctx context.Context, username, clientIp string) (err error) {
+	now := time.Now()
+	expiresAt := now.Add(s.config.Window + s.config.LockoutDuration)
+
+	err = s.loginAttemptRepo.Create(ctx, models.RepoLoginAttemptModel{Key: throttleUserKeyPrefix + username, At: now, ExpiresAt: expiresAt})
+	if err != nil {
+		return models.ErrUnexpected.Wrap(err)
+	}
+
+	if clientIp != "" {
+		err = s.loginAttemptRepo.Create(ctx, models.RepoLoginAttemptModel{Key: throttleIpKeyPrefix + clientIp, At: now, ExpiresAt: now.Add(s.config.Window)})
+		if err != nil {
+			return models.ErrUnexpected.Wrap(err)
+		}
+	}
+
+	return
+}
+
+func (s throttleSrv) Reset(ctx context.Context, username string) (err error) {
+	err = s.loginAttemptRepo.Delete(ctx, throttleUserKeyPrefix+username)
+	if err != nil {
+		return models.ErrUnexpected.Wrap(err)
+	}
+
+	return
+}
+
+// delay doubles from BaseDelay with every failure past DelayAfter
+func (s throttleSrv) delay(failures int) time.Duration {
+	delay := s.config.BaseDelay
+	for i := s.config.DelayAfter; i < failures && delay < s.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.config.MaxDelay {
+		delay = s.config.MaxDelay
+	}
+	return delay
+}
+
+func countSince(attempts []models.RepoLoginAttemptModel, since time.Time) (count int) {
+	for _, attempt := range attempts {
+		if !attempt.At.Before(since) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type throttleSrvMock struct {
+	mock.Mock
+}
+
+func NewThrottleSrvMock() throttleSrvMock {
+	return throttleSrvMock{}
+}
+
+func (m *throttleSrvMock) Check(ctx context.Context, username, clientIp string) (err error) {
+	args := m.Called(ctx, username, clientIp)
+	return args.Error(0)
+}
+
+func (m *throttleSrvMock) Failure(ctx context.Context, username, clientIp string) (err error) {
+	args := m.Called(ctx, username, clientIp)
+	return args.Error(0)
+}
+
+func (m *throttleSrvMock) Reset(ctx context.Context, username string) (err error) {
+	args := m.Called(ctx, username)
+	return args.Error(0)
+}
@@ -0,0 +1,211 @@
+package services_test
+
+import (
+	"errors"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"hexagonal-gotest/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var throttleConfig = services.ThrottleConfig{
+	Window:           10 * time.Minute,
+	DelayAfter:       3,
+	BaseDelay:        time.Second,
+	MaxDelay:         4 * time.Second,
+	LockoutThreshold: 5,
+	LockoutDuration:  10 * time.Minute,
+	IPLimit:          20,
+}
+
+// failuresAgo returns one failed attempt per offset, oldest first
+func failuresAgo(key string, offsets ...time.Duration) []models.RepoLoginAttemptModel {
+	now := time.Now()
+	attempts := []models.RepoLoginAttemptModel{}
+	for _, offset := range offsets {
+		attempts = append(attempts, models.RepoLoginAttemptModel{Key: key, At: now.Add(-offset), ExpiresAt: now.Add(time.Hour)})
+	}
+	return attempts
+}
+
+func TestThrottleCheck(t *testing.T) {
+	manyIpFailures := make([]time.Duration, 20)
+	for i := range manyIpFailures {
+		manyIpFailures[i] = time.Duration(9-i/4) * time.Minute
+	}
+	tests := []struct {
+		name           string
+		wantErr        error
+		wantRetryAfter time.Duration
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "unexpected gets ip",
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "unexpected gets user",
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:           "ip limit",
+			wantErr:        models.ErrTooManyAttempts,
+			wantRetryAfter: time.Minute,
+		},
+		{
+			name:           "locked",
+			wantErr:        models.ErrAccountLocked,
+			wantRetryAfter: 9 * time.Minute,
+		},
+		{
+			name:    "lockout expired",
+			wantErr: nil,
+		},
+		{
+			name:           "delayed",
+			wantErr:        models.ErrTooManyAttempts,
+			wantRetryAfter: 2 * time.Second,
+		},
+		{
+			name:    "delay elapsed",
+			wantErr: nil,
+		},
+		{
+			name:    "success no failures",
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			loginAttemptRepo := repositories.NewLoginAttemptRepoMock()
+
+			// mock Gets ip
+			switch tt.name {
+			case "unexpected gets ip":
+				loginAttemptRepo.On("Gets", mock.Anything, "ip:10.0.0.1", mock.Anything).Return(nil, errors.New(""))
+			case "ip limit":
+				loginAttemptRepo.On("Gets", mock.Anything, "ip:10.0.0.1", mock.Anything).Return(failuresAgo("ip:10.0.0.1", manyIpFailures...), nil)
+			default:
+				loginAttemptRepo.On("Gets", mock.Anything, "ip:10.0.0.1", mock.Anything).Return(nil, nil)
+			}
+
+			// mock Gets user
+			switch tt.name {
+			case "unexpected gets user":
+				loginAttemptRepo.On("Gets", mock.Anything, "user:admin", mock.Anything).Return(nil, errors.New(""))
+			case "locked":
+				loginAttemptRepo.On("Gets", mock.Anything, "user:admin", mock.Anything).Return(failuresAgo("user:admin", 5*time.Minute, 4*time.Minute, 3*time.Minute, 2*time.Minute, time.Minute), nil)
+			case "lockout expired":
+				loginAttemptRepo.On("Gets", mock.Anything, "user:admin", mock.Anything).Return(failuresAgo("user:admin", 15*time.Minute, 14*time.Minute, 13*time.Minute, 12*time.Minute, 11*time.Minute), nil)
+			case "delayed":
+				loginAttemptRepo.On("Gets", mock.Anything, "user:admin", mock.Anything).Return(failuresAgo("user:admin", 3*time.Minute, 2*time.Minute, time.Minute, 0), nil)
+			case "delay elapsed":
+				loginAttemptRepo.On("Gets", mock.Anything, "user:admin", mock.Anything).Return(failuresAgo("user:admin", 3*time.Minute, 2*time.Minute, time.Minute, 5*time.Second), nil)
+			default:
+				loginAttemptRepo.On("Gets", mock.Anything, "user:admin", mock.Anything).Return(nil, nil)
+			}
+
+			throttleSrv := services.NewThrottleService(&loginAttemptRepo, throttleConfig)
+
+			// -------------------- Act (กระทำ)--------------------
+			err := throttleSrv.Check(ctx, "admin", "10.0.0.1")
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.ErrorIs(t, err, tt.wantErr)
+			throttledErr := &models.ThrottledError{}
+			if errors.As(err, &throttledErr) {
+				assert.InDelta(t, tt.wantRetryAfter, throttledErr.RetryAfter, float64(time.Second))
+			} else {
+				assert.Zero(t, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestThrottleDelayGrows(t *testing.T) {
+	// ------------------- Arrange (เตรียมของ) --------------------
+	loginAttemptRepo := repositories.NewLoginAttemptMemoryRepository()
+	throttleSrv := services.NewThrottleService(loginAttemptRepo, throttleConfig)
+
+	// -------------------- Act (กระทำ)--------------------
+	retryAfters := []time.Duration{}
+	for i := 0; i < 5; i++ {
+		throttleSrv.Failure(ctx, "admin", "")
+
+		throttledErr := &models.ThrottledError{}
+		if errors.As(throttleSrv.Check(ctx, "admin", ""), &throttledErr) {
+			retryAfters = append(retryAfters, throttledErr.RetryAfter.Round(time.Second))
+		} else {
+			retryAfters = append(retryAfters, 0)
+		}
+	}
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.Equal(t, []time.Duration{0, 0, time.Second, 2 * time.Second, throttleConfig.LockoutDuration}, retryAfters)
+	assert.ErrorIs(t, throttleSrv.Check(ctx, "admin", ""), models.ErrAccountLocked)
+
+	assert.NoError(t, throttleSrv.Reset(ctx, "admin"))
+	assert.NoError(t, throttleSrv.Check(ctx, "admin", ""))
+}
+
+func TestThrottleFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		clientIp string
+		wantErr  error
+	}{
+		// TODO: Add test cases.
+		{
+			name:     "unexpected create",
+			clientIp: "10.0.0.1",
+			wantErr:  models.ErrUnexpected,
+		},
+		{
+			name:     "success without ip",
+			clientIp: "",
+			wantErr:  nil,
+		},
+		{
+			name:     "success1",
+			clientIp: "10.0.0.1",
+			wantErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			loginAttemptRepo := repositories.NewLoginAttemptRepoMock()
+
+			// mock Create
+			switch tt.name {
+			case "unexpected create":
+				loginAttemptRepo.On("Create", mock.Anything, mock.Anything).Return(errors.New(""))
+			default:
+				loginAttemptRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			}
+
+			throttleSrv := services.NewThrottleService(&loginAttemptRepo, throttleConfig)
+
+			// -------------------- Act (กระทำ)--------------------
+			err := throttleSrv.Failure(ctx, "admin", tt.clientIp)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				loginAttemptRepo.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(payload models.RepoLoginAttemptModel) bool {
+					return payload.Key == "user:admin" && payload.ExpiresAt.After(payload.At)
+				}))
+				if tt.clientIp == "" {
+					loginAttemptRepo.AssertNumberOfCalls(t, "Create", 1)
+				} else {
+					loginAttemptRepo.AssertNumberOfCalls(t, "Create", 2)
+				}
+			}
+		})
+	}
+}
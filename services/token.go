@@ -0,0 +1,24 @@
+package services
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/utils"
+)
+
+// PORT token service
+type TokenService interface {
+	Issue(ctx context.Context, principal models.SrvPrincipalModel) (token, refreshToken string, err error)
+
+	Refresh(ctx context.Context, refreshToken string) (token, newRefreshToken string, err error)
+
+	// Verify validates the token, consults the revocation list and checks the token is
+	// from the current password of its user
+	Verify(ctx context.Context, token string) (tokenData utils.TokenDataModel, err error)
+
+	// Logout revokes the access token and, when given, the refresh token family
+	Logout(ctx context.Context, tokenData utils.TokenDataModel, refreshToken string) (err error)
+
+	// LogoutAll revokes every access and refresh token of the user
+	LogoutAll(ctx context.Context, userId string) (err error)
+}
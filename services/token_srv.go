@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"hexagonal-gotest/utils"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type tokenSrv struct {
+	userRepo         repositories.UserRepository
+	refreshTokenRepo repositories.RefreshTokenRepository
+	revokedTokenRepo repositories.RevokedTokenRepository
+	jwt              utils.JWT
+	refreshTokenTTL  time.Duration
+}
+
+func NewTokenService(userRepo repositories.UserRepository, refreshTokenRepo repositories.RefreshTokenRepository, revokedTokenRepo repositories.RevokedTokenRepository, jwt utils.JWT, refreshTokenTTL time.Duration) TokenService {
+	return tokenSrv{userRepo, refreshTokenRepo, revokedTokenRepo, jwt, refreshTokenTTL}
+}
+
+func (s tokenSrv) Issue(ctx context.Context, principal models.SrvPrincipalModel) (token, refreshToken string, err error) {
+	return s.issue(ctx, principal, uuid.NewString())
+}
+
+func (s tokenSrv) Refresh(ctx context.Context, refreshToken string) (token, newRefreshToken string, err error) {
+	if refreshToken == "" {
+		return token, newRefreshToken, models.ErrRefreshTokenNotfound
+	}
+
+	tokenHash := hashToken(refreshToken)
+
+	resToken, err := s.refreshTokenRepo.Get(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, models.ErrRefreshTokenIsNotExist) {
+			return token, newRefreshToken, models.ErrUnauthorized
+		}
+
+		return token, newRefreshToken, models.ErrUnexpected.Wrap(err)
+	}
+
+	if resToken.Revoked || time.Now().After(resToken.ExpiresAt) {
+		return token, newRefreshToken, models.ErrUnauthorized
+	}
+
+	// a used token presented again means it leaked, kill every token descended from the same login
+	if resToken.UsedAt != nil {
+		return token, newRefreshToken, s.revokeFamily(ctx, resToken.FamilyId)
+	}
+
+	err = s.refreshTokenRepo.MarkUsed(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, models.ErrRefreshTokenIsUsed) {
+			return token, newRefreshToken, s.revokeFamily(ctx, resToken.FamilyId)
+		}
+
+		return token, newRefreshToken, models.ErrUnexpected.Wrap(err)
+	}
+
+	resUsers, err := s.userRepo.Gets(ctx, models.RepoGetUserModel{UserId: resToken.UserId})
+	if err != nil {
+		return token, newRefreshToken, models.ErrUnexpected.Wrap(err)
+	}
+	// the password changed since the login this token descends from
+	if len(resUsers) == 0 || resUsers[0].CredentialVersion != resToken.CredentialVersion {
+		return token, newRefreshToken, s.revokeFamily(ctx, resToken.FamilyId)
+	}
+
+	return s.issue(ctx, models.SrvPrincipalModel{UserId: resUsers[0].UserId, Username: resUsers[0].Username, Role: resUsers[0].Role, CredentialVersion: resUsers[0].CredentialVersion}, resToken.FamilyId)
+}
+
+func (s tokenSrv) Verify(ctx context.Context, token string) (tokenData utils.TokenDataModel, err error) {
+	tokenData, err = s.jwt.Verify(token)
+	if err != nil {
+		return utils.TokenDataModel{}, models.ErrUnauthorized
+	}
+
+	revoked, err := s.revokedTokenRepo.IsTokenRevoked(ctx, tokenData.ID)
+	if err != nil {
+		return utils.TokenDataModel{}, models.ErrUnexpected.Wrap(err)
+	}
+	if revoked {
+		return utils.TokenDataModel{}, models.ErrUnauthorized
+	}
+
+	revokedAt, err := s.revokedTokenRepo.UserRevokedAt(ctx, tokenData.UserId)
+	if err != nil {
+		return utils.TokenDataModel{}, models.ErrUnexpected.Wrap(err)
+	}
+
+	// iat only has second precision, a token from the same second as the logout is treated as revoked
+	if !revokedAt.IsZero() && (tokenData.IssuedAt == nil || !tokenData.IssuedAt.After(revokedAt.Truncate(time.Second))) {
+		return utils.TokenDataModel{}, models.ErrUnauthorized
+	}
+
+	// a password change invalidates every token issued before it, tokens of deleted users go with them
+	resUsers, err := s.userRepo.Gets(ctx, models.RepoGetUserModel{UserId: tokenData.UserId})
+	if err != nil {
+		return utils.TokenDataModel{}, models.ErrUnexpected.Wrap(err)
+	}
+	if len(resUsers) == 0 || resUsers[0].CredentialVersion != tokenData.CredentialVersion {
+		return utils.TokenDataModel{}, models.ErrUnauthorized
+	}
+
+	return tokenData, nil
+}
+
+func (s tokenSrv) Logout(ctx context.Context, tokenData utils.TokenDataModel, refreshToken string) (err error) {
+	if tokenData.ID == "" || tokenData.ExpiresAt == nil {
+		return models.ErrUnauthorized
+	}
+
+	err = s.revokedTokenRepo.Revoke(ctx, models.RepoRevokedTokenModel{
+		Jti:       tokenData.ID,
+		UserId:    tokenData.UserId,
+		RevokedAt: time.Now(),
+		ExpiresAt: tokenData.ExpiresAt.Time,
+	})
+	if err != nil {
+		return models.ErrUnexpected.Wrap(err)
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	resToken, err := s.refreshTokenRepo.Get(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, models.ErrRefreshTokenIsNotExist) {
+			return nil
+		}
+
+		return models.ErrUnexpected.Wrap(err)
+	}
+
+	// never let a caller revoke someone else's session
+	if resToken.UserId != tokenData.UserId {
+		return nil
+	}
+
+	if err = s.refreshTokenRepo.RevokeFamily(ctx, resToken.FamilyId); err != nil {
+		return models.ErrUnexpected.Wrap(err)
+	}
+
+	return nil
+}
+
+func (s tokenSrv) LogoutAll(ctx context.Context, userId string) (err error) {
+	if userId == "" {
+		return models.ErrUnauthorized
+	}
+
+	now := time.Now()
+	err = s.revokedTokenRepo.Revoke(ctx, models.RepoRevokedTokenModel{
+		UserId:    userId,
+		RevokedAt: now,
+		ExpiresAt: now.Add(s.jwt.Lifetime()),
+	})
+	if err != nil {
+		return models.ErrUnexpected.Wrap(err)
+	}
+
+	if err = s.refreshTokenRepo.RevokeUser(ctx, userId); err != nil {
+		return models.ErrUnexpected.Wrap(err)
+	}
+
+	return nil
+}
+
+func (s tokenSrv) issue(ctx context.Context, principal models.SrvPrincipalModel, familyId string) (token, refreshToken string, err error) {
+	token, err = s.jwt.Sign(utils.TokenDataModel{UserId: principal.UserId, Username: principal.Username, Role: principal.Role, CredentialVersion: principal.CredentialVersion})
+	if err != nil {
+		return "", "", models.ErrUnexpected.Wrap(err)
+	}
+
+	refreshToken, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return "", "", models.ErrUnexpected.Wrap(err)
+	}
+
+	err = s.refreshTokenRepo.Create(ctx, models.RepoRefreshTokenModel{
+		TokenHash:         tokenHash,
+		FamilyId:          familyId,
+		UserId:            principal.UserId,
+		CredentialVersion: principal.CredentialVersion,
+		ExpiresAt:         time.Now().Add(s.refreshTokenTTL),
+	})
+	if err != nil {
+		return "", "", models.ErrUnexpected.Wrap(err)
+	}
+
+	return token, refreshToken, nil
+}
+
+func (s tokenSrv) revokeFamily(ctx context.Context, familyId string) (err error) {
+	if err = s.refreshTokenRepo.RevokeFamily(ctx, familyId); err != nil {
+		return models.ErrUnexpected.Wrap(err)
+	}
+
+	return models.ErrUnauthorized
+}
+
+// newOpaqueToken returns a random token for the client and the hash to persist
+func newOpaqueToken() (token, tokenHash string, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+
+	return token, hashToken(token), nil
+}
+
+// hashToken only the hash is persisted so a database leak cannot be replayed
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/utils"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type tokenSrvMock struct {
+	mock.Mock
+}
+
+func NewTokenSrvMock() tokenSrvMock {
+	return tokenSrvMock{}
+}
+
+func (m *tokenSrvMock) Issue(ctx context.Context, principal models.SrvPrincipalModel) (token, refreshToken string, err error) {
+	args := m.Called(ctx, principal)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *tokenSrvMock) Refresh(ctx context.Context, refreshToken string) (token, newRefreshToken string, err error) {
+	args := m.Called(ctx, refreshToken)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *tokenSrvMock) Verify(ctx context.Context, token string) (tokenData utils.TokenDataModel, err error) {
+	args := m.Called(ctx, token)
+	res, _ := args.Get(0).(utils.TokenDataModel)
+	return res, args.Error(1)
+}
+
+func (m *tokenSrvMock) Logout(ctx context.Context, tokenData utils.TokenDataModel, refreshToken string) (err error) {
+	args := m.Called(ctx, tokenData, refreshToken)
+	return args.Error(0)
+}
+
+func (m *tokenSrvMock) LogoutAll(ctx context.Context, userId string) (err error) {
+	args := m.Called(ctx, userId)
+	return args.Error(0)
+}
@@ -0,0 +1,526 @@
+package services_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hexagonal-gotest/models"
+	"hexagonal-gotest/repositories"
+	"hexagonal-gotest/services"
+	"hexagonal-gotest/utils"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testJWT = func() utils.JWT {
+	key, _ := utils.NewHMACKey("test", []byte("hexagonal-gotest-secret-for-tests"))
+	jwt, _ := utils.NewJWT(utils.JWTConfig{Issuer: "hexagonal-gotest", Lifetime: time.Hour}, key)
+	return jwt
+}()
+
+func hashOf(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestIssue(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr error
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "unexpected create refresh token",
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "success1",
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userRepo := repositories.NewUserRepoMock()
+			refreshTokenRepo := repositories.NewRefreshTokenRepoMock()
+			revokedTokenRepo := repositories.NewRevokedTokenRepoMock()
+
+			// mock Create refresh token
+			switch tt.name {
+			case "unexpected create refresh token":
+				refreshTokenRepo.On("Create", mock.Anything, mock.AnythingOfType("models.RepoRefreshTokenModel")).Return(errors.New(""))
+			default:
+				refreshTokenRepo.On("Create", mock.Anything, mock.AnythingOfType("models.RepoRefreshTokenModel")).Return(nil)
+			}
+
+			tokenSrv := services.NewTokenService(&userRepo, &refreshTokenRepo, &revokedTokenRepo, testJWT, time.Hour)
+
+			// -------------------- Act (กระทำ)--------------------
+			gotToken, gotRefreshToken, err := tokenSrv.Issue(ctx, owner)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				result, err := testJWT.Verify(gotToken)
+				assert.NoError(t, err)
+				assert.Equal(t, owner.UserId, result.UserId)
+				assert.Equal(t, owner.Role, result.Role)
+
+				refreshTokenRepo.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(payload models.RepoRefreshTokenModel) bool {
+					return payload.TokenHash == hashOf(gotRefreshToken) && payload.UserId == owner.UserId && payload.FamilyId != "" && payload.ExpiresAt.After(time.Now())
+				}))
+			}
+		})
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	type args struct {
+		refreshToken string
+	}
+	used := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name        string
+		args        args
+		wantErr     error
+		wantRevoked bool
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "error1",
+			args:    args{refreshToken: ""},
+			wantErr: models.ErrRefreshTokenNotfound,
+		},
+		{
+			name:    "error2",
+			args:    args{refreshToken: "refresh-token"},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:    "revoked",
+			args:    args{refreshToken: "refresh-token"},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:    "expired",
+			args:    args{refreshToken: "refresh-token"},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:        "reused",
+			args:        args{refreshToken: "refresh-token"},
+			wantErr:     models.ErrUnauthorized,
+			wantRevoked: true,
+		},
+		{
+			name:        "reused concurrently",
+			args:        args{refreshToken: "refresh-token"},
+			wantErr:     models.ErrUnauthorized,
+			wantRevoked: true,
+		},
+		{
+			name:        "user deleted",
+			args:        args{refreshToken: "refresh-token"},
+			wantErr:     models.ErrUnauthorized,
+			wantRevoked: true,
+		},
+		{
+			name:        "password changed",
+			args:        args{refreshToken: "refresh-token"},
+			wantErr:     models.ErrUnauthorized,
+			wantRevoked: true,
+		},
+		{
+			name:    "unexpected get refresh token",
+			args:    args{refreshToken: "refresh-token"},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "unexpected mark used",
+			args:    args{refreshToken: "refresh-token"},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "success1",
+			args:    args{refreshToken: "refresh-token"},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userRepo := repositories.NewUserRepoMock()
+			refreshTokenRepo := repositories.NewRefreshTokenRepoMock()
+			revokedTokenRepo := repositories.NewRevokedTokenRepoMock()
+
+			stored := models.RepoRefreshTokenModel{
+				TokenHash:         hashOf(tt.args.refreshToken),
+				FamilyId:          "family-1",
+				UserId:            owner.UserId,
+				CredentialVersion: 1,
+				ExpiresAt:         time.Now().Add(time.Hour),
+			}
+
+			// mock Get refresh token
+			switch tt.name {
+			case "error2":
+				refreshTokenRepo.On("Get", mock.Anything, stored.TokenHash).Return(nil, models.ErrRefreshTokenIsNotExist)
+			case "unexpected get refresh token":
+				refreshTokenRepo.On("Get", mock.Anything, stored.TokenHash).Return(nil, errors.New(""))
+			case "revoked":
+				stored.Revoked = true
+				refreshTokenRepo.On("Get", mock.Anything, stored.TokenHash).Return(stored, nil)
+			case "expired":
+				stored.ExpiresAt = time.Now().Add(-time.Minute)
+				refreshTokenRepo.On("Get", mock.Anything, stored.TokenHash).Return(stored, nil)
+			case "reused":
+				stored.UsedAt = &used
+				refreshTokenRepo.On("Get", mock.Anything, stored.TokenHash).Return(stored, nil)
+			default:
+				refreshTokenRepo.On("Get", mock.Anything, stored.TokenHash).Return(stored, nil)
+			}
+
+			// mock MarkUsed refresh token
+			switch tt.name {
+			case "reused concurrently":
+				refreshTokenRepo.On("MarkUsed", mock.Anything, stored.TokenHash).Return(models.ErrRefreshTokenIsUsed)
+			case "unexpected mark used":
+				refreshTokenRepo.On("MarkUsed", mock.Anything, stored.TokenHash).Return(errors.New(""))
+			default:
+				refreshTokenRepo.On("MarkUsed", mock.Anything, stored.TokenHash).Return(nil)
+			}
+
+			refreshTokenRepo.On("RevokeFamily", mock.Anything, "family-1").Return(nil)
+			refreshTokenRepo.On("Create", mock.Anything, mock.AnythingOfType("models.RepoRefreshTokenModel")).Return(nil)
+
+			// mock Gets user
+			switch tt.name {
+			case "user deleted":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: owner.UserId}).Return([]models.RepoUserModel{}, nil)
+			case "password changed":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: owner.UserId}).Return([]models.RepoUserModel{
+					{UserId: owner.UserId, Username: owner.Username, Role: owner.Role, CredentialVersion: 2},
+				}, nil)
+			default:
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: owner.UserId}).Return([]models.RepoUserModel{
+					{UserId: owner.UserId, Username: owner.Username, Role: owner.Role, CredentialVersion: 1},
+				}, nil)
+			}
+
+			tokenSrv := services.NewTokenService(&userRepo, &refreshTokenRepo, &revokedTokenRepo, testJWT, time.Hour)
+
+			// -------------------- Act (กระทำ)--------------------
+			gotToken, gotRefreshToken, err := tokenSrv.Refresh(ctx, tt.args.refreshToken)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantRevoked {
+				refreshTokenRepo.AssertCalled(t, "RevokeFamily", mock.Anything, "family-1")
+			} else {
+				refreshTokenRepo.AssertNotCalled(t, "RevokeFamily", mock.Anything, mock.Anything)
+			}
+			if tt.wantErr == nil {
+				result, err := testJWT.Verify(gotToken)
+				assert.NoError(t, err)
+				assert.Equal(t, owner.Username, result.Username)
+				assert.Equal(t, 1, result.CredentialVersion)
+
+				assert.NotEqual(t, tt.args.refreshToken, gotRefreshToken)
+				refreshTokenRepo.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(payload models.RepoRefreshTokenModel) bool {
+					return payload.TokenHash == hashOf(gotRefreshToken) && payload.FamilyId == "family-1" && payload.CredentialVersion == 1
+				}))
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	type args struct {
+		token string
+	}
+	token, _ := testJWT.Sign(utils.TokenDataModel{UserId: owner.UserId, Username: owner.Username, Role: owner.Role})
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "error1",
+			args:    args{token: "invalid-token"},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:    "revoked token",
+			args:    args{token: token},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:    "revoked user",
+			args:    args{token: token},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:    "unexpected is token revoked",
+			args:    args{token: token},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "unexpected user revoked at",
+			args:    args{token: token},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "success1",
+			args:    args{token: token},
+			wantErr: nil,
+		},
+		{
+			name:    "success issued after revoke",
+			args:    args{token: token},
+			wantErr: nil,
+		},
+		{
+			name:    "password changed",
+			args:    args{token: token},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:    "user deleted",
+			args:    args{token: token},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:    "unexpected gets user",
+			args:    args{token: token},
+			wantErr: models.ErrUnexpected,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userRepo := repositories.NewUserRepoMock()
+			refreshTokenRepo := repositories.NewRefreshTokenRepoMock()
+			revokedTokenRepo := repositories.NewRevokedTokenRepoMock()
+
+			// mock IsTokenRevoked
+			switch tt.name {
+			case "revoked token":
+				revokedTokenRepo.On("IsTokenRevoked", mock.Anything, mock.AnythingOfType("string")).Return(true, nil)
+			case "unexpected is token revoked":
+				revokedTokenRepo.On("IsTokenRevoked", mock.Anything, mock.AnythingOfType("string")).Return(false, errors.New(""))
+			default:
+				revokedTokenRepo.On("IsTokenRevoked", mock.Anything, mock.AnythingOfType("string")).Return(false, nil)
+			}
+
+			// mock UserRevokedAt
+			switch tt.name {
+			case "revoked user":
+				revokedTokenRepo.On("UserRevokedAt", mock.Anything, owner.UserId).Return(time.Now(), nil)
+			case "unexpected user revoked at":
+				revokedTokenRepo.On("UserRevokedAt", mock.Anything, owner.UserId).Return(nil, errors.New(""))
+			case "success issued after revoke":
+				revokedTokenRepo.On("UserRevokedAt", mock.Anything, owner.UserId).Return(time.Now().Add(-time.Hour), nil)
+			default:
+				revokedTokenRepo.On("UserRevokedAt", mock.Anything, owner.UserId).Return(time.Time{}, nil)
+			}
+
+			// mock Gets user
+			switch tt.name {
+			case "password changed":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: owner.UserId}).Return([]models.RepoUserModel{
+					{UserId: owner.UserId, CredentialVersion: 1},
+				}, nil)
+			case "user deleted":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: owner.UserId}).Return([]models.RepoUserModel{}, nil)
+			case "unexpected gets user":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: owner.UserId}).Return(nil, errors.New(""))
+			default:
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: owner.UserId}).Return([]models.RepoUserModel{
+					{UserId: owner.UserId},
+				}, nil)
+			}
+
+			tokenSrv := services.NewTokenService(&userRepo, &refreshTokenRepo, &revokedTokenRepo, testJWT, time.Hour)
+
+			// -------------------- Act (กระทำ)--------------------
+			gotTokenData, err := tokenSrv.Verify(ctx, tt.args.token)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, owner.UserId, gotTokenData.UserId)
+				revokedTokenRepo.AssertCalled(t, "IsTokenRevoked", mock.Anything, gotTokenData.ID)
+			}
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	type args struct {
+		tokenData    utils.TokenDataModel
+		refreshToken string
+	}
+	tokenData := utils.TokenDataModel{UserId: owner.UserId, Username: owner.Username}
+	tokenData.ID = "jti-1"
+	tokenData.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+	tests := []struct {
+		name        string
+		args        args
+		wantErr     error
+		wantRevoked bool
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "error1",
+			args:    args{tokenData: utils.TokenDataModel{UserId: owner.UserId}},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:    "unexpected revoke",
+			args:    args{tokenData: tokenData},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "other user refresh token",
+			args:    args{tokenData: tokenData, refreshToken: "refresh-token"},
+			wantErr: nil,
+		},
+		{
+			name:    "unknown refresh token",
+			args:    args{tokenData: tokenData, refreshToken: "refresh-token"},
+			wantErr: nil,
+		},
+		{
+			name:    "success1",
+			args:    args{tokenData: tokenData},
+			wantErr: nil,
+		},
+		{
+			name:        "success with refresh token",
+			args:        args{tokenData: tokenData, refreshToken: "refresh-token"},
+			wantErr:     nil,
+			wantRevoked: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userRepo := repositories.NewUserRepoMock()
+			refreshTokenRepo := repositories.NewRefreshTokenRepoMock()
+			revokedTokenRepo := repositories.NewRevokedTokenRepoMock()
+
+			// mock Revoke
+			switch tt.name {
+			case "unexpected revoke":
+				revokedTokenRepo.On("Revoke", mock.Anything, mock.AnythingOfType("models.RepoRevokedTokenModel")).Return(errors.New(""))
+			default:
+				revokedTokenRepo.On("Revoke", mock.Anything, mock.AnythingOfType("models.RepoRevokedTokenModel")).Return(nil)
+			}
+
+			// mock Get refresh token
+			switch tt.name {
+			case "other user refresh token":
+				refreshTokenRepo.On("Get", mock.Anything, hashOf(tt.args.refreshToken)).Return(models.RepoRefreshTokenModel{FamilyId: "family-1", UserId: "other"}, nil)
+			case "unknown refresh token":
+				refreshTokenRepo.On("Get", mock.Anything, hashOf(tt.args.refreshToken)).Return(nil, models.ErrRefreshTokenIsNotExist)
+			default:
+				refreshTokenRepo.On("Get", mock.Anything, hashOf(tt.args.refreshToken)).Return(models.RepoRefreshTokenModel{FamilyId: "family-1", UserId: owner.UserId}, nil)
+			}
+			refreshTokenRepo.On("RevokeFamily", mock.Anything, "family-1").Return(nil)
+
+			tokenSrv := services.NewTokenService(&userRepo, &refreshTokenRepo, &revokedTokenRepo, testJWT, time.Hour)
+
+			// -------------------- Act (กระทำ)--------------------
+			err := tokenSrv.Logout(ctx, tt.args.tokenData, tt.args.refreshToken)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				revokedTokenRepo.AssertCalled(t, "Revoke", mock.Anything, mock.MatchedBy(func(payload models.RepoRevokedTokenModel) bool {
+					return payload.Jti == "jti-1" && payload.ExpiresAt.Equal(tokenData.ExpiresAt.Time)
+				}))
+			}
+			if tt.wantRevoked {
+				refreshTokenRepo.AssertCalled(t, "RevokeFamily", mock.Anything, "family-1")
+			} else {
+				refreshTokenRepo.AssertNotCalled(t, "RevokeFamily", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestLogoutAll(t *testing.T) {
+	type args struct {
+		userId string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "error1",
+			args:    args{userId: ""},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:    "unexpected revoke",
+			args:    args{userId: owner.UserId},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "unexpected revoke refresh tokens",
+			args:    args{userId: owner.UserId},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "success1",
+			args:    args{userId: owner.UserId},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userRepo := repositories.NewUserRepoMock()
+			refreshTokenRepo := repositories.NewRefreshTokenRepoMock()
+			revokedTokenRepo := repositories.NewRevokedTokenRepoMock()
+
+			// mock Revoke
+			switch tt.name {
+			case "unexpected revoke":
+				revokedTokenRepo.On("Revoke", mock.Anything, mock.AnythingOfType("models.RepoRevokedTokenModel")).Return(errors.New(""))
+			default:
+				revokedTokenRepo.On("Revoke", mock.Anything, mock.AnythingOfType("models.RepoRevokedTokenModel")).Return(nil)
+			}
+
+			// mock RevokeUser
+			switch tt.name {
+			case "unexpected revoke refresh tokens":
+				refreshTokenRepo.On("RevokeUser", mock.Anything, tt.args.userId).Return(errors.New(""))
+			default:
+				refreshTokenRepo.On("RevokeUser", mock.Anything, tt.args.userId).Return(nil)
+			}
+
+			tokenSrv := services.NewTokenService(&userRepo, &refreshTokenRepo, &revokedTokenRepo, testJWT, time.Hour)
+
+			// -------------------- Act (กระทำ)--------------------
+			err := tokenSrv.LogoutAll(ctx, tt.args.userId)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				revokedTokenRepo.AssertCalled(t, "Revoke", mock.Anything, mock.MatchedBy(func(payload models.RepoRevokedTokenModel) bool {
+					return payload.Jti == "" && payload.UserId == tt.args.userId && payload.ExpiresAt.After(payload.RevokedAt)
+				}))
+				refreshTokenRepo.AssertCalled(t, "RevokeUser", mock.Anything, tt.args.userId)
+			}
+		})
+	}
+}
@@ -1,12 +1,45 @@
 package services
 
+import (
+	"context"
+	"hexagonal-gotest/models"
+)
+
 // PORT user service
 type UserService interface {
-	Register(username, password string) (err error)
+	Register(ctx context.Context, username, password string) (err error)
+
+	// Login is throttled per account and per clientIp, see ThrottleService
+	Login(ctx context.Context, username, password, clientIp string) (token, refreshToken string, err error)
+
+	// ChangePassword needs the current password, it ends every other session of the
+	// principal and returns new tokens for the calling one. Wrong current passwords are
+	// throttled like logins.
+	ChangePassword(ctx context.Context, principal models.SrvPrincipalModel, currentPassword, newPassword, clientIp string) (token, refreshToken string, err error)
+
+	// ResetPassword sets a password without the current one and ends every session,
+	// callers must have proven the account is theirs some other way, like a reset token
+	ResetPassword(ctx context.Context, userId, newPassword string) (err error)
+
+	// ChangeUsername renames userId, the old username stays reserved for its owner
+	// during UserConfig.UsernameCooldown. Every session naming the old username ends,
+	// an owner renaming themselves goes on with the returned tokens.
+	ChangeUsername(ctx context.Context, principal models.SrvPrincipalModel, userId, username string) (token, refreshToken string, err error)
+
+	// GetUser returns the account of userId to its owner or an admin
+	GetUser(ctx context.Context, principal models.SrvPrincipalModel, userId string) (user models.SrvUserModel, err error)
+
+	// ListUsers pages through every account, admin only
+	ListUsers(ctx context.Context, principal models.SrvPrincipalModel, query models.SrvListUsersModel) (page models.SrvUserPageModel, err error)
+
+	// GetProfile returns the profile of userId to its owner or an admin
+	GetProfile(ctx context.Context, principal models.SrvPrincipalModel, userId string) (profile models.SrvProfileModel, err error)
 
-	Login(username, password string) (token string, err error)
+	// UpdateProfile changes the fields set in payload and returns the updated profile
+	UpdateProfile(ctx context.Context, principal models.SrvPrincipalModel, userId string, payload models.SrvUpdateProfileModel) (profile models.SrvProfileModel, err error)
 
-	ResetPassword(userId, newPasword string) (err error)
+	// UnlockUser clears a login lockout of the account, admin only
+	UnlockUser(ctx context.Context, principal models.SrvPrincipalModel, userId string) (err error)
 
-	DeleteUser(userId string) (err error)
+	DeleteUser(ctx context.Context, principal models.SrvPrincipalModel, userId string) (err error)
 }
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"hexagonal-gotest/models"
+	"time"
+)
+
+// userCursor is where the next page of a user list starts, Sort and UsernamePrefix
+// tie it to the query that made it
+type userCursor struct {
+	Sort           string    `json:"s"`
+	UsernamePrefix string    `json:"p"`
+	UserId         string    `json:"i"`
+	Username       string    `json:"u"`
+	CreatedAt      time.Time `json:"c"`
+}
+
+// encodeUserCursor is opaque to clients, it is not signed since listing is admin only
+// and a forged cursor only moves the start of the page
+func encodeUserCursor(sort, usernamePrefix string, last models.RepoUserModel) string {
+	data, _ := json.Marshal(userCursor{Sort: sort, UsernamePrefix: usernamePrefix, UserId: last.UserId, Username: last.Username, CreatedAt: last.CreatedAt})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeUserCursor(cursor, sort, usernamePrefix string) (after *models.RepoUserModel, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, models.ErrListCursor
+	}
+
+	decoded := userCursor{}
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.UserId == "" {
+		return nil, models.ErrListCursor
+	}
+	if decoded.Sort != sort || decoded.UsernamePrefix != usernamePrefix {
+		return nil, models.ErrListCursor
+	}
+
+	return &models.RepoUserModel{UserId: decoded.UserId, Username: decoded.Username, CreatedAt: decoded.CreatedAt}, nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"fmt"
+	"hexagonal-gotest/models"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	maxDisplayNameLength = 64
+	maxEmailLength       = 254
+	maxAttributes        = 16
+	maxAttributeLength   = 256
+)
+
+// attributeNamePattern keeps names usable as mongo field names and json keys alike
+var attributeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// checkProfile normalizes payload in place and reports every field it rejects.
+// attributes are the stored ones, the merged result must stay within maxAttributes.
+func checkProfile(payload *models.SrvUpdateProfileModel, attributes map[string]string) error {
+	violations := []*models.Error{}
+
+	if payload.DisplayName != nil {
+		displayName := strings.TrimSpace(*payload.DisplayName)
+		if utf8.RuneCountInString(displayName) > maxDisplayNameLength || strings.IndexFunc(displayName, isNotPrintable) >= 0 {
+			violations = append(violations, models.ErrDisplayNameInvalid)
+		}
+		payload.DisplayName = &displayName
+	}
+
+	if payload.Email != nil {
+		email := strings.TrimSpace(*payload.Email)
+		if email != "" {
+			// a bare address only, ParseAddress also accepts "Name <address>"
+			address, err := mail.ParseAddress(email)
+			if err != nil || address.Name != "" || address.Address != email || len(email) > maxEmailLength {
+				violations = append(violations, models.ErrEmailFormat)
+			}
+		}
+		payload.Email = &email
+	}
+
+	if payload.Locale != nil {
+		locale := strings.TrimSpace(*payload.Locale)
+		if locale != "" {
+			tag, err := language.Parse(locale)
+			if err != nil {
+				violations = append(violations, models.ErrLocaleFormat)
+			} else {
+				locale = tag.String()
+			}
+		}
+		payload.Locale = &locale
+	}
+
+	count := len(attributes)
+	for name, value := range payload.Attributes {
+		if !attributeNamePattern.MatchString(name) {
+			violations = append(violations, attributeViolation(models.ErrAttributeName, name))
+			continue
+		}
+		if value != nil && utf8.RuneCountInString(*value) > maxAttributeLength {
+			violations = append(violations, attributeViolation(models.ErrAttributeTooLong, name))
+		}
+
+		_, stored := attributes[name]
+		switch {
+		case value == nil && stored:
+			count--
+		case value != nil && !stored:
+			count++
+		}
+	}
+	if count > maxAttributes {
+		violations = append(violations, models.ErrAttributesTooMany)
+	}
+
+	if len(violations) > 0 {
+		return models.NewValidationError(violations...)
+	}
+	return nil
+}
+
+func attributeViolation(err *models.Error, name string) *models.Error {
+	return &models.Error{Code: err.Code, Message: fmt.Sprintf("%v (%v)", err.Message, name), Field: "attributes." + name}
+}
+
+func isNotPrintable(r rune) bool {
+	return !unicode.IsPrint(r)
+}
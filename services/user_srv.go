@@ -1,118 +1,639 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"hexagonal-gotest/hashers"
 	"hexagonal-gotest/models"
+	"hexagonal-gotest/policies"
 	"hexagonal-gotest/repositories"
-	"hexagonal-gotest/utils"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// UserConfig ConcealRegistration makes Register answer for a taken username as for a
+// new one, so registering cannot be used to find out which accounts exist.
+// UsernameCooldown is how long a username given up by ChangeUsername is held for its
+// previous owner, 0 releases it right away. PasswordHistory is how many of the latest
+// passwords, the current one included, cannot be chosen again, 0 allows any.
+type UserConfig struct {
+	ConcealRegistration bool
+	UsernameCooldown    time.Duration
+	PasswordHistory     int
+}
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
 type userSrv struct {
-	userRepo repositories.UserRepository
+	userRepo            repositories.UserRepository
+	usernameHistoryRepo repositories.UsernameHistoryRepository
+	tokenSrv            TokenService
+	throttleSrv         ThrottleService
+	hasher              hashers.PasswordHasher
+	usernamePolicy      policies.UsernamePolicy
+	passwordPolicy      policies.PasswordPolicy
+	config              UserConfig
+	dummy               *dummyHash
 }
 
-func NewUserService(userRepo repositories.UserRepository) UserService {
-	return userSrv{userRepo}
+// dummyHash is verified for unknown usernames so they take as long as a wrong password,
+// it is made on first use so it carries the hasher's current parameters
+type dummyHash struct {
+	once sync.Once
+	hash string
+	err  error
 }
 
-func (s userSrv) Register(username, password string) (err error) {
+func NewUserService(userRepo repositories.UserRepository, usernameHistoryRepo repositories.UsernameHistoryRepository, tokenSrv TokenService, throttleSrv ThrottleService, hasher hashers.PasswordHasher, usernamePolicy policies.UsernamePolicy, passwordPolicy policies.PasswordPolicy, config UserConfig) UserService {
+	return userSrv{userRepo, usernameHistoryRepo, tokenSrv, throttleSrv, hasher, usernamePolicy, passwordPolicy, config, &dummyHash{}}
+}
+
+func (s userSrv) Register(ctx context.Context, username, password string) (err error) {
 	if username == "" {
-		return errors.New(models.ErrUsernameNotfound)
+		return models.ErrUsernameNotfound
 	}
 
 	if password == "" {
-		return errors.New(models.ErrPasswordNotfound)
+		return models.ErrPasswordNotfound
+	}
+
+	username, err = s.usernamePolicy.Check(username)
+	if err != nil {
+		return err
 	}
 
-	if len(password) < 6 || len(password) > 16 {
-		return errors.New(models.ErrPasswordFormat)
+	if err := s.passwordPolicy.Check(username, password); err != nil {
+		return err
 	}
 
-	resGets, err := s.userRepo.Gets(models.RepoGetUserModel{Username: username})
+	resGets, err := s.userRepo.Gets(ctx, models.RepoGetUserModel{Username: username})
 	if err != nil {
-		return errors.New(models.ErrUnexpected)
+		return models.ErrUnexpected.Wrap(err)
 	}
 	if len(resGets) > 0 {
-		return errors.New(models.ErrUsernameIsExist)
+		return s.usernameTaken(password)
+	}
+
+	held, err := s.usernameHeld(ctx, username, "")
+	if err != nil {
+		return err
+	}
+	if held {
+		return s.usernameTaken(password)
+	}
+
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return models.ErrUnexpected.Wrap(err)
 	}
 
-	err = s.userRepo.Create(models.RepoCreateUserModel{UserId: uuid.NewString(), Username: username, Password: password})
+	createdAt := now()
+	err = s.userRepo.Create(ctx, models.RepoCreateUserModel{UserId: uuid.NewString(), Username: username, Password: hash, Role: models.RoleUser, CreatedAt: createdAt, UpdatedAt: createdAt})
 	if err != nil {
-		return errors.New(models.ErrUnexpected)
+		// lost a race with a concurrent register of the same username, the hash is already paid for
+		if errors.Is(err, models.ErrUsernameIsExist) {
+			if s.config.ConcealRegistration {
+				return nil
+			}
+			return models.ErrUsernameIsExist
+		}
+
+		return models.ErrUnexpected.Wrap(err)
 	}
 
 	return
 }
 
-func (s userSrv) Login(username, password string) (token string, err error) {
+func (s userSrv) Login(ctx context.Context, username, password, clientIp string) (token, refreshToken string, err error) {
 	if username == "" {
-		return token, errors.New(models.ErrUsernameNotfound)
+		return token, refreshToken, models.ErrUsernameNotfound
 	}
 
+	// only presence is checked, the policy applies when a password is set and
+	// existing credentials must keep working when it changes
 	if password == "" {
-		return token, errors.New(models.ErrPasswordNotfound)
+		return token, refreshToken, models.ErrPasswordNotfound
 	}
 
-	if len(password) < 6 || len(password) > 16 {
-		return token, errors.New(models.ErrPasswordFormat)
+	username = s.usernamePolicy.Canonical(username)
+
+	// a throttled attempt is refused before the password is looked at, so guessing
+	// during a lockout tells nothing and does not extend it
+	if err := s.throttleSrv.Check(ctx, username, clientIp); err != nil {
+		return token, refreshToken, err
 	}
 
-	resUsers, err := s.userRepo.Gets(models.RepoGetUserModel{Username: username})
+	resUsers, err := s.userRepo.Gets(ctx, models.RepoGetUserModel{Username: username})
 	if err != nil {
-		return token, errors.New(models.ErrUnexpected)
+		return token, refreshToken, models.ErrUnexpected.Wrap(err)
 	}
+	// an unknown username and a wrong password must look the same, in the response and in its timing
 	if len(resUsers) == 0 {
-		return token, errors.New(models.ErrUsernameIsNotExist)
+		s.verifyDummy(password)
+		return token, refreshToken, s.failLogin(ctx, username, clientIp, models.ErrInvalidCredentials)
 	}
 
-	if resUsers[0].Password != password {
-		return token, errors.New(models.ErrUnauthorized)
+	ok, needsRehash, err := s.hasher.Verify(password, resUsers[0].Password)
+	if err != nil || !ok {
+		return token, refreshToken, s.failLogin(ctx, username, clientIp, models.ErrInvalidCredentials)
 	}
 
-	token, err = utils.SignJWT(utils.TokenDataModel{UserId: resUsers[0].UserId, Username: resUsers[0].Username})
+	// a failed reset only leaves older failures counting until they expire, login must not fail on it
+	s.throttleSrv.Reset(ctx, username)
 
-	return
+	// upgrade outdated hash while the plaintext is at hand, login must not fail on it
+	// nor on recording the login
+	update := models.RepoUpdateUserModel{LastLoginAt: now()}
+	if needsRehash {
+		if hash, err := s.hasher.Hash(password); err == nil {
+			update.Password = hash
+		}
+	}
+	s.userRepo.Update(ctx, resUsers[0].UserId, update)
+
+	return s.tokenSrv.Issue(ctx, models.SrvPrincipalModel{UserId: resUsers[0].UserId, Username: resUsers[0].Username, Role: resUsers[0].Role, CredentialVersion: resUsers[0].CredentialVersion})
 }
 
-func (s userSrv) ResetPassword(userId, newPassword string) (err error) {
+func (s userSrv) ChangePassword(ctx context.Context, principal models.SrvPrincipalModel, currentPassword, newPassword, clientIp string) (token, refreshToken string, err error) {
+	if principal.UserId == "" {
+		return token, refreshToken, models.ErrUnauthorized
+	}
+
+	if currentPassword == "" {
+		return token, refreshToken, models.ErrCurrentPasswordNotfound
+	}
+
 	if newPassword == "" {
-		return errors.New(models.ErrPasswordNotfound)
+		return token, refreshToken, models.ErrPasswordNotfound
 	}
 
-	if len(newPassword) < 6 || len(newPassword) > 16 {
-		return errors.New(models.ErrPasswordFormat)
+	resUsers, err := s.userRepo.Gets(ctx, models.RepoGetUserModel{UserId: principal.UserId})
+	if err != nil {
+		return token, refreshToken, models.ErrUnexpected.Wrap(err)
+	}
+	if len(resUsers) == 0 {
+		return token, refreshToken, models.ErrUnauthorized
+	}
+	user := resUsers[0]
+
+	// guessing the current password from a hijacked session is throttled like logging in
+	if err := s.throttleSrv.Check(ctx, user.Username, clientIp); err != nil {
+		return token, refreshToken, err
+	}
+
+	ok, _, err := s.hasher.Verify(currentPassword, user.Password)
+	if err != nil || !ok {
+		return token, refreshToken, s.failLogin(ctx, user.Username, clientIp, models.ErrCurrentPasswordInvalid)
+	}
+
+	s.throttleSrv.Reset(ctx, user.Username)
+
+	credentialVersion, err := s.setPassword(ctx, user, newPassword)
+	if err != nil {
+		return token, refreshToken, err
+	}
+
+	// every session ended with the old credential version, the caller's goes on with new tokens
+	return s.tokenSrv.Issue(ctx, models.SrvPrincipalModel{UserId: user.UserId, Username: user.Username, Role: user.Role, CredentialVersion: credentialVersion})
+}
+
+func (s userSrv) ResetPassword(ctx context.Context, userId, newPassword string) (err error) {
+	if newPassword == "" {
+		return models.ErrPasswordNotfound
 	}
 
 	if _, err := uuid.Parse(userId); err != nil {
-		return errors.New(models.ErrUserIdFormat)
+		return models.ErrUserIdFormat
 	}
 
-	err = s.userRepo.Update(userId, models.RepoUpdateUserModel{Password: newPassword})
+	resUsers, err := s.userRepo.Gets(ctx, models.RepoGetUserModel{UserId: userId})
 	if err != nil {
-		if err.Error() == models.ErrUserIdIsNotExist {
-			return errors.New(models.ErrUserIdIsNotExist)
+		return models.ErrUnexpected.Wrap(err)
+	}
+	if len(resUsers) == 0 {
+		return models.ErrUserIdIsNotExist
+	}
+
+	_, err = s.setPassword(ctx, resUsers[0], newPassword)
+	return err
+}
+
+func (s userSrv) ChangeUsername(ctx context.Context, principal models.SrvPrincipalModel, userId, username string) (token, refreshToken string, err error) {
+	if username == "" {
+		return token, refreshToken, models.ErrUsernameNotfound
+	}
+
+	if _, err := uuid.Parse(userId); err != nil {
+		return token, refreshToken, models.ErrUserIdFormat
+	}
+
+	if err := authorizeOwner(principal, userId); err != nil {
+		return token, refreshToken, err
+	}
+
+	username, err = s.usernamePolicy.Check(username)
+	if err != nil {
+		return token, refreshToken, err
+	}
+
+	resUsers, err := s.userRepo.Gets(ctx, models.RepoGetUserModel{UserId: userId})
+	if err != nil {
+		return token, refreshToken, models.ErrUnexpected.Wrap(err)
+	}
+	if len(resUsers) == 0 {
+		return token, refreshToken, models.ErrUserIdIsNotExist
+	}
+	user := resUsers[0]
+	previous := user.Username
+	// nothing changes, the caller's tokens stay valid
+	if username == previous {
+		return token, refreshToken, nil
+	}
+
+	held, err := s.usernameHeld(ctx, username, userId)
+	if err != nil {
+		return token, refreshToken, err
+	}
+	if held {
+		return token, refreshToken, models.ErrUsernameIsExist
+	}
+
+	// the old username is held before it is given up, so there is no moment in which
+	// someone else could register it
+	if s.config.UsernameCooldown > 0 {
+		releasedAt := now()
+		err = s.usernameHistoryRepo.Create(ctx, models.RepoUsernameHistoryModel{Username: previous, UserId: userId, ReleasedAt: releasedAt, ExpiresAt: releasedAt.Add(s.config.UsernameCooldown)})
+		if err != nil {
+			return token, refreshToken, models.ErrUnexpected.Wrap(err)
+		}
+	}
+
+	// uniqueness is left to the repository, a concurrent register or change to the
+	// same username makes one of them fail with ErrUsernameIsExist. Tokens carry the
+	// username in their claims, bumping the credential version ends every session that
+	// still names the old one. The repository only bumps the version read above, a
+	// concurrent credential change makes one of them fail with ErrCredentialsChanged
+	credentialVersion := user.CredentialVersion + 1
+	err = s.userRepo.Update(ctx, userId, models.RepoUpdateUserModel{Username: username, CredentialVersion: credentialVersion, UpdatedAt: now()})
+	if err != nil {
+		if errors.Is(err, models.ErrUsernameIsExist) {
+			return token, refreshToken, models.ErrUsernameIsExist
+		}
+		if errors.Is(err, models.ErrCredentialsChanged) {
+			return token, refreshToken, models.ErrCredentialsChanged
+		}
+		if errors.Is(err, models.ErrUserIdIsNotExist) {
+			return token, refreshToken, models.ErrUserIdIsNotExist
 		}
 
-		return errors.New(models.ErrUnexpected)
+		return token, refreshToken, models.ErrUnexpected.Wrap(err)
 	}
 
-	return
+	// an admin renaming someone else keeps their own session and gets no tokens
+	if principal.UserId != userId {
+		return token, refreshToken, nil
+	}
+
+	return s.tokenSrv.Issue(ctx, models.SrvPrincipalModel{UserId: user.UserId, Username: username, Role: user.Role, CredentialVersion: credentialVersion})
+}
+
+func (s userSrv) GetUser(ctx context.Context, principal models.SrvPrincipalModel, userId string) (user models.SrvUserModel, err error) {
+	if _, err := uuid.Parse(userId); err != nil {
+		return user, models.ErrUserIdFormat
+	}
+
+	if err := authorizeOwner(principal, userId); err != nil {
+		return user, err
+	}
+
+	resUsers, err := s.userRepo.Gets(ctx, models.RepoGetUserModel{UserId: userId})
+	if err != nil {
+		return user, models.ErrUnexpected.Wrap(err)
+	}
+	if len(resUsers) == 0 {
+		return user, models.ErrUserIdIsNotExist
+	}
+
+	return newSrvUser(resUsers[0]), nil
+}
+
+func (s userSrv) ListUsers(ctx context.Context, principal models.SrvPrincipalModel, query models.SrvListUsersModel) (page models.SrvUserPageModel, err error) {
+	if err := authorizeAdmin(principal); err != nil {
+		return page, err
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+	if limit < 0 || limit > maxListLimit {
+		return page, models.ErrListLimit
+	}
+
+	sort := query.Sort
+	if sort == "" {
+		sort = models.UserSortUsername
+	}
+	sortBy, descending := strings.CutPrefix(sort, "-")
+	if sortBy != models.UserSortUsername && sortBy != models.UserSortCreatedAt {
+		return page, models.ErrListSort
+	}
+
+	usernamePrefix := s.usernamePolicy.Canonical(query.UsernamePrefix)
+
+	var after *models.RepoUserModel
+	if query.Cursor != "" {
+		if after, err = decodeUserCursor(query.Cursor, sort, usernamePrefix); err != nil {
+			return page, err
+		}
+	}
+
+	// one more than the page tells whether there is a next one
+	resUsers, err := s.userRepo.List(ctx, models.RepoListUserModel{UsernamePrefix: usernamePrefix, SortBy: sortBy, Descending: descending, After: after, Limit: limit + 1})
+	if err != nil {
+		return page, models.ErrUnexpected.Wrap(err)
+	}
+	if len(resUsers) > limit {
+		resUsers = resUsers[:limit]
+		page.NextCursor = encodeUserCursor(sort, usernamePrefix, resUsers[limit-1])
+	}
+
+	page.Users = make([]models.SrvUserModel, 0, len(resUsers))
+	for _, user := range resUsers {
+		page.Users = append(page.Users, newSrvUser(user))
+	}
+
+	return page, nil
+}
+
+func (s userSrv) GetProfile(ctx context.Context, principal models.SrvPrincipalModel, userId string) (profile models.SrvProfileModel, err error) {
+	if _, err := uuid.Parse(userId); err != nil {
+		return profile, models.ErrUserIdFormat
+	}
+
+	if err := authorizeOwner(principal, userId); err != nil {
+		return profile, err
+	}
+
+	return s.profile(ctx, userId)
 }
 
-func (s userSrv) DeleteUser(userId string) (err error) {
+func (s userSrv) UpdateProfile(ctx context.Context, principal models.SrvPrincipalModel, userId string, payload models.SrvUpdateProfileModel) (profile models.SrvProfileModel, err error) {
 	if _, err := uuid.Parse(userId); err != nil {
-		return errors.New(models.ErrUserIdFormat)
+		return profile, models.ErrUserIdFormat
+	}
+
+	if err := authorizeOwner(principal, userId); err != nil {
+		return profile, err
+	}
+
+	// the attribute limit is checked against the stored attributes, concurrent updates
+	// adding different attributes can overshoot it by what each of them adds
+	resUsers, err := s.userRepo.Gets(ctx, models.RepoGetUserModel{UserId: userId})
+	if err != nil {
+		return profile, models.ErrUnexpected.Wrap(err)
+	}
+	if len(resUsers) == 0 {
+		return profile, models.ErrUserIdIsNotExist
+	}
+
+	if err := checkProfile(&payload, resUsers[0].Attributes); err != nil {
+		return profile, err
 	}
 
-	err = s.userRepo.Delete(userId)
+	err = s.userRepo.Update(ctx, userId, models.RepoUpdateUserModel{
+		DisplayName: payload.DisplayName,
+		Email:       payload.Email,
+		Locale:      payload.Locale,
+		Attributes:  payload.Attributes,
+		UpdatedAt:   now(),
+	})
 	if err != nil {
-		if err.Error() == models.ErrUserIdIsNotExist {
-			return errors.New(models.ErrUserIdIsNotExist)
+		if errors.Is(err, models.ErrUserIdIsNotExist) {
+			return profile, models.ErrUserIdIsNotExist
 		}
 
-		return errors.New(models.ErrUnexpected)
+		return profile, models.ErrUnexpected.Wrap(err)
+	}
+
+	return s.profile(ctx, userId)
+}
+
+// UnlockUser lifts a lockout before it expires by forgetting the failed logins of the account
+func (s userSrv) UnlockUser(ctx context.Context, principal models.SrvPrincipalModel, userId string) (err error) {
+	if _, err := uuid.Parse(userId); err != nil {
+		return models.ErrUserIdFormat
+	}
+
+	if err := authorizeAdmin(principal); err != nil {
+		return err
+	}
+
+	resUsers, err := s.userRepo.Gets(ctx, models.RepoGetUserModel{UserId: userId})
+	if err != nil {
+		return models.ErrUnexpected.Wrap(err)
+	}
+	if len(resUsers) == 0 {
+		return models.ErrUserIdIsNotExist
+	}
+
+	return s.throttleSrv.Reset(ctx, resUsers[0].Username)
+}
+
+func (s userSrv) DeleteUser(ctx context.Context, principal models.SrvPrincipalModel, userId string) (err error) {
+	if _, err := uuid.Parse(userId); err != nil {
+		return models.ErrUserIdFormat
+	}
+
+	if err := authorizeOwner(principal, userId); err != nil {
+		return err
+	}
+
+	err = s.userRepo.Delete(ctx, userId)
+	if err != nil {
+		if errors.Is(err, models.ErrUserIdIsNotExist) {
+			return models.ErrUserIdIsNotExist
+		}
+
+		return models.ErrUnexpected.Wrap(err)
+	}
+
+	return
+}
+
+// newSrvUser leaves the password hash behind
+func newSrvUser(user models.RepoUserModel) models.SrvUserModel {
+	return models.SrvUserModel{UserId: user.UserId, Username: user.Username, Role: user.Role, CreatedAt: user.CreatedAt}
+}
+
+func (s userSrv) profile(ctx context.Context, userId string) (profile models.SrvProfileModel, err error) {
+	resUsers, err := s.userRepo.Gets(ctx, models.RepoGetUserModel{UserId: userId})
+	if err != nil {
+		return profile, models.ErrUnexpected.Wrap(err)
+	}
+	if len(resUsers) == 0 {
+		return profile, models.ErrUserIdIsNotExist
+	}
+
+	user := resUsers[0]
+	return models.SrvProfileModel{
+		UserId:      user.UserId,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		Email:       user.Email,
+		Locale:      user.Locale,
+		Attributes:  user.Attributes,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		LastLoginAt: user.LastLoginAt,
+	}, nil
+}
+
+// now is rounded to what every repository adapter stores unchanged
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// usernameTaken hashes like a registration would before reporting the username, or
+// hiding it when registrations are concealed
+func (s userSrv) usernameTaken(password string) (err error) {
+	if !s.config.ConcealRegistration {
+		return models.ErrUsernameIsExist
+	}
+
+	s.hasher.Hash(password)
+	return nil
+}
+
+// setPassword replaces the password of user and bumps its credential version, which
+// ends every session of the user, and returns the new version. It fails with
+// ErrCredentialsChanged when the version moved on since user was read
+func (s userSrv) setPassword(ctx context.Context, user models.RepoUserModel, newPassword string) (credentialVersion int, err error) {
+	if err := s.passwordPolicy.Check(user.Username, newPassword); err != nil {
+		return 0, err
+	}
+
+	if s.passwordReused(user, newPassword) {
+		return 0, models.ErrPasswordReused
+	}
+
+	hash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return 0, models.ErrUnexpected.Wrap(err)
+	}
+
+	credentialVersion = user.CredentialVersion + 1
+	err = s.userRepo.Update(ctx, user.UserId, models.RepoUpdateUserModel{
+		Password:          hash,
+		PasswordHistory:   s.passwordHistory(user),
+		CredentialVersion: credentialVersion,
+		UpdatedAt:         now(),
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrUserIdIsNotExist) {
+			return 0, models.ErrUserIdIsNotExist
+		}
+		if errors.Is(err, models.ErrCredentialsChanged) {
+			return 0, models.ErrCredentialsChanged
+		}
+
+		return 0, models.ErrUnexpected.Wrap(err)
+	}
+
+	return credentialVersion, nil
+}
+
+// passwordReused checks password against the latest UserConfig.PasswordHistory hashes
+// of user, each check costs a full hash verification
+func (s userSrv) passwordReused(user models.RepoUserModel, password string) bool {
+	if s.config.PasswordHistory < 1 {
+		return false
+	}
+
+	hashes := append([]string{user.Password}, user.PasswordHistory...)
+	if len(hashes) > s.config.PasswordHistory {
+		hashes = hashes[:s.config.PasswordHistory]
+	}
+	for _, hash := range hashes {
+		if ok, _, err := s.hasher.Verify(password, hash); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// passwordHistory is the history to store once user leaves the current password, the
+// new current password takes the remaining place
+func (s userSrv) passwordHistory(user models.RepoUserModel) []string {
+	keep := s.config.PasswordHistory - 1
+	if keep < 1 {
+		return nil
+	}
+
+	history := append([]string{user.Password}, user.PasswordHistory...)
+	if len(history) > keep {
+		history = history[:keep]
+	}
+	return history
+}
+
+// usernameHeld tells whether username was recently given up by an account other than userId
+func (s userSrv) usernameHeld(ctx context.Context, username, userId string) (held bool, err error) {
+	resHistory, err := s.usernameHistoryRepo.Gets(ctx, username)
+	if err != nil {
+		return false, models.ErrUnexpected.Wrap(err)
+	}
+	for _, entry := range resHistory {
+		if entry.UserId != userId {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// verifyDummy spends the time a password check against a real account takes
+func (s userSrv) verifyDummy(password string) {
+	s.dummy.once.Do(func() {
+		s.dummy.hash, s.dummy.err = s.hasher.Hash(uuid.NewString())
+	})
+	if s.dummy.err == nil {
+		s.hasher.Verify(password, s.dummy.hash)
+	}
+}
+
+// failLogin counts the failure and returns err, losing the count must not hide why the login failed
+func (s userSrv) failLogin(ctx context.Context, username, clientIp string, err error) error {
+	s.throttleSrv.Failure(ctx, username, clientIp)
+	return err
+}
+
+// authorizeAdmin allows principal to act on any account when they are admin
+func authorizeAdmin(principal models.SrvPrincipalModel) (err error) {
+	if principal.UserId == "" {
+		return models.ErrUnauthorized
+	}
+
+	if principal.Role != models.RoleAdmin {
+		return models.ErrForbidden
+	}
+
+	return
+}
+
+// authorizeOwner allows principal to act on userId when it is their own account or they are admin
+func authorizeOwner(principal models.SrvPrincipalModel, userId string) (err error) {
+	if principal.UserId == "" {
+		return models.ErrUnauthorized
+	}
+
+	if principal.UserId != userId && principal.Role != models.RoleAdmin {
+		return models.ErrForbidden
 	}
 
 	return
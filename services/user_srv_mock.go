@@ -1,6 +1,11 @@
 package services
 
-import "github.com/stretchr/testify/mock"
+import (
+	"context"
+	"hexagonal-gotest/models"
+
+	"github.com/stretchr/testify/mock"
+)
 
 type userSrvMock struct {
 	mock.Mock
@@ -10,22 +15,61 @@ func NewUserSrvMock() userSrvMock {
 	return userSrvMock{}
 }
 
-func (m *userSrvMock) Register(username, password string) (err error) {
-	args := m.Called(username, password)
+func (m *userSrvMock) Register(ctx context.Context, username, password string) (err error) {
+	args := m.Called(ctx, username, password)
+	return args.Error(0)
+}
+
+func (m *userSrvMock) Login(ctx context.Context, username, password, clientIp string) (token, refreshToken string, err error) {
+	args := m.Called(ctx, username, password, clientIp)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *userSrvMock) ChangeUsername(ctx context.Context, principal models.SrvPrincipalModel, userId, username string) (token, refreshToken string, err error) {
+	args := m.Called(ctx, principal, userId, username)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *userSrvMock) ChangePassword(ctx context.Context, principal models.SrvPrincipalModel, currentPassword, newPassword, clientIp string) (token, refreshToken string, err error) {
+	args := m.Called(ctx, principal, currentPassword, newPassword, clientIp)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *userSrvMock) ResetPassword(ctx context.Context, userId, newPassword string) (err error) {
+	args := m.Called(ctx, userId, newPassword)
 	return args.Error(0)
 }
 
-func (m *userSrvMock) Login(username, password string) (token string, err error) {
-	args := m.Called(username, password)
-	return args.String(0), args.Error(1)
+func (m *userSrvMock) GetUser(ctx context.Context, principal models.SrvPrincipalModel, userId string) (user models.SrvUserModel, err error) {
+	args := m.Called(ctx, principal, userId)
+	res, _ := args.Get(0).(models.SrvUserModel)
+	return res, args.Error(1)
+}
+
+func (m *userSrvMock) ListUsers(ctx context.Context, principal models.SrvPrincipalModel, query models.SrvListUsersModel) (page models.SrvUserPageModel, err error) {
+	args := m.Called(ctx, principal, query)
+	res, _ := args.Get(0).(models.SrvUserPageModel)
+	return res, args.Error(1)
+}
+
+func (m *userSrvMock) GetProfile(ctx context.Context, principal models.SrvPrincipalModel, userId string) (profile models.SrvProfileModel, err error) {
+	args := m.Called(ctx, principal, userId)
+	res, _ := args.Get(0).(models.SrvProfileModel)
+	return res, args.Error(1)
+}
+
+func (m *userSrvMock) UpdateProfile(ctx context.Context, principal models.SrvPrincipalModel, userId string, payload models.SrvUpdateProfileModel) (profile models.SrvProfileModel, err error) {
+	args := m.Called(ctx, principal, userId, payload)
+	res, _ := args.Get(0).(models.SrvProfileModel)
+	return res, args.Error(1)
 }
 
-func (m *userSrvMock) ResetPassword(userId, newPassword string) (err error) {
-	args := m.Called(userId, newPassword)
+func (m *userSrvMock) UnlockUser(ctx context.Context, principal models.SrvPrincipalModel, userId string) (err error) {
+	args := m.Called(ctx, principal, userId)
 	return args.Error(0)
 }
 
-func (m *userSrvMock) DeleteUser(userId string) (err error) {
-	args := m.Called(userId)
+func (m *userSrvMock) DeleteUser(ctx context.Context, principal models.SrvPrincipalModel, userId string) (err error) {
+	args := m.Called(ctx, principal, userId)
 	return args.Error(0)
 }
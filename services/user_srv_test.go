@@ -1,11 +1,15 @@
 package services_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"hexagonal-gotest/hashers"
 	"hexagonal-gotest/models"
+	"hexagonal-gotest/policies"
 	"hexagonal-gotest/repositories"
 	"hexagonal-gotest/services"
-	"hexagonal-gotest/utils"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,6 +18,17 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
+var (
+	ctx = context.Background()
+
+	owner = models.SrvPrincipalModel{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: "admin", Role: models.RoleUser}
+	other = models.SrvPrincipalModel{UserId: "6b3f4c1e-5a2d-4e8f-9c7b-1d2e3f4a5b6c", Username: "other", Role: models.RoleUser}
+	admin = models.SrvPrincipalModel{UserId: "6b3f4c1e-5a2d-4e8f-9c7b-1d2e3f4a5b6c", Username: "root", Role: models.RoleAdmin}
+
+	usernamePolicy = policies.NewUsernamePolicy(policies.UsernamePolicyConfig{MinLength: 1, MaxLength: 32})
+	passwordPolicy = policies.NewPasswordPolicy(policies.PasswordPolicyConfig{MinLength: 6, MaxLength: 16})
+)
+
 func TestRegister(t *testing.T) {
 	type args struct {
 		username string
@@ -22,94 +37,184 @@ func TestRegister(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    args
+		config  services.UserConfig
 		wantErr error
 	}{
 		// TODO: Add test cases.
 		{
 			name:    "error1",
 			args:    args{username: "", password: "admin01"},
-			wantErr: errors.New(models.ErrUsernameNotfound),
+			wantErr: models.ErrUsernameNotfound,
 		},
 		{
 			name:    "error2",
 			args:    args{username: "admin", password: ""},
-			wantErr: errors.New(models.ErrPasswordNotfound),
+			wantErr: models.ErrPasswordNotfound,
 		},
 		{
 			name:    "error3",
 			args:    args{username: "admin", password: "123"},
-			wantErr: errors.New(models.ErrPasswordFormat),
+			wantErr: models.ErrPasswordTooShort,
 		},
 		{
 			name:    "error4",
 			args:    args{username: "admin", password: "123456789123456789"},
-			wantErr: errors.New(models.ErrPasswordFormat),
+			wantErr: models.ErrPasswordTooLong,
+		},
+		{
+			name:    "invalid username",
+			args:    args{username: "som chai", password: "admin01"},
+			wantErr: models.ErrUsernameCharacters,
 		},
 		{
 			name:    "error5",
 			args:    args{username: "admin", password: "admin01"},
-			wantErr: errors.New(models.ErrUsernameIsExist),
+			wantErr: models.ErrUsernameIsExist,
+		},
+		{
+			name:    "create username race",
+			args:    args{username: "admin", password: "admin01"},
+			wantErr: models.ErrUsernameIsExist,
+		},
+		{
+			name:    "concealed username exists",
+			args:    args{username: "admin", password: "admin01"},
+			config:  services.UserConfig{ConcealRegistration: true},
+			wantErr: nil,
+		},
+		{
+			name:    "concealed create username race",
+			args:    args{username: "admin", password: "admin01"},
+			config:  services.UserConfig{ConcealRegistration: true},
+			wantErr: nil,
+		},
+		{
+			name:    "username recently changed away",
+			args:    args{username: "admin", password: "admin01"},
+			wantErr: models.ErrUsernameIsExist,
+		},
+		{
+			name:    "concealed username recently changed away",
+			args:    args{username: "admin", password: "admin01"},
+			config:  services.UserConfig{ConcealRegistration: true},
+			wantErr: nil,
+		},
+		{
+			name:    "unexpected gets username history",
+			args:    args{username: "admin", password: "admin01"},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "concealed still reports the policy",
+			args:    args{username: "admin", password: "123"},
+			config:  services.UserConfig{ConcealRegistration: true},
+			wantErr: models.ErrPasswordTooShort,
 		},
 		{
 			name:    "unexpected gets user",
 			args:    args{username: "admin", password: "admin01"},
-			wantErr: errors.New(models.ErrUnexpected),
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "unexpected hash password",
+			args:    args{username: "admin", password: "admin01"},
+			wantErr: models.ErrUnexpected,
 		},
 		{
 			name:    "unexpected create user",
 			args:    args{username: "admin", password: "admin01"},
-			wantErr: errors.New(models.ErrUnexpected),
+			wantErr: models.ErrUnexpected,
 		},
 		{
 			name:    "success1",
 			args:    args{username: "admin", password: "admin01"},
 			wantErr: nil,
 		},
+		{
+			name:    "success username is stored case folded",
+			args:    args{username: "Admin", password: "admin01"},
+			wantErr: nil,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// ------------------- Arrange (เตรียมของ) --------------------
 			userRepo := repositories.NewUserRepoMock()
+			usernameHistoryRepo := repositories.NewUsernameHistoryRepoMock()
 
 			// mock Gets user
 			switch tt.name {
-			case "error5":
-				userRepo.On("Gets", mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
-					return filter.Username == tt.args.username
+			case "error5", "concealed username exists":
+				userRepo.On("Gets", mock.Anything, mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
+					return filter.Username == strings.ToLower(tt.args.username)
 				})).Return([]models.RepoUserModel{
 					{Username: tt.args.username},
 				}, nil)
 			case "unexpected gets user":
-				userRepo.On("Gets", mock.AnythingOfType("models.RepoGetUserModel")).Return(nil, errors.New(""))
+				userRepo.On("Gets", mock.Anything, mock.AnythingOfType("models.RepoGetUserModel")).Return(nil, errors.New(""))
 			default:
-				userRepo.On("Gets", mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
-					return filter.Username == tt.args.username
+				userRepo.On("Gets", mock.Anything, mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
+					return filter.Username == strings.ToLower(tt.args.username)
 				})).Return([]models.RepoUserModel{}, nil)
 			}
 
+			// mock Gets username history
+			switch tt.name {
+			case "username recently changed away", "concealed username recently changed away":
+				usernameHistoryRepo.On("Gets", mock.Anything, strings.ToLower(tt.args.username)).Return([]models.RepoUsernameHistoryModel{
+					{Username: strings.ToLower(tt.args.username), UserId: owner.UserId},
+				}, nil)
+			case "unexpected gets username history":
+				usernameHistoryRepo.On("Gets", mock.Anything, mock.Anything).Return(nil, errors.New(""))
+			default:
+				usernameHistoryRepo.On("Gets", mock.Anything, strings.ToLower(tt.args.username)).Return([]models.RepoUsernameHistoryModel{}, nil)
+			}
+
+			tokenSrv := services.NewTokenSrvMock()
+
+			// mock Hash password
+			hasher := hashers.NewHasherMock()
+			switch tt.name {
+			case "unexpected hash password":
+				hasher.On("Hash", tt.args.password).Return("", errors.New(""))
+			default:
+				hasher.On("Hash", tt.args.password).Return("hashed-"+tt.args.password, nil)
+			}
+
 			// mock Create user
 			switch tt.name {
+			case "create username race", "concealed create username race":
+				userRepo.On("Create", mock.Anything, mock.AnythingOfType("models.RepoCreateUserModel")).Return(models.ErrUsernameIsExist)
 			case "unexpected create user":
-				userRepo.On("Create", mock.AnythingOfType("models.RepoCreateUserModel")).Return(errors.New(""))
+				userRepo.On("Create", mock.Anything, mock.AnythingOfType("models.RepoCreateUserModel")).Return(errors.New(""))
 			default:
-				userRepo.On("Create", mock.MatchedBy(func(payload models.RepoCreateUserModel) bool {
+				userRepo.On("Create", mock.Anything, mock.MatchedBy(func(payload models.RepoCreateUserModel) bool {
 					_, errUUID := uuid.Parse(payload.UserId)
-					return payload.Username == tt.args.username && payload.Password == tt.args.password && errUUID == nil
+					return payload.Username == strings.ToLower(tt.args.username) && payload.Password == "hashed-"+tt.args.password && errUUID == nil
 				})).Return(nil)
 			}
 
-			userSrv := services.NewUserService(&userRepo)
+			throttleSrv := services.NewThrottleSrvMock()
+			userSrv := services.NewUserService(&userRepo, &usernameHistoryRepo, &tokenSrv, &throttleSrv, &hasher, usernamePolicy, passwordPolicy, tt.config)
 
 			// -------------------- Act (กระทำ)--------------------
-			err := userSrv.Register(tt.args.username, tt.args.password)
+			err := userSrv.Register(ctx, tt.args.username, tt.args.password)
 
 			// -------------------- Assert (ยืนยัน) --------------------
-			if tt.wantErr != nil {
-				assert.Equal(t, tt.wantErr, err)
-			} else {
-				userRepo.AssertCalled(t, "Create", mock.MatchedBy(func(payload models.RepoCreateUserModel) bool {
+			switch {
+			case tt.wantErr != nil:
+				assert.ErrorIs(t, err, tt.wantErr)
+			case tt.name == "concealed username exists", tt.name == "concealed username recently changed away":
+				// the password is hashed like for a new user, only nothing is stored
+				assert.NoError(t, err)
+				hasher.AssertCalled(t, "Hash", tt.args.password)
+				userRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			default:
+				assert.NoError(t, err)
+				userRepo.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(payload models.RepoCreateUserModel) bool {
 					_, errUUID := uuid.Parse(payload.UserId)
-					return payload.Username == tt.args.username && payload.Password == tt.args.password && errUUID == nil
+					return payload.Username == strings.ToLower(tt.args.username) && payload.Password == "hashed-"+tt.args.password && payload.Role == models.RoleUser && errUUID == nil &&
+						!payload.CreatedAt.IsZero() && payload.UpdatedAt.Equal(payload.CreatedAt)
 				}))
 			}
 		})
@@ -130,95 +235,213 @@ func TestLogin(t *testing.T) {
 		{
 			name:    "error1",
 			args:    args{username: "", password: "admin01"},
-			wantErr: errors.New(models.ErrUsernameNotfound),
+			wantErr: models.ErrUsernameNotfound,
 		},
 		{
 			name:    "error2",
 			args:    args{username: "admin", password: ""},
-			wantErr: errors.New(models.ErrPasswordNotfound),
+			wantErr: models.ErrPasswordNotfound,
 		},
 		{
-			name:    "error3",
-			args:    args{username: "admin", password: "123"},
-			wantErr: errors.New(models.ErrPasswordFormat),
+			name:    "error5",
+			args:    args{username: "admin", password: "admin01"},
+			wantErr: models.ErrInvalidCredentials,
 		},
 		{
-			name:    "error4",
-			args:    args{username: "admin", password: "123456789123456789"},
-			wantErr: errors.New(models.ErrPasswordFormat),
+			name:    "error6",
+			args:    args{username: "admin", password: "admin01"},
+			wantErr: models.ErrInvalidCredentials,
 		},
 		{
-			name:    "error5",
+			name:    "throttled",
+			args:    args{username: "ADMIN", password: "admin01"},
+			wantErr: models.ErrTooManyAttempts,
+		},
+		{
+			name:    "locked",
 			args:    args{username: "admin", password: "admin01"},
-			wantErr: errors.New(models.ErrUsernameIsNotExist),
+			wantErr: models.ErrAccountLocked,
 		},
 		{
-			name:    "error6",
+			name:    "unexpected check throttle",
 			args:    args{username: "admin", password: "admin01"},
-			wantErr: errors.New(models.ErrUnauthorized),
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "error6 failure not counted",
+			args:    args{username: "admin", password: "admin01"},
+			wantErr: models.ErrInvalidCredentials,
 		},
 		{
 			name:    "unexpected gets user",
 			args:    args{username: "admin", password: "admin01"},
-			wantErr: errors.New(models.ErrUnexpected),
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "unexpected issue token",
+			args:    args{username: "admin", password: "admin01"},
+			wantErr: models.ErrUnexpected,
 		},
 		{
 			name:    "success1",
 			args:    args{username: "admin", password: "admin01"},
 			wantErr: nil,
 		},
+		{
+			name:    "success rehash",
+			args:    args{username: "admin", password: "admin01"},
+			wantErr: nil,
+		},
+		{
+			name:    "success record login fails",
+			args:    args{username: "admin", password: "admin01"},
+			wantErr: nil,
+		},
+		{
+			name:    "success username in another case",
+			args:    args{username: "ADMIN", password: "admin01"},
+			wantErr: nil,
+		},
+		{
+			name:    "success password set before the policy",
+			args:    args{username: "admin", password: "123"},
+			wantErr: nil,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// ------------------- Arrange (เตรียมของ) --------------------
 			userRepo := repositories.NewUserRepoMock()
+			usernameHistoryRepo := repositories.NewUsernameHistoryRepoMock()
 
 			// mock Get user
 			switch tt.name {
 			case "error5":
-				userRepo.On("Gets", mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
-					return filter.Username == tt.args.username
+				userRepo.On("Gets", mock.Anything, mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
+					return filter.Username == strings.ToLower(tt.args.username)
 				})).Return([]models.RepoUserModel{}, nil)
 
-			case "error6":
-				userRepo.On("Gets", mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
-					return filter.Username == tt.args.username
+			case "error6", "error6 failure not counted":
+				userRepo.On("Gets", mock.Anything, mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
+					return filter.Username == strings.ToLower(tt.args.username)
 				})).Return([]models.RepoUserModel{
-					{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: tt.args.username, Password: ""},
+					{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: strings.ToLower(tt.args.username), Password: "hashed-other"},
 				}, nil)
 
 			case "unexpected gets user":
-				userRepo.On("Gets", mock.AnythingOfType("models.RepoGetUserModel")).Return(nil, errors.New(""))
+				userRepo.On("Gets", mock.Anything, mock.AnythingOfType("models.RepoGetUserModel")).Return(nil, errors.New(""))
 
 			default:
-				userRepo.On("Gets", mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
-					return filter.Username == tt.args.username
+				userRepo.On("Gets", mock.Anything, mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
+					return filter.Username == strings.ToLower(tt.args.username)
 				})).Return([]models.RepoUserModel{
-					{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: tt.args.username, Password: tt.args.password},
+					{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: strings.ToLower(tt.args.username), Password: "hashed-" + tt.args.password, Role: models.RoleUser},
 				}, nil)
 			}
 
-			userService := services.NewUserService(&userRepo)
+			// mock Verify password
+			hasher := hashers.NewHasherMock()
+			switch tt.name {
+			case "error5":
+				hasher.On("Hash", mock.Anything).Return("hashed-dummy", nil)
+				hasher.On("Verify", tt.args.password, "hashed-dummy").Return(false, false, nil)
+			case "error6", "error6 failure not counted":
+				hasher.On("Verify", tt.args.password, "hashed-other").Return(false, false, nil)
+			case "success rehash":
+				hasher.On("Verify", tt.args.password, "hashed-"+tt.args.password).Return(true, true, nil)
+				hasher.On("Hash", tt.args.password).Return("rehashed-"+tt.args.password, nil)
+			default:
+				hasher.On("Verify", tt.args.password, "hashed-"+tt.args.password).Return(true, false, nil)
+			}
+
+			// mock Update user, recording the login
+			wantUpdate := mock.MatchedBy(func(payload models.RepoUpdateUserModel) bool {
+				wantPassword := ""
+				if tt.name == "success rehash" {
+					wantPassword = "rehashed-" + tt.args.password
+				}
+				return payload.Password == wantPassword && time.Since(payload.LastLoginAt) < time.Minute && payload.UpdatedAt.IsZero()
+			})
+			switch tt.name {
+			case "success record login fails":
+				userRepo.On("Update", mock.Anything, "225cfc88-c66b-4f2f-b424-a3b74e3b1191", wantUpdate).Return(errors.New(""))
+			default:
+				userRepo.On("Update", mock.Anything, "225cfc88-c66b-4f2f-b424-a3b74e3b1191", wantUpdate).Return(nil)
+			}
+
+			// mock Issue token
+			tokenSrv := services.NewTokenSrvMock()
+			switch tt.name {
+			case "unexpected issue token":
+				tokenSrv.On("Issue", mock.Anything, owner).Return("", "", models.ErrUnexpected)
+			default:
+				tokenSrv.On("Issue", mock.Anything, owner).Return("token", "refresh-token", nil)
+			}
+
+			// mock Check throttle
+			throttleSrv := services.NewThrottleSrvMock()
+			switch tt.name {
+			case "throttled":
+				throttleSrv.On("Check", mock.Anything, "admin", "10.0.0.1").Return(models.NewThrottledError(models.ErrTooManyAttempts, time.Second))
+			case "locked":
+				throttleSrv.On("Check", mock.Anything, "admin", "10.0.0.1").Return(models.NewThrottledError(models.ErrAccountLocked, time.Minute))
+			case "unexpected check throttle":
+				throttleSrv.On("Check", mock.Anything, "admin", "10.0.0.1").Return(models.ErrUnexpected.Wrap(errors.New("")))
+			default:
+				throttleSrv.On("Check", mock.Anything, strings.ToLower(tt.args.username), "10.0.0.1").Return(nil)
+			}
+
+			// mock Failure and Reset throttle
+			switch tt.name {
+			case "error6 failure not counted":
+				throttleSrv.On("Failure", mock.Anything, "admin", "10.0.0.1").Return(models.ErrUnexpected)
+			default:
+				throttleSrv.On("Failure", mock.Anything, strings.ToLower(tt.args.username), "10.0.0.1").Return(nil)
+			}
+			throttleSrv.On("Reset", mock.Anything, strings.ToLower(tt.args.username)).Return(nil)
+
+			userService := services.NewUserService(&userRepo, &usernameHistoryRepo, &tokenSrv, &throttleSrv, &hasher, usernamePolicy, passwordPolicy, services.UserConfig{})
 
 			// -------------------- Act (กระทำ)--------------------
-			gotToken, err := userService.Login(tt.args.username, tt.args.password)
+			gotToken, gotRefreshToken, err := userService.Login(ctx, tt.args.username, tt.args.password, "10.0.0.1")
 
 			// -------------------- Assert (ยืนยัน) --------------------
 			if tt.wantErr != nil {
-				assert.Equal(t, tt.wantErr, err)
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				switch tt.name {
+				case "error5", "error6", "error6 failure not counted":
+					// unknown username and wrong password cannot be told apart
+					assert.Same(t, models.ErrInvalidCredentials, err)
+					throttleSrv.AssertCalled(t, "Failure", mock.Anything, "admin", "10.0.0.1")
+					throttleSrv.AssertNotCalled(t, "Reset", mock.Anything, mock.Anything)
+					userRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+				}
+
+				switch tt.name {
+				case "error5":
+					// the dummy hash is made once and then verified for every unknown username
+					userService.Login(ctx, tt.args.username, tt.args.password, "10.0.0.1")
+					hasher.AssertNumberOfCalls(t, "Hash", 1)
+					hasher.AssertNumberOfCalls(t, "Verify", 2)
+				case "throttled", "locked":
+					throttleSrv.AssertNotCalled(t, "Failure", mock.Anything, mock.Anything, mock.Anything)
+					userRepo.AssertNotCalled(t, "Gets", mock.Anything, mock.Anything)
+					hasher.AssertNotCalled(t, "Verify", mock.Anything, mock.Anything)
+				}
 			} else {
-				userRepo.AssertCalled(t, "Gets", mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
-					return filter.Username == tt.args.username
+				throttleSrv.AssertCalled(t, "Reset", mock.Anything, strings.ToLower(tt.args.username))
+
+				userRepo.AssertCalled(t, "Gets", mock.Anything, mock.MatchedBy(func(filter models.RepoGetUserModel) bool {
+					return filter.Username == strings.ToLower(tt.args.username)
 				}))
 
-				result, err := utils.ValidateJWT(gotToken)
-				if err != nil {
-					t.Error(err)
-				}
-				assert.Equal(t, tt.args.username, result.Username)
-				assert.LessOrEqual(t, time.Now(), result.ExpiresAt.Time)
-				_, err = uuid.Parse(result.UserId)
+				tokenSrv.AssertCalled(t, "Issue", mock.Anything, owner)
 				assert.NoError(t, err)
+				assert.Equal(t, "token", gotToken)
+				assert.Equal(t, "refresh-token", gotRefreshToken)
+
+				userRepo.AssertCalled(t, "Update", mock.Anything, "225cfc88-c66b-4f2f-b424-a3b74e3b1191", wantUpdate)
 			}
 		})
 	}
@@ -238,32 +461,47 @@ func TestResetPassword(t *testing.T) {
 		{
 			name:    "error1",
 			args:    args{userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", newPassword: ""},
-			wantErr: errors.New(models.ErrPasswordNotfound),
+			wantErr: models.ErrPasswordNotfound,
 		},
 		{
 			name:    "error2",
 			args:    args{userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", newPassword: "123"},
-			wantErr: errors.New(models.ErrPasswordFormat),
+			wantErr: models.ErrPasswordTooShort,
 		},
 		{
 			name:    "error3",
 			args:    args{userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", newPassword: "123456789123456789"},
-			wantErr: errors.New(models.ErrPasswordFormat),
+			wantErr: models.ErrPasswordTooLong,
 		},
 		{
 			name:    "error4",
 			args:    args{userId: "", newPassword: "admin01"},
-			wantErr: errors.New(models.ErrUserIdFormat),
+			wantErr: models.ErrUserIdFormat,
 		},
 		{
 			name:    "error5",
 			args:    args{userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", newPassword: "admin01"},
-			wantErr: errors.New(models.ErrUserIdIsNotExist),
+			wantErr: models.ErrUserIdIsNotExist,
+		},
+		{
+			name:    "password reused",
+			args:    args{userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", newPassword: "admin01"},
+			wantErr: models.ErrPasswordReused,
+		},
+		{
+			name:    "unexpected gets user",
+			args:    args{userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", newPassword: "admin01"},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "unexpected hash password",
+			args:    args{userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", newPassword: "admin01"},
+			wantErr: models.ErrUnexpected,
 		},
 		{
 			name:    "unexpected update user",
 			args:    args{userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", newPassword: "admin01"},
-			wantErr: errors.New(models.ErrUnexpected),
+			wantErr: models.ErrUnexpected,
 		},
 		{
 			name:    "success1",
@@ -275,96 +513,1158 @@ func TestResetPassword(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// ------------------- Arrange (เตรียมของ) --------------------
 			userRepo := repositories.NewUserRepoMock()
+			usernameHistoryRepo := repositories.NewUsernameHistoryRepoMock()
 
-			// mock Update user
+			tokenSrv := services.NewTokenSrvMock()
+
+			// mock Hash password
+			hasher := hashers.NewHasherMock()
+			switch tt.name {
+			case "unexpected hash password":
+				hasher.On("Hash", tt.args.newPassword).Return("", errors.New(""))
+			default:
+				hasher.On("Hash", tt.args.newPassword).Return("hashed-"+tt.args.newPassword, nil)
+			}
+
+			// mock Verify password history
+			switch tt.name {
+			case "password reused":
+				hasher.On("Verify", tt.args.newPassword, "hashed-previous01").Return(true, false, nil)
+			default:
+				hasher.On("Verify", tt.args.newPassword, "hashed-previous01").Return(false, false, nil)
+			}
+			hasher.On("Verify", tt.args.newPassword, mock.AnythingOfType("string")).Return(false, false, nil)
+
+			// mock Get user
 			switch tt.name {
 			case "error5":
-				userRepo.On("Update", tt.args.userId, mock.MatchedBy(func(payload models.RepoUpdateUserModel) bool {
-					return payload.Password == tt.args.newPassword
-				})).Return(errors.New(tt.wantErr.Error()))
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: tt.args.userId}).Return([]models.RepoUserModel{}, nil)
+			case "unexpected gets user":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: tt.args.userId}).Return(nil, errors.New(""))
+			default:
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: tt.args.userId}).Return([]models.RepoUserModel{
+					{UserId: tt.args.userId, Username: "admin", Password: "hashed-current01", PasswordHistory: []string{"hashed-previous01", "hashed-earliest01"}, CredentialVersion: 2, Role: models.RoleUser},
+				}, nil)
+			}
 
+			// mock Update user
+			switch tt.name {
 			case "unexpected update user":
-				userRepo.On("Update", tt.args.userId, mock.AnythingOfType("models.RepoUpdateUserModel")).Return(errors.New(""))
+				userRepo.On("Update", mock.Anything, tt.args.userId, mock.AnythingOfType("models.RepoUpdateUserModel")).Return(errors.New(""))
 
 			default:
-				userRepo.On("Update", tt.args.userId, mock.MatchedBy(func(payload models.RepoUpdateUserModel) bool {
-					return payload.Password == tt.args.newPassword
+				userRepo.On("Update", mock.Anything, tt.args.userId, mock.MatchedBy(func(payload models.RepoUpdateUserModel) bool {
+					return payload.Password == "hashed-"+tt.args.newPassword
 				})).Return(nil)
 			}
 
-			userService := services.NewUserService(&userRepo)
+			throttleSrv := services.NewThrottleSrvMock()
+			userService := services.NewUserService(&userRepo, &usernameHistoryRepo, &tokenSrv, &throttleSrv, &hasher, usernamePolicy, passwordPolicy, services.UserConfig{PasswordHistory: 3})
 
 			// -------------------- Act (กระทำ)--------------------
-			err := userService.ResetPassword(tt.args.userId, tt.args.newPassword)
+			err := userService.ResetPassword(ctx, tt.args.userId, tt.args.newPassword)
 
 			// -------------------- Assert (ยืนยัน) --------------------
-			assert.Equal(t, tt.wantErr, err)
+			assert.ErrorIs(t, err, tt.wantErr)
 			if tt.wantErr == nil {
-				userRepo.AssertCalled(t, "Update", tt.args.userId, mock.MatchedBy(func(filter models.RepoUpdateUserModel) bool {
-					return filter.Password == tt.args.newPassword
+				// the oldest password leaves the history, the version bump ends every session
+				userRepo.AssertCalled(t, "Update", mock.Anything, tt.args.userId, mock.MatchedBy(func(filter models.RepoUpdateUserModel) bool {
+					return filter.Password == "hashed-"+tt.args.newPassword && !filter.UpdatedAt.IsZero() &&
+						assert.ObjectsAreEqual([]string{"hashed-current01", "hashed-previous01"}, filter.PasswordHistory) && filter.CredentialVersion == 3
 				}))
 			}
+			if tt.name == "password reused" {
+				userRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+			}
 		})
 	}
 }
 
-func TestDeleteUser(t *testing.T) {
+func TestChangePassword(t *testing.T) {
 	type args struct {
-		userId string
+		principal       models.SrvPrincipalModel
+		currentPassword string
+		newPassword     string
 	}
 	tests := []struct {
-		name    string
-		args    args
-		wantErr error
+		name      string
+		args      args
+		config    services.UserConfig
+		wantToken string
+		wantErr   error
 	}{
 		// TODO: Add test cases.
 		{
-			name:    "error1",
-			args:    args{userId: ""},
-			wantErr: errors.New(models.ErrUserIdFormat),
+			name:    "unauthorized",
+			args:    args{currentPassword: "current01", newPassword: "admin01"},
+			wantErr: models.ErrUnauthorized,
 		},
 		{
-			name:    "error2",
-			args:    args{userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
-			wantErr: errors.New(models.ErrUserIdIsNotExist),
+			name:    "current password is required",
+			args:    args{principal: owner, currentPassword: "", newPassword: "admin01"},
+			wantErr: models.ErrCurrentPasswordNotfound,
 		},
 		{
-			name:    "unexpected delete user",
-			args:    args{userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
-			wantErr: errors.New(models.ErrUnexpected),
+			name:    "password is required",
+			args:    args{principal: owner, currentPassword: "current01", newPassword: ""},
+			wantErr: models.ErrPasswordNotfound,
 		},
 		{
-			name:    "success1",
-			args:    args{userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
-			wantErr: nil,
+			name:    "user deleted",
+			args:    args{principal: owner, currentPassword: "current01", newPassword: "admin01"},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:    "throttled",
+			args:    args{principal: owner, currentPassword: "current01", newPassword: "admin01"},
+			wantErr: models.ErrAccountLocked,
+		},
+		{
+			name:    "wrong current password",
+			args:    args{principal: owner, currentPassword: "wrong01", newPassword: "admin01"},
+			wantErr: models.ErrCurrentPasswordInvalid,
+		},
+		{
+			name:    "password too short",
+			args:    args{principal: owner, currentPassword: "current01", newPassword: "123"},
+			wantErr: models.ErrPasswordTooShort,
+		},
+		{
+			name:    "password is the current one",
+			args:    args{principal: owner, currentPassword: "current01", newPassword: "current01"},
+			config:  services.UserConfig{PasswordHistory: 1},
+			wantErr: models.ErrPasswordReused,
+		},
+		{
+			name:    "password is in the history",
+			args:    args{principal: owner, currentPassword: "current01", newPassword: "previous01"},
+			config:  services.UserConfig{PasswordHistory: 2},
+			wantErr: models.ErrPasswordReused,
+		},
+		{
+			name:    "unexpected gets user",
+			args:    args{principal: owner, currentPassword: "current01", newPassword: "admin01"},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "unexpected update user",
+			args:    args{principal: owner, currentPassword: "current01", newPassword: "admin01"},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:      "success history older than kept",
+			args:      args{principal: owner, currentPassword: "current01", newPassword: "earliest01"},
+			config:    services.UserConfig{PasswordHistory: 2},
+			wantToken: "token",
+			wantErr:   nil,
+		},
+		{
+			name:      "success without history",
+			args:      args{principal: owner, currentPassword: "current01", newPassword: "current01"},
+			wantToken: "token",
+			wantErr:   nil,
+		},
+		{
+			name:      "success1",
+			args:      args{principal: owner, currentPassword: "current01", newPassword: "admin01"},
+			config:    services.UserConfig{PasswordHistory: 3},
+			wantToken: "token",
+			wantErr:   nil,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// ------------------- Arrange (เตรียมของ) --------------------
 			userRepo := repositories.NewUserRepoMock()
+			usernameHistoryRepo := repositories.NewUsernameHistoryRepoMock()
+			stored := models.RepoUserModel{UserId: owner.UserId, Username: "admin", Password: "hashed-current01", PasswordHistory: []string{"hashed-previous01", "hashed-earliest01"}, CredentialVersion: 2, Role: models.RoleUser}
 
-			// mock Delete user
+			// mock Gets user
 			switch tt.name {
-			case "error2":
-				userRepo.On("Delete", tt.args.userId).Return(errors.New(tt.wantErr.Error()))
+			case "user deleted":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: owner.UserId}).Return([]models.RepoUserModel{}, nil)
+			case "unexpected gets user":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: owner.UserId}).Return(nil, errors.New(""))
+			default:
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: owner.UserId}).Return([]models.RepoUserModel{stored}, nil)
+			}
 
-			case "unexpected delete user":
-				userRepo.On("Delete", tt.args.userId).Return(errors.New(""))
+			// mock Update user
+			switch tt.name {
+			case "unexpected update user":
+				userRepo.On("Update", mock.Anything, owner.UserId, mock.AnythingOfType("models.RepoUpdateUserModel")).Return(errors.New(""))
+			default:
+				userRepo.On("Update", mock.Anything, owner.UserId, mock.AnythingOfType("models.RepoUpdateUserModel")).Return(nil)
+			}
 
+			// mock Verify and Hash password, a hash is "hashed-" and its password
+			hasher := hashers.NewHasherMock()
+			for _, password := range []string{tt.args.currentPassword, tt.args.newPassword} {
+				for _, hash := range append([]string{stored.Password}, stored.PasswordHistory...) {
+					hasher.On("Verify", password, hash).Return(hash == "hashed-"+password, false, nil)
+				}
+			}
+			hasher.On("Hash", tt.args.newPassword).Return("hashed-"+tt.args.newPassword, nil)
+
+			// mock Check, Failure and Reset throttle
+			throttleSrv := services.NewThrottleSrvMock()
+			switch tt.name {
+			case "throttled":
+				throttleSrv.On("Check", mock.Anything, "admin", "10.0.0.1").Return(models.ErrAccountLocked)
 			default:
-				userRepo.On("Delete", tt.args.userId).Return(nil)
+				throttleSrv.On("Check", mock.Anything, "admin", "10.0.0.1").Return(nil)
 			}
+			throttleSrv.On("Failure", mock.Anything, "admin", "10.0.0.1").Return(nil)
+			throttleSrv.On("Reset", mock.Anything, "admin").Return(nil)
 
-			userService := services.NewUserService(&userRepo)
+			// mock Issue token
+			tokenSrv := services.NewTokenSrvMock()
+			tokenSrv.On("Issue", mock.Anything, models.SrvPrincipalModel{UserId: owner.UserId, Username: "admin", Role: models.RoleUser, CredentialVersion: 3}).Return("token", "refresh-token", nil)
+
+			userService := services.NewUserService(&userRepo, &usernameHistoryRepo, &tokenSrv, &throttleSrv, &hasher, usernamePolicy, passwordPolicy, tt.config)
 
 			// -------------------- Act (กระทำ)--------------------
-			err := userService.DeleteUser(tt.args.userId)
+			gotToken, _, err := userService.ChangePassword(ctx, tt.args.principal, tt.args.currentPassword, tt.args.newPassword, "10.0.0.1")
 
 			// -------------------- Assert (ยืนยัน) --------------------
-			assert.Equal(t, tt.wantErr, err)
-			if tt.wantErr == nil {
-				userRepo.AssertCalled(t, "Delete", tt.args.userId)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantToken, gotToken)
+			switch {
+			case tt.name == "wrong current password":
+				throttleSrv.AssertCalled(t, "Failure", mock.Anything, "admin", "10.0.0.1")
+				userRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+			case tt.name == "throttled":
+				hasher.AssertNotCalled(t, "Verify", mock.Anything, mock.Anything)
+			case tt.name == "success without history":
+				userRepo.AssertCalled(t, "Update", mock.Anything, owner.UserId, mock.MatchedBy(func(payload models.RepoUpdateUserModel) bool {
+					return payload.PasswordHistory == nil && payload.CredentialVersion == 3
+				}))
+			case tt.name == "success1":
+				throttleSrv.AssertCalled(t, "Reset", mock.Anything, "admin")
+				userRepo.AssertCalled(t, "Update", mock.Anything, owner.UserId, mock.MatchedBy(func(payload models.RepoUpdateUserModel) bool {
+					return payload.Password == "hashed-admin01" && assert.ObjectsAreEqual([]string{"hashed-current01", "hashed-previous01"}, payload.PasswordHistory) &&
+						payload.CredentialVersion == 3 && !payload.UpdatedAt.IsZero()
+				}))
+			}
+		})
+	}
+}
+
+func TestChangeUsername(t *testing.T) {
+	type args struct {
+		principal models.SrvPrincipalModel
+		userId    string
+		username  string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		config  services.UserConfig
+		wantErr error
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "username is required",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: ""},
+			wantErr: models.ErrUsernameNotfound,
+		},
+		{
+			name:    "invalid user id",
+			args:    args{principal: owner, userId: "", username: "somchai"},
+			wantErr: models.ErrUserIdFormat,
+		},
+		{
+			name:    "unauthorized",
+			args:    args{userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: "somchai"},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:    "forbidden",
+			args:    args{principal: other, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: "somchai"},
+			wantErr: models.ErrForbidden,
+		},
+		{
+			name:    "invalid username",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: "som chai"},
+			wantErr: models.ErrUsernameCharacters,
+		},
+		{
+			name:    "user is not exist",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: "somchai"},
+			wantErr: models.ErrUserIdIsNotExist,
+		},
+		{
+			name:    "username is held by another user",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: "somchai"},
+			config:  services.UserConfig{UsernameCooldown: 24 * time.Hour},
+			wantErr: models.ErrUsernameIsExist,
+		},
+		{
+			name:    "username is taken",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: "somchai"},
+			config:  services.UserConfig{UsernameCooldown: 24 * time.Hour},
+			wantErr: models.ErrUsernameIsExist,
+		},
+		{
+			name:    "unexpected gets user",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: "somchai"},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "unexpected gets username history",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: "somchai"},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "unexpected create username history",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: "somchai"},
+			config:  services.UserConfig{UsernameCooldown: 24 * time.Hour},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "credentials changed",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: "somchai"},
+			config:  services.UserConfig{UsernameCooldown: 24 * time.Hour},
+			wantErr: models.ErrCredentialsChanged,
+		},
+		{
+			name:    "unexpected update user",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: "somchai"},
+			config:  services.UserConfig{UsernameCooldown: 24 * time.Hour},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "unexpected issue tokens",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: "somchai"},
+			config:  services.UserConfig{UsernameCooldown: 24 * time.Hour},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "success same username",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: "Admin"},
+			config:  services.UserConfig{UsernameCooldown: 24 * time.Hour},
+			wantErr: nil,
+		},
+		{
+			name:    "success reclaim own username",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: "somchai"},
+			config:  services.UserConfig{UsernameCooldown: 24 * time.Hour},
+			wantErr: nil,
+		},
+		{
+			name:    "success without cooldown",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: "somchai"},
+			wantErr: nil,
+		},
+		{
+			name:    "success1",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: "SomChai"},
+			config:  services.UserConfig{UsernameCooldown: 24 * time.Hour},
+			wantErr: nil,
+		},
+		{
+			name:    "success admin",
+			args:    args{principal: admin, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", username: "somchai"},
+			config:  services.UserConfig{UsernameCooldown: 24 * time.Hour},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userRepo := repositories.NewUserRepoMock()
+			usernameHistoryRepo := repositories.NewUsernameHistoryRepoMock()
+
+			// mock Get user
+			switch tt.name {
+			case "user is not exist":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: tt.args.userId}).Return([]models.RepoUserModel{}, nil)
+			case "unexpected gets user":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: tt.args.userId}).Return(nil, errors.New(""))
+			default:
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: tt.args.userId}).Return([]models.RepoUserModel{
+					{UserId: tt.args.userId, Username: "admin", Role: models.RoleUser, CredentialVersion: 2},
+				}, nil)
+			}
+
+			// mock Gets username history
+			switch tt.name {
+			case "username is held by another user":
+				usernameHistoryRepo.On("Gets", mock.Anything, "somchai").Return([]models.RepoUsernameHistoryModel{
+					{Username: "somchai", UserId: other.UserId},
+				}, nil)
+			case "success reclaim own username":
+				usernameHistoryRepo.On("Gets", mock.Anything, "somchai").Return([]models.RepoUsernameHistoryModel{
+					{Username: "somchai", UserId: tt.args.userId},
+				}, nil)
+			case "unexpected gets username history":
+				usernameHistoryRepo.On("Gets", mock.Anything, mock.Anything).Return(nil, errors.New(""))
+			default:
+				usernameHistoryRepo.On("Gets", mock.Anything, "somchai").Return([]models.RepoUsernameHistoryModel{}, nil)
+			}
+
+			// mock Create username history
+			switch tt.name {
+			case "unexpected create username history":
+				usernameHistoryRepo.On("Create", mock.Anything, mock.AnythingOfType("models.RepoUsernameHistoryModel")).Return(errors.New(""))
+			default:
+				usernameHistoryRepo.On("Create", mock.Anything, mock.MatchedBy(func(payload models.RepoUsernameHistoryModel) bool {
+					return payload.Username == "admin" && payload.UserId == tt.args.userId && payload.ExpiresAt.Equal(payload.ReleasedAt.Add(tt.config.UsernameCooldown))
+				})).Return(nil)
+			}
+
+			// mock Update user
+			switch tt.name {
+			case "username is taken":
+				userRepo.On("Update", mock.Anything, tt.args.userId, mock.AnythingOfType("models.RepoUpdateUserModel")).Return(models.ErrUsernameIsExist)
+			case "credentials changed":
+				userRepo.On("Update", mock.Anything, tt.args.userId, mock.AnythingOfType("models.RepoUpdateUserModel")).Return(models.ErrCredentialsChanged)
+			case "unexpected update user":
+				userRepo.On("Update", mock.Anything, tt.args.userId, mock.AnythingOfType("models.RepoUpdateUserModel")).Return(errors.New(""))
+			default:
+				userRepo.On("Update", mock.Anything, tt.args.userId, mock.MatchedBy(func(payload models.RepoUpdateUserModel) bool {
+					return payload.Username == "somchai" && payload.CredentialVersion == 3 && !payload.UpdatedAt.IsZero()
+				})).Return(nil)
+			}
+
+			// mock Issue tokens, they carry the new username and credential version
+			tokenSrv := services.NewTokenSrvMock()
+			renamed := models.SrvPrincipalModel{UserId: tt.args.userId, Username: "somchai", Role: models.RoleUser, CredentialVersion: 3}
+			switch tt.name {
+			case "unexpected issue tokens":
+				tokenSrv.On("Issue", mock.Anything, renamed).Return("", "", models.ErrUnexpected)
+			default:
+				tokenSrv.On("Issue", mock.Anything, renamed).Return("token", "refresh-token", nil)
+			}
+
+			hasher := hashers.NewHasherMock()
+			throttleSrv := services.NewThrottleSrvMock()
+			userService := services.NewUserService(&userRepo, &usernameHistoryRepo, &tokenSrv, &throttleSrv, &hasher, usernamePolicy, passwordPolicy, tt.config)
+
+			// -------------------- Act (กระทำ)--------------------
+			token, refreshToken, err := userService.ChangeUsername(ctx, tt.args.principal, tt.args.userId, tt.args.username)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.ErrorIs(t, err, tt.wantErr)
+			switch {
+			case tt.name == "username is held by another user":
+				// the holder keeps the username, nothing is written
+				usernameHistoryRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				userRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+			case tt.name == "success same username":
+				usernameHistoryRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				userRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+				tokenSrv.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+				assert.Empty(t, token, "the caller's tokens stay valid")
+			case tt.name == "success without cooldown":
+				usernameHistoryRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				assert.Equal(t, "token", token)
+			case tt.name == "username is taken", tt.name == "credentials changed", tt.name == "unexpected update user":
+				tokenSrv.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+			case tt.name == "success admin":
+				// the admin's own session is untouched, the renamed user logs in again
+				userRepo.AssertCalled(t, "Update", mock.Anything, tt.args.userId, mock.AnythingOfType("models.RepoUpdateUserModel"))
+				tokenSrv.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+				assert.Empty(t, token)
+			case tt.wantErr == nil:
+				usernameHistoryRepo.AssertCalled(t, "Create", mock.Anything, mock.AnythingOfType("models.RepoUsernameHistoryModel"))
+				userRepo.AssertCalled(t, "Update", mock.Anything, tt.args.userId, mock.AnythingOfType("models.RepoUpdateUserModel"))
+				assert.Equal(t, "token", token)
+				assert.Equal(t, "refresh-token", refreshToken)
+			}
+		})
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	type args struct {
+		principal models.SrvPrincipalModel
+		userId    string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "error1",
+			args:    args{principal: owner, userId: ""},
+			wantErr: models.ErrUserIdFormat,
+		},
+		{
+			name:    "error2",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrUserIdIsNotExist,
+		},
+		{
+			name:    "unauthorized",
+			args:    args{userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:    "forbidden",
+			args:    args{principal: other, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrForbidden,
+		},
+		{
+			name:    "unexpected delete user",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "success1",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: nil,
+		},
+		{
+			name:    "success admin",
+			args:    args{principal: admin, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userRepo := repositories.NewUserRepoMock()
+			usernameHistoryRepo := repositories.NewUsernameHistoryRepoMock()
+
+			// mock Delete user
+			switch tt.name {
+			case "error2":
+				userRepo.On("Delete", mock.Anything, tt.args.userId).Return(tt.wantErr)
+
+			case "unexpected delete user":
+				userRepo.On("Delete", mock.Anything, tt.args.userId).Return(errors.New(""))
+
+			default:
+				userRepo.On("Delete", mock.Anything, tt.args.userId).Return(nil)
+			}
+
+			tokenSrv := services.NewTokenSrvMock()
+			hasher := hashers.NewHasherMock()
+			throttleSrv := services.NewThrottleSrvMock()
+			userService := services.NewUserService(&userRepo, &usernameHistoryRepo, &tokenSrv, &throttleSrv, &hasher, usernamePolicy, passwordPolicy, services.UserConfig{})
+
+			// -------------------- Act (กระทำ)--------------------
+			err := userService.DeleteUser(ctx, tt.args.principal, tt.args.userId)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				userRepo.AssertCalled(t, "Delete", mock.Anything, tt.args.userId)
+			}
+		})
+	}
+}
+
+func TestUnlockUser(t *testing.T) {
+	type args struct {
+		principal models.SrvPrincipalModel
+		userId    string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "error1",
+			args:    args{principal: admin, userId: ""},
+			wantErr: models.ErrUserIdFormat,
+		},
+		{
+			name:    "unauthorized",
+			args:    args{userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:    "forbidden owner",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrForbidden,
+		},
+		{
+			name:    "error2",
+			args:    args{principal: admin, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrUserIdIsNotExist,
+		},
+		{
+			name:    "unexpected gets user",
+			args:    args{principal: admin, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "unexpected reset throttle",
+			args:    args{principal: admin, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:    "success1",
+			args:    args{principal: admin, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userRepo := repositories.NewUserRepoMock()
+			usernameHistoryRepo := repositories.NewUsernameHistoryRepoMock()
+
+			// mock Gets user
+			switch tt.name {
+			case "error2":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: tt.args.userId}).Return([]models.RepoUserModel{}, nil)
+			case "unexpected gets user":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: tt.args.userId}).Return(nil, errors.New(""))
+			default:
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: tt.args.userId}).Return([]models.RepoUserModel{
+					{UserId: tt.args.userId, Username: "admin", Role: models.RoleUser},
+				}, nil)
+			}
+
+			// mock Reset throttle
+			throttleSrv := services.NewThrottleSrvMock()
+			switch tt.name {
+			case "unexpected reset throttle":
+				throttleSrv.On("Reset", mock.Anything, "admin").Return(models.ErrUnexpected.Wrap(errors.New("")))
+			default:
+				throttleSrv.On("Reset", mock.Anything, "admin").Return(nil)
+			}
+
+			tokenSrv := services.NewTokenSrvMock()
+			hasher := hashers.NewHasherMock()
+			userService := services.NewUserService(&userRepo, &usernameHistoryRepo, &tokenSrv, &throttleSrv, &hasher, usernamePolicy, passwordPolicy, services.UserConfig{})
+
+			// -------------------- Act (กระทำ)--------------------
+			err := userService.UnlockUser(ctx, tt.args.principal, tt.args.userId)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.ErrorIs(t, err, tt.wantErr)
+			switch tt.name {
+			case "success1":
+				throttleSrv.AssertCalled(t, "Reset", mock.Anything, "admin")
+			case "unauthorized", "forbidden owner":
+				userRepo.AssertNotCalled(t, "Gets", mock.Anything, mock.Anything)
+				throttleSrv.AssertNotCalled(t, "Reset", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	type args struct {
+		principal models.SrvPrincipalModel
+		userId    string
+	}
+	createdAt := time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		args     args
+		wantUser models.SrvUserModel
+		wantErr  error
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "error1",
+			args:    args{principal: owner, userId: ""},
+			wantErr: models.ErrUserIdFormat,
+		},
+		{
+			name:    "unauthorized",
+			args:    args{userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:    "forbidden",
+			args:    args{principal: other, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrForbidden,
+		},
+		{
+			name:    "error2",
+			args:    args{principal: admin, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrUserIdIsNotExist,
+		},
+		{
+			name:    "unexpected gets user",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:     "success owner",
+			args:     args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantUser: models.SrvUserModel{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: "admin", Role: models.RoleUser, CreatedAt: createdAt},
+			wantErr:  nil,
+		},
+		{
+			name:     "success admin",
+			args:     args{principal: admin, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantUser: models.SrvUserModel{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: "admin", Role: models.RoleUser, CreatedAt: createdAt},
+			wantErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userRepo := repositories.NewUserRepoMock()
+			usernameHistoryRepo := repositories.NewUsernameHistoryRepoMock()
+
+			// mock Gets user
+			switch tt.name {
+			case "error2":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: tt.args.userId}).Return([]models.RepoUserModel{}, nil)
+			case "unexpected gets user":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: tt.args.userId}).Return(nil, errors.New(""))
+			default:
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: tt.args.userId}).Return([]models.RepoUserModel{
+					{UserId: tt.args.userId, Username: "admin", Password: "hash", Role: models.RoleUser, CreatedAt: createdAt},
+				}, nil)
+			}
+
+			tokenSrv := services.NewTokenSrvMock()
+			throttleSrv := services.NewThrottleSrvMock()
+			hasher := hashers.NewHasherMock()
+			userService := services.NewUserService(&userRepo, &usernameHistoryRepo, &tokenSrv, &throttleSrv, &hasher, usernamePolicy, passwordPolicy, services.UserConfig{})
+
+			// -------------------- Act (กระทำ)--------------------
+			user, err := userService.GetUser(ctx, tt.args.principal, tt.args.userId)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantUser, user)
+			switch tt.name {
+			case "unauthorized", "forbidden":
+				userRepo.AssertNotCalled(t, "Gets", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	users := []models.RepoUserModel{
+		{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: "amy", Password: "hash", Role: models.RoleUser, CreatedAt: time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC)},
+		{UserId: "6b3f4c1e-5a2d-4e8f-9c7b-1d2e3f4a5b6c", Username: "bob", Password: "hash", Role: models.RoleAdmin, CreatedAt: time.Date(2023, 8, 2, 10, 0, 0, 0, time.UTC)},
+	}
+	srvUsers := []models.SrvUserModel{
+		{UserId: users[0].UserId, Username: "amy", Role: models.RoleUser, CreatedAt: users[0].CreatedAt},
+		{UserId: users[1].UserId, Username: "bob", Role: models.RoleAdmin, CreatedAt: users[1].CreatedAt},
+	}
+
+	type args struct {
+		principal models.SrvPrincipalModel
+		query     models.SrvListUsersModel
+	}
+	tests := []struct {
+		name      string
+		args      args
+		repoQuery models.RepoListUserModel
+		repoUsers []models.RepoUserModel
+		wantUsers []models.SrvUserModel
+		wantNext  bool
+		wantErr   error
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "unauthorized",
+			args:    args{},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:    "forbidden",
+			args:    args{principal: owner},
+			wantErr: models.ErrForbidden,
+		},
+		{
+			name:    "limit negative",
+			args:    args{principal: admin, query: models.SrvListUsersModel{Limit: -1}},
+			wantErr: models.ErrListLimit,
+		},
+		{
+			name:    "limit too large",
+			args:    args{principal: admin, query: models.SrvListUsersModel{Limit: 101}},
+			wantErr: models.ErrListLimit,
+		},
+		{
+			name:    "sort unsupported",
+			args:    args{principal: admin, query: models.SrvListUsersModel{Sort: "password"}},
+			wantErr: models.ErrListSort,
+		},
+		{
+			name:    "sort dash only",
+			args:    args{principal: admin, query: models.SrvListUsersModel{Sort: "-"}},
+			wantErr: models.ErrListSort,
+		},
+		{
+			name:    "cursor malformed",
+			args:    args{principal: admin, query: models.SrvListUsersModel{Cursor: "%%%"}},
+			wantErr: models.ErrListCursor,
+		},
+		{
+			name:    "cursor not json",
+			args:    args{principal: admin, query: models.SrvListUsersModel{Cursor: "bm90IGpzb24"}},
+			wantErr: models.ErrListCursor,
+		},
+		{
+			name:      "unexpected list users",
+			args:      args{principal: admin},
+			repoQuery: models.RepoListUserModel{SortBy: models.UserSortUsername, Limit: 21},
+			wantErr:   models.ErrUnexpected,
+		},
+		{
+			name:      "success default",
+			args:      args{principal: admin},
+			repoQuery: models.RepoListUserModel{SortBy: models.UserSortUsername, Limit: 21},
+			repoUsers: users,
+			wantUsers: srvUsers,
+			wantErr:   nil,
+		},
+		{
+			name:      "success empty",
+			args:      args{principal: admin, query: models.SrvListUsersModel{UsernamePrefix: "zz"}},
+			repoQuery: models.RepoListUserModel{UsernamePrefix: "zz", SortBy: models.UserSortUsername, Limit: 21},
+			repoUsers: []models.RepoUserModel{},
+			wantUsers: []models.SrvUserModel{},
+			wantErr:   nil,
+		},
+		{
+			name:      "success canonical prefix descending created_at",
+			args:      args{principal: admin, query: models.SrvListUsersModel{UsernamePrefix: "AM", Sort: "-created_at", Limit: 5}},
+			repoQuery: models.RepoListUserModel{UsernamePrefix: "am", SortBy: models.UserSortCreatedAt, Descending: true, Limit: 6},
+			repoUsers: users[:1],
+			wantUsers: srvUsers[:1],
+			wantErr:   nil,
+		},
+		{
+			name:      "success next page",
+			args:      args{principal: admin, query: models.SrvListUsersModel{Limit: 1}},
+			repoQuery: models.RepoListUserModel{SortBy: models.UserSortUsername, Limit: 2},
+			repoUsers: users,
+			wantUsers: srvUsers[:1],
+			wantNext:  true,
+			wantErr:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userRepo := repositories.NewUserRepoMock()
+			usernameHistoryRepo := repositories.NewUsernameHistoryRepoMock()
+
+			// mock List users
+			switch tt.name {
+			case "unexpected list users":
+				userRepo.On("List", mock.Anything, tt.repoQuery).Return(nil, errors.New(""))
+			default:
+				userRepo.On("List", mock.Anything, tt.repoQuery).Return(tt.repoUsers, nil)
+			}
+
+			tokenSrv := services.NewTokenSrvMock()
+			throttleSrv := services.NewThrottleSrvMock()
+			hasher := hashers.NewHasherMock()
+			userService := services.NewUserService(&userRepo, &usernameHistoryRepo, &tokenSrv, &throttleSrv, &hasher, usernamePolicy, passwordPolicy, services.UserConfig{})
+
+			// -------------------- Act (กระทำ)--------------------
+			page, err := userService.ListUsers(ctx, tt.args.principal, tt.args.query)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantUsers, page.Users)
+			assert.Equal(t, tt.wantNext, page.NextCursor != "")
+			if tt.wantErr != nil && !errors.Is(tt.wantErr, models.ErrUnexpected) {
+				userRepo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+			}
+
+			if tt.wantNext {
+				// the cursor resumes after the last user of the page
+				next := tt.repoQuery
+				next.After = &models.RepoUserModel{UserId: users[0].UserId, Username: users[0].Username, CreatedAt: users[0].CreatedAt}
+				userRepo.On("List", mock.Anything, next).Return(users[1:], nil)
+
+				query := tt.args.query
+				query.Cursor = page.NextCursor
+				page, err := userService.ListUsers(ctx, tt.args.principal, query)
+				assert.NoError(t, err)
+				assert.Equal(t, srvUsers[1:], page.Users)
+				assert.Empty(t, page.NextCursor)
+
+				// and only for the query that made it
+				query.Sort = "-username"
+				_, err = userService.ListUsers(ctx, tt.args.principal, query)
+				assert.ErrorIs(t, err, models.ErrListCursor)
+			}
+		})
+	}
+}
+
+func TestGetProfile(t *testing.T) {
+	type args struct {
+		principal models.SrvPrincipalModel
+		userId    string
+	}
+	createdAt := time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC)
+	profile := models.SrvProfileModel{
+		UserId:      "225cfc88-c66b-4f2f-b424-a3b74e3b1191",
+		Username:    "admin",
+		DisplayName: "Admin",
+		Email:       "admin@example.com",
+		Locale:      "th-TH",
+		Attributes:  map[string]string{"team": "core"},
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt.Add(time.Hour),
+		LastLoginAt: createdAt.Add(2 * time.Hour),
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantProfile models.SrvProfileModel
+		wantErr     error
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "error1",
+			args:    args{principal: owner, userId: ""},
+			wantErr: models.ErrUserIdFormat,
+		},
+		{
+			name:    "unauthorized",
+			args:    args{userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:    "forbidden",
+			args:    args{principal: other, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrForbidden,
+		},
+		{
+			name:    "error2",
+			args:    args{principal: admin, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrUserIdIsNotExist,
+		},
+		{
+			name:    "unexpected gets user",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrUnexpected,
+		},
+		{
+			name:        "success owner",
+			args:        args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantProfile: profile,
+			wantErr:     nil,
+		},
+		{
+			name:        "success admin",
+			args:        args{principal: admin, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantProfile: profile,
+			wantErr:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userRepo := repositories.NewUserRepoMock()
+			usernameHistoryRepo := repositories.NewUsernameHistoryRepoMock()
+
+			// mock Gets user
+			switch tt.name {
+			case "error2":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: tt.args.userId}).Return([]models.RepoUserModel{}, nil)
+			case "unexpected gets user":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: tt.args.userId}).Return(nil, errors.New(""))
+			default:
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: tt.args.userId}).Return([]models.RepoUserModel{{
+					UserId:      profile.UserId,
+					Username:    profile.Username,
+					Password:    "hash",
+					Role:        models.RoleUser,
+					DisplayName: profile.DisplayName,
+					Email:       profile.Email,
+					Locale:      profile.Locale,
+					Attributes:  profile.Attributes,
+					CreatedAt:   profile.CreatedAt,
+					UpdatedAt:   profile.UpdatedAt,
+					LastLoginAt: profile.LastLoginAt,
+				}}, nil)
+			}
+
+			tokenSrv := services.NewTokenSrvMock()
+			throttleSrv := services.NewThrottleSrvMock()
+			hasher := hashers.NewHasherMock()
+			userService := services.NewUserService(&userRepo, &usernameHistoryRepo, &tokenSrv, &throttleSrv, &hasher, usernamePolicy, passwordPolicy, services.UserConfig{})
+
+			// -------------------- Act (กระทำ)--------------------
+			gotProfile, err := userService.GetProfile(ctx, tt.args.principal, tt.args.userId)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantProfile, gotProfile)
+			switch tt.name {
+			case "unauthorized", "forbidden":
+				userRepo.AssertNotCalled(t, "Gets", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	ptr := func(value string) *string { return &value }
+	stored := map[string]string{}
+	for i := 0; i < 15; i++ {
+		stored[fmt.Sprintf("key_%v", i)] = "value"
+	}
+
+	type args struct {
+		principal models.SrvPrincipalModel
+		userId    string
+		payload   models.SrvUpdateProfileModel
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantUpdate models.RepoUpdateUserModel
+		wantErr    error
+		wantFields []string
+	}{
+		// TODO: Add test cases.
+		{
+			name:    "error1",
+			args:    args{principal: owner, userId: ""},
+			wantErr: models.ErrUserIdFormat,
+		},
+		{
+			name:    "unauthorized",
+			args:    args{userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrUnauthorized,
+		},
+		{
+			name:    "forbidden",
+			args:    args{principal: other, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrForbidden,
+		},
+		{
+			name:    "error2",
+			args:    args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191"},
+			wantErr: models.ErrUserIdIsNotExist,
+		},
+		{
+			name: "invalid fields",
+			args: args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", payload: models.SrvUpdateProfileModel{
+				DisplayName: ptr(strings.Repeat("a", 65)),
+				Email:       ptr("Admin <admin@example.com>"),
+				Locale:      ptr("not a locale"),
+				Attributes:  map[string]*string{"Team": ptr("core"), "bio": ptr(strings.Repeat("a", 257))},
+			}},
+			wantErr:    models.ErrValidation,
+			wantFields: []string{"display_name", "email", "locale", "attributes.Team", "attributes.bio"},
+		},
+		{
+			name: "display name control character",
+			args: args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", payload: models.SrvUpdateProfileModel{
+				DisplayName: ptr("Ad\u0000min"),
+			}},
+			wantErr:    models.ErrValidation,
+			wantFields: []string{"display_name"},
+		},
+		{
+			name: "too many attributes",
+			args: args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", payload: models.SrvUpdateProfileModel{
+				Attributes: map[string]*string{"team": ptr("core"), "floor": ptr("4")},
+			}},
+			wantErr:    models.ErrValidation,
+			wantFields: []string{"attributes"},
+		},
+		{
+			name: "unexpected update user",
+			args: args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", payload: models.SrvUpdateProfileModel{
+				DisplayName: ptr("Admin"),
+			}},
+			wantUpdate: models.RepoUpdateUserModel{DisplayName: ptr("Admin")},
+			wantErr:    models.ErrUnexpected,
+		},
+		{
+			name: "success",
+			args: args{principal: owner, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", payload: models.SrvUpdateProfileModel{
+				DisplayName: ptr("  Admin  "),
+				Email:       ptr(""),
+				Locale:      ptr("th-th"),
+				Attributes:  map[string]*string{"team": ptr("core"), "key_0": nil},
+			}},
+			wantUpdate: models.RepoUpdateUserModel{
+				DisplayName: ptr("Admin"),
+				Email:       ptr(""),
+				Locale:      ptr("th-TH"),
+				Attributes:  map[string]*string{"team": ptr("core"), "key_0": nil},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "success admin",
+			args: args{principal: admin, userId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", payload: models.SrvUpdateProfileModel{
+				Email: ptr("admin@example.com"),
+			}},
+			wantUpdate: models.RepoUpdateUserModel{Email: ptr("admin@example.com")},
+			wantErr:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			userRepo := repositories.NewUserRepoMock()
+			usernameHistoryRepo := repositories.NewUsernameHistoryRepoMock()
+
+			// mock Gets user
+			switch tt.name {
+			case "error2":
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: tt.args.userId}).Return([]models.RepoUserModel{}, nil)
+			default:
+				userRepo.On("Gets", mock.Anything, models.RepoGetUserModel{UserId: tt.args.userId}).Return([]models.RepoUserModel{
+					{UserId: tt.args.userId, Username: "admin", Role: models.RoleUser, Attributes: stored},
+				}, nil)
+			}
+
+			// mock Update user, the time is the service's own
+			wantUpdate := mock.MatchedBy(func(payload models.RepoUpdateUserModel) bool {
+				if time.Since(payload.UpdatedAt) > time.Minute {
+					return false
+				}
+				payload.UpdatedAt = time.Time{}
+				return assert.ObjectsAreEqual(tt.wantUpdate, payload)
+			})
+			switch tt.name {
+			case "unexpected update user":
+				userRepo.On("Update", mock.Anything, tt.args.userId, wantUpdate).Return(errors.New(""))
+			default:
+				userRepo.On("Update", mock.Anything, tt.args.userId, wantUpdate).Return(nil)
+			}
+
+			tokenSrv := services.NewTokenSrvMock()
+			throttleSrv := services.NewThrottleSrvMock()
+			hasher := hashers.NewHasherMock()
+			userService := services.NewUserService(&userRepo, &usernameHistoryRepo, &tokenSrv, &throttleSrv, &hasher, usernamePolicy, passwordPolicy, services.UserConfig{})
+
+			// -------------------- Act (กระทำ)--------------------
+			profile, err := userService.UpdateProfile(ctx, tt.args.principal, tt.args.userId, tt.args.payload)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantFields != nil {
+				validationErr := &models.ValidationError{}
+				if assert.ErrorAs(t, err, &validationErr) {
+					fields := []string{}
+					for _, field := range validationErr.Fields {
+						fields = append(fields, field.Field)
+					}
+					assert.ElementsMatch(t, tt.wantFields, fields)
+				}
+			}
+
+			if tt.wantErr == nil {
+				userRepo.AssertCalled(t, "Update", mock.Anything, tt.args.userId, wantUpdate)
+				assert.Equal(t, tt.args.userId, profile.UserId)
+			} else {
+				assert.Empty(t, profile)
+			}
+			if tt.wantErr != nil && !errors.Is(tt.wantErr, models.ErrUnexpected) {
+				userRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
 			}
 		})
 	}
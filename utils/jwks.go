@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+)
+
+// JWKModel is the RFC 7517 public representation of a JWTKey
+type JWKModel struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKSModel struct {
+	Keys []JWKModel `json:"keys"`
+}
+
+// JWK is false for HS256 keys, a shared secret must never be published
+func (k JWTKey) JWK() (jwk JWKModel, ok bool) {
+	switch publicKey := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return JWKModel{
+			Kty: "RSA",
+			Kid: k.Kid,
+			Use: "sig",
+			Alg: k.Algorithm,
+			N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		}, true
+
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		return JWKModel{
+			Kty: "EC",
+			Kid: k.Kid,
+			Use: "sig",
+			Alg: k.Algorithm,
+			Crv: publicKey.Curve.Params().Name,
+			X:   base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, size))),
+			Y:   base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, size))),
+		}, true
+
+	default:
+		return jwk, false
+	}
+}
+
+func NewJWKS(keys []JWTKey) JWKSModel {
+	jwks := JWKSModel{Keys: []JWKModel{}}
+	for _, key := range keys {
+		if jwk, ok := key.JWK(); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
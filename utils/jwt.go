@@ -1,34 +1,130 @@
 package utils
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-var secret = "hexagonal-gotest"
-
+// TokenDataModel CredentialVersion is the password version of the user the token was
+// issued for, tokens from before a password change no longer match it
 type TokenDataModel struct {
-	UserId   string `json:"user_id"`
-	Username string `json:"username"`
+	UserId            string `json:"user_id"`
+	Username          string `json:"username"`
+	Role              string `json:"role,omitempty"`
+	CredentialVersion int    `json:"credential_version,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func SignJWT(payload TokenDataModel) (tokenString string, err error) {
+type TokenSigner interface {
+	Sign(payload TokenDataModel) (tokenString string, err error)
+
+	// Lifetime of the tokens this signer issues
+	Lifetime() time.Duration
+}
+
+type TokenVerifier interface {
+	Verify(tokenString string) (tokenData TokenDataModel, err error)
+}
+
+type JWT interface {
+	TokenSigner
+	TokenVerifier
+
+	// Keys returns the signing key first, then the retiring verification keys
+	Keys() []JWTKey
+
+	Issuer() string
+}
+
+type JWTConfig struct {
+	Issuer   string
+	Audience string
+	Lifetime time.Duration
+}
+
+type jwtManager struct {
+	config           JWTConfig
+	signingKey       JWTKey
+	verificationKeys map[string]JWTKey
+	retiringKeys     []JWTKey
+	validMethods     []string
+}
+
+// NewJWT signs with signingKey and verifies with signingKey plus any retiring
+// verificationKeys, selected by the kid header.
+func NewJWT(config JWTConfig, signingKey JWTKey, verificationKeys ...JWTKey) (JWT, error) {
+	if signingKey.Kid == "" || signingKey.PrivateKey == nil {
+		return nil, errors.New("signing key must have a kid and a private key")
+	}
+	if signingMethod(signingKey.Algorithm) == nil {
+		return nil, fmt.Errorf("unsupported jwt algorithm %v", signingKey.Algorithm)
+	}
+	if config.Lifetime <= 0 {
+		config.Lifetime = 1 * time.Hour
+	}
+
+	keys := map[string]JWTKey{signingKey.Kid: signingKey}
+	methods := map[string]bool{signingKey.Algorithm: true}
+	for _, key := range verificationKeys {
+		if signingMethod(key.Algorithm) == nil {
+			return nil, fmt.Errorf("unsupported jwt algorithm %v", key.Algorithm)
+		}
+		if _, ok := keys[key.Kid]; ok || key.Kid == "" {
+			return nil, fmt.Errorf("verification key kid %q is empty or duplicated", key.Kid)
+		}
+		if key.verificationKey() == nil {
+			return nil, fmt.Errorf("verification key %v has no key material", key.Kid)
+		}
+		keys[key.Kid] = key
+		methods[key.Algorithm] = true
+	}
+
+	validMethods := []string{}
+	for method := range methods {
+		validMethods = append(validMethods, method)
+	}
+
+	return jwtManager{config, signingKey, keys, verificationKeys, validMethods}, nil
+}
+
+func (m jwtManager) Lifetime() time.Duration {
+	return m.config.Lifetime
+}
+
+func (m jwtManager) Keys() []JWTKey {
+	return append([]JWTKey{m.signingKey}, m.retiringKeys...)
+}
+
+func (m jwtManager) Issuer() string {
+	return m.config.Issuer
+}
+
+func (m jwtManager) Sign(payload TokenDataModel) (tokenString string, err error) {
+	now := time.Now()
 	claims := TokenDataModel{
-		UserId:   payload.UserId,
-		Username: payload.Username,
+		UserId:            payload.UserId,
+		Username:          payload.Username,
+		Role:              payload.Role,
+		CredentialVersion: payload.CredentialVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Hour)),
+			ID:        uuid.NewString(),
+			Issuer:    m.config.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.config.Lifetime)),
 		},
 	}
+	if m.config.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{m.config.Audience}
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims)
+	token := jwt.NewWithClaims(signingMethod(m.signingKey.Algorithm), &claims)
+	token.Header["kid"] = m.signingKey.Kid
 
-	tokenString, err = token.SignedString([]byte(secret))
+	tokenString, err = token.SignedString(m.signingKey.signingKey())
 	if err != nil {
 		return tokenString, errors.New("error sign token")
 	}
@@ -36,41 +132,40 @@ func SignJWT(payload TokenDataModel) (tokenString string, err error) {
 	return
 }
 
-func ValidateJWT(tokenString string) (tokenData TokenDataModel, err error) {
+func (m jwtManager) Verify(tokenString string) (tokenData TokenDataModel, err error) {
+	options := []jwt.ParserOption{jwt.WithValidMethods(m.validMethods)}
+	if m.config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(m.config.Issuer))
+	}
+	if m.config.Audience != "" {
+		options = append(options, jwt.WithAudience(m.config.Audience))
+	}
+
+	claims := TokenDataModel{}
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := m.verificationKeys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Don't forget to validate the alg is what you expect:
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		// the key decides the algorithm, never the token header
+		if token.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Method.Alg())
 		}
 
-		// secret is a []byte containing your secret, e.g. []byte("my_secret_key")
-		return []byte(secret), nil
-	})
+		return key.verificationKey(), nil
+	}, options...)
 	if err != nil {
 		return tokenData, err
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !(ok && token.Valid) {
-		return tokenData, errors.New("error validate token")
-	}
-	if _, ok := claims["user_id"].(string); !ok {
+	if !token.Valid || claims.ExpiresAt == nil {
 		return tokenData, errors.New("error validate token")
 	}
-	if _, ok := claims["username"].(string); !ok {
+	if claims.UserId == "" || claims.Username == "" || claims.ID == "" {
 		return tokenData, errors.New("error validate token")
 	}
 
-	dataBytes, err := json.Marshal(claims)
-	if err != nil {
-		return tokenData, err
-	}
-
-	err = json.Unmarshal(dataBytes, &tokenData)
-	if err != nil {
-		return tokenData, err
-	}
-
-	return
+	return claims, nil
 }
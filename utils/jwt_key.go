@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+)
+
+// JWTKey PrivateKey is empty for verification only keys
+type JWTKey struct {
+	Kid        string
+	Algorithm  string
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+}
+
+func (k JWTKey) signingKey() interface{} {
+	return k.PrivateKey
+}
+
+func (k JWTKey) verificationKey() interface{} {
+	if k.Algorithm == AlgorithmHS256 {
+		return k.PrivateKey
+	}
+	return k.PublicKey
+}
+
+// NewHMACKey secret must be at least 32 bytes, the HS256 output size
+func NewHMACKey(kid string, secret []byte) (key JWTKey, err error) {
+	if len(secret) < 32 {
+		return key, errors.New("hmac secret must be at least 32 bytes")
+	}
+
+	return JWTKey{Kid: kid, Algorithm: AlgorithmHS256, PrivateKey: secret}, nil
+}
+
+// ParseJWTKey accepts the raw secret for HS256, or a PEM private or public key for RS256 and ES256
+func ParseJWTKey(kid, algorithm string, data []byte) (key JWTKey, err error) {
+	switch algorithm {
+	case AlgorithmHS256:
+		return NewHMACKey(kid, data)
+
+	case AlgorithmRS256:
+		if privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			return JWTKey{Kid: kid, Algorithm: algorithm, PrivateKey: privateKey, PublicKey: &privateKey.PublicKey}, nil
+		}
+		publicKey, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return key, fmt.Errorf("parse rsa key %v: %w", kid, err)
+		}
+		return JWTKey{Kid: kid, Algorithm: algorithm, PublicKey: publicKey}, nil
+
+	case AlgorithmES256:
+		var publicKey *ecdsa.PublicKey
+		privateKey, err := jwt.ParseECPrivateKeyFromPEM(data)
+		if err == nil {
+			publicKey = &privateKey.PublicKey
+		} else if publicKey, err = jwt.ParseECPublicKeyFromPEM(data); err != nil {
+			return key, fmt.Errorf("parse ec key %v: %w", kid, err)
+		}
+		if publicKey.Curve != elliptic.P256() {
+			return key, fmt.Errorf("ec key %v must use curve P-256", kid)
+		}
+		if privateKey == nil {
+			return JWTKey{Kid: kid, Algorithm: algorithm, PublicKey: publicKey}, nil
+		}
+		return JWTKey{Kid: kid, Algorithm: algorithm, PrivateKey: privateKey, PublicKey: publicKey}, nil
+
+	default:
+		return key, fmt.Errorf("unsupported jwt algorithm %v", algorithm)
+	}
+}
+
+func LoadJWTKeyFromFile(kid, algorithm, path string) (key JWTKey, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return key, err
+	}
+
+	return ParseJWTKey(kid, algorithm, data)
+}
+
+// signingMethod also guards against a key being used with another algorithm
+func signingMethod(algorithm string) jwt.SigningMethod {
+	switch algorithm {
+	case AlgorithmHS256:
+		return jwt.SigningMethodHS256
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256
+	case AlgorithmES256:
+		return jwt.SigningMethodES256
+	default:
+		return nil
+	}
+}
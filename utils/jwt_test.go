@@ -0,0 +1,223 @@
+package utils_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"hexagonal-gotest/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func rsaPEM(t *testing.T) (private, public []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+}
+
+func ecPEM(t *testing.T, curve elliptic.Curve) (private, public []byte) {
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, _ := x509.MarshalECPrivateKey(key)
+	publicDER, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: privateDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+}
+
+func TestParseJWTKey(t *testing.T) {
+	rsaPrivate, rsaPublic := rsaPEM(t)
+	ecPrivate, ecPublic := ecPEM(t, elliptic.P256())
+	ecP384Private, _ := ecPEM(t, elliptic.P384())
+	tests := []struct {
+		name           string
+		algorithm      string
+		data           []byte
+		wantPrivateKey bool
+		wantErr        bool
+	}{
+		{name: "hmac", algorithm: utils.AlgorithmHS256, data: []byte("hexagonal-gotest-secret-for-tests"), wantPrivateKey: true},
+		{name: "hmac too short", algorithm: utils.AlgorithmHS256, data: []byte("secret"), wantErr: true},
+		{name: "rsa private", algorithm: utils.AlgorithmRS256, data: rsaPrivate, wantPrivateKey: true},
+		{name: "rsa public", algorithm: utils.AlgorithmRS256, data: rsaPublic},
+		{name: "rsa invalid", algorithm: utils.AlgorithmRS256, data: ecPrivate, wantErr: true},
+		{name: "ec private", algorithm: utils.AlgorithmES256, data: ecPrivate, wantPrivateKey: true},
+		{name: "ec public", algorithm: utils.AlgorithmES256, data: ecPublic},
+		{name: "ec wrong curve", algorithm: utils.AlgorithmES256, data: ecP384Private, wantErr: true},
+		{name: "unsupported", algorithm: "none", data: []byte("x"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// -------------------- Act (กระทำ)--------------------
+			key, err := utils.ParseJWTKey("kid-1", tt.algorithm, tt.data)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.Equal(t, tt.wantErr, err != nil)
+			if !tt.wantErr {
+				assert.Equal(t, "kid-1", key.Kid)
+				assert.Equal(t, tt.wantPrivateKey, key.PrivateKey != nil)
+			}
+		})
+	}
+}
+
+func TestJWTSignVerify(t *testing.T) {
+	rsaPrivate, rsaPublic := rsaPEM(t)
+	ecPrivate, _ := ecPEM(t, elliptic.P256())
+
+	hmacKey, _ := utils.NewHMACKey("hmac-1", []byte("hexagonal-gotest-secret-for-tests"))
+	rsaKey, _ := utils.ParseJWTKey("rsa-1", utils.AlgorithmRS256, rsaPrivate)
+	rsaPublicKey, _ := utils.ParseJWTKey("rsa-1", utils.AlgorithmRS256, rsaPublic)
+	ecKey, _ := utils.ParseJWTKey("ec-1", utils.AlgorithmES256, ecPrivate)
+	config := utils.JWTConfig{Issuer: "hexagonal-gotest", Audience: "api", Lifetime: time.Hour}
+	payload := utils.TokenDataModel{UserId: "225cfc88-c66b-4f2f-b424-a3b74e3b1191", Username: "admin", Role: "user", CredentialVersion: 2}
+
+	tests := []struct {
+		name     string
+		signer   func() (utils.JWT, error)
+		verifier func() (utils.JWT, error)
+		wantErr  bool
+	}{
+		{
+			name:     "hs256",
+			signer:   func() (utils.JWT, error) { return utils.NewJWT(config, hmacKey) },
+			verifier: func() (utils.JWT, error) { return utils.NewJWT(config, hmacKey) },
+		},
+		{
+			name:     "rs256",
+			signer:   func() (utils.JWT, error) { return utils.NewJWT(config, rsaKey) },
+			verifier: func() (utils.JWT, error) { return utils.NewJWT(config, rsaKey) },
+		},
+		{
+			name:     "es256",
+			signer:   func() (utils.JWT, error) { return utils.NewJWT(config, ecKey) },
+			verifier: func() (utils.JWT, error) { return utils.NewJWT(config, ecKey) },
+		},
+		{
+			name:     "rotated key still verifies",
+			signer:   func() (utils.JWT, error) { return utils.NewJWT(config, rsaKey) },
+			verifier: func() (utils.JWT, error) { return utils.NewJWT(config, ecKey, rsaPublicKey) },
+		},
+		{
+			name:     "unknown kid",
+			signer:   func() (utils.JWT, error) { return utils.NewJWT(config, rsaKey) },
+			verifier: func() (utils.JWT, error) { return utils.NewJWT(config, ecKey) },
+			wantErr:  true,
+		},
+		{
+			name:   "algorithm confusion",
+			signer: func() (utils.JWT, error) { return utils.NewJWT(config, hmacKey) },
+			verifier: func() (utils.JWT, error) {
+				key := rsaPublicKey
+				key.Kid = "hmac-1"
+				return utils.NewJWT(config, ecKey, key)
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong issuer",
+			signer: func() (utils.JWT, error) {
+				return utils.NewJWT(utils.JWTConfig{Issuer: "other", Audience: "api"}, hmacKey)
+			},
+			verifier: func() (utils.JWT, error) {
+				return utils.NewJWT(config, hmacKey)
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong audience",
+			signer: func() (utils.JWT, error) {
+				return utils.NewJWT(utils.JWTConfig{Issuer: "hexagonal-gotest", Audience: "other"}, hmacKey)
+			},
+			verifier: func() (utils.JWT, error) {
+				return utils.NewJWT(config, hmacKey)
+			},
+			wantErr: true,
+		},
+		{
+			name: "expired",
+			signer: func() (utils.JWT, error) {
+				return utils.NewJWT(utils.JWTConfig{Issuer: "hexagonal-gotest", Audience: "api", Lifetime: time.Nanosecond}, hmacKey)
+			},
+			verifier: func() (utils.JWT, error) {
+				return utils.NewJWT(config, hmacKey)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ------------------- Arrange (เตรียมของ) --------------------
+			signer, err := tt.signer()
+			assert.NoError(t, err)
+			verifier, err := tt.verifier()
+			assert.NoError(t, err)
+
+			// -------------------- Act (กระทำ)--------------------
+			tokenString, err := signer.Sign(payload)
+			assert.NoError(t, err)
+			time.Sleep(time.Millisecond)
+			tokenData, err := verifier.Verify(tokenString)
+
+			// -------------------- Assert (ยืนยัน) --------------------
+			assert.Equal(t, tt.wantErr, err != nil)
+			if !tt.wantErr {
+				assert.Equal(t, payload.UserId, tokenData.UserId)
+				assert.Equal(t, payload.Username, tokenData.Username)
+				assert.Equal(t, payload.Role, tokenData.Role)
+				assert.Equal(t, payload.CredentialVersion, tokenData.CredentialVersion)
+				assert.NotEmpty(t, tokenData.ID)
+				assert.Equal(t, "hexagonal-gotest", tokenData.Issuer)
+			}
+		})
+	}
+}
+
+func TestNewJWT(t *testing.T) {
+	hmacKey, _ := utils.NewHMACKey("hmac-1", []byte("hexagonal-gotest-secret-for-tests"))
+	_, rsaPublic := rsaPEM(t)
+	rsaPublicKey, _ := utils.ParseJWTKey("rsa-1", utils.AlgorithmRS256, rsaPublic)
+
+	_, err := utils.NewJWT(utils.JWTConfig{}, rsaPublicKey)
+	assert.Error(t, err, "signing key without private key")
+
+	_, err = utils.NewJWT(utils.JWTConfig{}, hmacKey, hmacKey)
+	assert.Error(t, err, "duplicated kid")
+
+	jwt, err := utils.NewJWT(utils.JWTConfig{}, hmacKey, rsaPublicKey)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, jwt.Lifetime())
+}
+
+func TestNewJWKS(t *testing.T) {
+	rsaPrivate, _ := rsaPEM(t)
+	_, ecPublic := ecPEM(t, elliptic.P256())
+	hmacKey, _ := utils.NewHMACKey("hmac-1", []byte("hexagonal-gotest-secret-for-tests"))
+	rsaKey, _ := utils.ParseJWTKey("rsa-1", utils.AlgorithmRS256, rsaPrivate)
+	ecKey, _ := utils.ParseJWTKey("ec-1", utils.AlgorithmES256, ecPublic)
+
+	// -------------------- Act (กระทำ)--------------------
+	jwks := utils.NewJWKS([]utils.JWTKey{hmacKey, rsaKey, ecKey})
+
+	// -------------------- Assert (ยืนยัน) --------------------
+	assert.Len(t, jwks.Keys, 2, "hmac key must not be published")
+	assert.Equal(t, "rsa-1", jwks.Keys[0].Kid)
+	assert.Equal(t, "RSA", jwks.Keys[0].Kty)
+	assert.Equal(t, "AQAB", jwks.Keys[0].E)
+	assert.NotEmpty(t, jwks.Keys[0].N)
+	assert.Equal(t, "ec-1", jwks.Keys[1].Kid)
+	assert.Equal(t, "EC", jwks.Keys[1].Kty)
+	assert.Equal(t, "P-256", jwks.Keys[1].Crv)
+	assert.Len(t, jwks.Keys[1].X, 43)
+	assert.Len(t, jwks.Keys[1].Y, 43)
+}